@@ -8,9 +8,10 @@ import (
 
 // Endpoints collects all of the endpoints that compose a workflow service.
 type Endpoints struct {
-	PostSavePipelineEndpoint    endpoint.Endpoint
-	PostExecPipelineEndpoint    endpoint.Endpoint
-	PostExecpipelineRunEndpoint endpoint.Endpoint
+	PostSavePipelineEndpoint      endpoint.Endpoint
+	PostExecPipelineEndpoint      endpoint.Endpoint
+	PostExecpipelineRunEndpoint   endpoint.Endpoint
+	PostRewindPipelineRunEndpoint endpoint.Endpoint
 }
 
 // NewServerEndpoints returns an Endpoints struct where each endpoint invokes
@@ -18,9 +19,10 @@ type Endpoints struct {
 // server.
 func NewServerEndpoints(s Service) Endpoints {
 	return Endpoints{
-		PostSavePipelineEndpoint:    PostSavePipelineEndpoints(s.GetPipeline()),
-		PostExecPipelineEndpoint:    PostExecPipelineEndpoints(s.GetPipeline()),
-		PostExecpipelineRunEndpoint: PostExecpipelineRunEndpoint(s.GetPipelineRun()),
+		PostSavePipelineEndpoint:      PostSavePipelineEndpoints(s.GetPipeline()),
+		PostExecPipelineEndpoint:      PostExecPipelineEndpoints(s.GetPipeline()),
+		PostExecpipelineRunEndpoint:   PostExecpipelineRunEndpoint(s.GetPipelineRun()),
+		PostRewindPipelineRunEndpoint: PostRewindPipelineRunEndpoint(s.GetPipelineRun()),
 	}
 }
 
@@ -48,6 +50,14 @@ func PostExecpipelineRunEndpoint(s PipelineRunService) endpoint.Endpoint {
 	}
 }
 
+func PostRewindPipelineRunEndpoint(s PipelineRunService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(*RewindPipelineRun)
+		err := s.Rewind(ctx, req)
+		return universalResponse{Err: err}, nil
+	}
+}
+
 func (e Endpoints) Save(ctx context.Context, in *SavePipeline) error {
 	_, err := e.PostSavePipelineEndpoint(ctx, in)
 	return err
@@ -63,6 +73,11 @@ func (e Endpoints) ExecPipelineRun(ctx context.Context, in *ExecPipelineRun) err
 	return err
 }
 
+func (e Endpoints) RewindPipelineRun(ctx context.Context, in *RewindPipelineRun) error {
+	_, err := e.PostRewindPipelineRunEndpoint(ctx, in)
+	return err
+}
+
 type ur interface {
 	GetErr() error
 	GetData() interface{}
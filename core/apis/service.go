@@ -33,9 +33,19 @@ type ExecPipelineRun struct {
 	ID int64 `json:"id,omitempty"`
 }
 
+// RewindPipelineRun moves a pipeline run back to Node, an empty Node means the first node.
+// The status of Node and of every node after it is dropped, so they are executed again.
+type RewindPipelineRun struct {
+	ID   int64  `json:"id,omitempty"`
+	Node string `json:"node,omitempty"`
+	// From is the node the run has to be pending at, it is not rewound otherwise. Any node when empty.
+	From string `json:"from,omitempty"`
+}
+
 type PipelineRunService interface {
 	Create(ctx context.Context, in *CreatePipelineRun) error
 	Exec(ctx context.Context, in *ExecPipelineRun) error
+	Rewind(ctx context.Context, in *RewindPipelineRun) error
 }
 
 type Service interface {
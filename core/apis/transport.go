@@ -8,6 +8,7 @@ import (
 
 	"git.yunify.com/quanxiang/workflow/internal/common"
 	"git.yunify.com/quanxiang/workflow/pkg/helper/errors"
+	"git.yunify.com/quanxiang/workflow/pkg/helper/signature"
 	"github.com/gin-gonic/gin"
 	"github.com/go-kit/kit/transport"
 	httptransport "github.com/go-kit/kit/transport/http"
@@ -23,8 +24,12 @@ func NewHTTPHandler(conf *common.Config, s Service, logger log.Logger) http.Hand
 		httptransport.ServerErrorEncoder(encodeError),
 	}
 
+	// keys are validated by common.GetConfig
+	signer, _ := signature.New(conf.Auth)
+
 	{
 		group := r.Group("/api/v1")
+		group.Use(verifySignature(signer))
 		group.POST("/pipeline", gin.WrapH(httptransport.NewServer(
 			e.PostSavePipelineEndpoint,
 			func(ctx context.Context, r *http.Request) (request interface{}, err error) {
@@ -66,11 +71,47 @@ func NewHTTPHandler(conf *common.Config, s Service, logger log.Logger) http.Hand
 				options...,
 			).ServeHTTP(c.Writer, c.Request)
 		})
+
+		group.POST("/pipelineRun/:id/rewind", func(c *gin.Context) {
+			id := c.Param("id")
+			httptransport.NewServer(
+				e.PostRewindPipelineRunEndpoint,
+				func(ctx context.Context, r *http.Request) (request interface{}, err error) {
+					_id, err := strconv.Atoi(id)
+					if err != nil {
+						return nil, err
+					}
+					var req RewindPipelineRun
+					if _, err := reqJSON(&req)(ctx, r); err != nil {
+						return nil, err
+					}
+					req.ID = int64(_id)
+					return &req, nil
+				},
+				responseJSON,
+				options...,
+			).ServeHTTP(c.Writer, c.Request)
+		})
 	}
 
 	return r
 }
 
+// verifySignature rejects requests that are not signed by a workflow service.
+func verifySignature(signer *signature.Signer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := signer.VerifyRequest(c.Request); err != nil {
+			encodeError(c, errors.NewErr(http.StatusUnauthorized, &errors.CodeError{
+				Code:    http.StatusUnauthorized,
+				Message: err.Error(),
+			}), c.Writer)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
 func encodeError(_ context.Context, err error, w http.ResponseWriter) {
 	if err == nil {
 		panic("encodeError with nil error")
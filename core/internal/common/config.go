@@ -4,6 +4,8 @@ import (
 	"os"
 
 	"git.yunify.com/quanxiang/workflow/pkg/helper/errors"
+	"git.yunify.com/quanxiang/workflow/pkg/helper/signature"
+	"git.yunify.com/quanxiang/workflow/pkg/node"
 	"gopkg.in/yaml.v3"
 )
 
@@ -17,6 +19,9 @@ type Config struct {
 
 	Nodes []Node `yaml:"nodes"`
 
+	// Auth signs requests to the node services and verifies requests to the core API.
+	Auth signature.Config `yaml:"auth"`
+
 	Retarder struct {
 		Enable     bool  `yaml:"enable"`
 		BufferSize int64 `yaml:"buffer_size"`
@@ -45,8 +50,30 @@ type Mysql struct {
 type Node struct {
 	Type string   `json:"type,omitempty"`
 	Host []string `json:"host,omitempty"`
+	// Local selects the in-process implementation registered by node.Register
+	// instead of calling the node service at Host.
+	Local bool `json:"local,omitempty"`
+	// Transport is the protocol used to reach Host, http (default) or grpc.
+	Transport string `json:"transport,omitempty"`
+	// TLS holds the client certificates for mutual TLS when Transport is grpc.
+	TLS node.TLSConfig `json:"tls,omitempty"`
+	// Config is the config of the local node, its fields are those of the config of the node service.
+	Config yaml.Node `json:"-" yaml:"config"`
+}
+
+// Decode decodes the config of the local node into v, leaving v as it is when there is none.
+func (n *Node) Decode(v interface{}) error {
+	if n.Config.Kind == 0 {
+		return nil
+	}
+	return n.Config.Decode(v)
 }
 
+const (
+	TransportHTTP = "http"
+	TransportGRPC = "grpc"
+)
+
 func GetConfig(path string) (*Config, error) {
 	body, err := os.ReadFile(path)
 	if err != nil {
@@ -59,5 +86,9 @@ func GetConfig(path string) (*Config, error) {
 		return nil, errors.Wrap(err, "fail unmarshal config file")
 	}
 
+	if _, err := signature.New(conf.Auth); err != nil {
+		return nil, errors.Wrap(err, "fail parse auth config")
+	}
+
 	return conf, nil
 }
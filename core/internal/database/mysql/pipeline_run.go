@@ -67,12 +67,13 @@ func (p *pipelineRun) Update(ctx context.Context, plr *database.PipelineRun) err
 	}
 
 	_, err = p.db.ExecContext(ctx,
-		`UPDATE pipeline_run set pipeline = ?, spec = ?, status = ?, state = ?, updated_at = ?`,
+		`UPDATE pipeline_run set pipeline = ?, spec = ?, status = ?, state = ?, updated_at = ? WHERE id = ?`,
 		string(plByte),
 		string(specByte),
 		string(statusByte),
 		plr.State,
 		plr.UpdatedAt,
+		plr.ID,
 	)
 
 	if err != nil {
@@ -0,0 +1,70 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"git.yunify.com/quanxiang/workflow/internal/database"
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
+)
+
+// recorder is a driver recording the statements executed, without a database.
+type recorder struct {
+	query string
+	args  []driver.NamedValue
+}
+
+func (r *recorder) Open(name string) (driver.Conn, error) {
+	return r, nil
+}
+
+func (r *recorder) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("recorder: prepare not supported")
+}
+
+func (r *recorder) Close() error {
+	return nil
+}
+
+func (r *recorder) Begin() (driver.Tx, error) {
+	return nil, errors.New("recorder: transactions not supported")
+}
+
+func (r *recorder) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	r.query, r.args = query, args
+	return driver.RowsAffected(1), nil
+}
+
+func init() {
+	sql.Register("recorder", &recorder{})
+}
+
+func TestPipelineRunUpdate(t *testing.T) {
+	db, err := sql.Open("recorder", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rec := db.Driver().(*recorder)
+
+	err = NewPipelineRun(db).Update(context.Background(), &database.PipelineRun{
+		ID:        7,
+		State:     v1alpha1.PipelineRunFinish,
+		UpdatedAt: 1000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the update must only touch the row of the pipeline run
+	if !strings.HasSuffix(rec.query, "WHERE id = ?") {
+		t.Fatalf("update of all pipeline runs: %s", rec.query)
+	}
+	if id := rec.args[len(rec.args)-1].Value; id != int64(7) {
+		t.Errorf("expect the update of pipeline run 7, got %v", id)
+	}
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+
+	"git.yunify.com/quanxiang/workflow/internal/common"
+	"git.yunify.com/quanxiang/workflow/pkg/helper/signature"
+	pn "git.yunify.com/quanxiang/workflow/pkg/node"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"google.golang.org/grpc"
+
+	// the nodes that may run in the core, set local in its config
+	_ "git.yunify.com/quanxiang/workflow/pkg/node/nodes/cc"
+	_ "git.yunify.com/quanxiang/workflow/pkg/node/nodes/email"
+	_ "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine"
+	_ "git.yunify.com/quanxiang/workflow/pkg/node/nodes/process_branch"
+	_ "git.yunify.com/quanxiang/workflow/pkg/node/nodes/quanxiang_form/create"
+	_ "git.yunify.com/quanxiang/workflow/pkg/node/nodes/quanxiang_form/update"
+	_ "git.yunify.com/quanxiang/workflow/pkg/node/nodes/webhook"
+)
+
+// newNodes returns the nodes of conf by type: the node services, and the nodes registered by
+// node.Register for the types set local. The null node is always local.
+func newNodes(ctx context.Context, conf *common.Config, logger log.Logger) map[string]pn.Interface {
+	nodes := make(map[string]pn.Interface, len(conf.Nodes)+1)
+	// keys are validated by common.GetConfig
+	signer, _ := signature.New(conf.Auth)
+	for k := range conf.Nodes {
+		n := &conf.Nodes[k]
+		if n.Local {
+			factory, ok := pn.Lookup(n.Type)
+			if !ok {
+				level.Error(logger).Log("message", "local node is not registered", "nodeType", n.Type)
+				continue
+			}
+			node, err := factory(ctx, log.With(logger, "node", n.Type), n.Decode)
+			if err != nil {
+				level.Error(logger).Log("message", err, "nodeType", n.Type)
+				continue
+			}
+			nodes[n.Type] = node
+			continue
+		}
+
+		switch n.Transport {
+		case common.TransportGRPC:
+			creds, err := n.TLS.ClientCredentials()
+			if err != nil {
+				level.Error(logger).Log("message", err, "nodeType", n.Type)
+				continue
+			}
+			nodes[n.Type] = pn.NewGRPC(n.Host, creds, logger,
+				grpc.WithUnaryInterceptor(pn.SignUnaryClientInterceptor(signer)))
+		default:
+			nodes[n.Type] = pn.New(n.Host, logger,
+				httptransport.ClientBefore(signer.RequestFunc()))
+		}
+	}
+
+	if _, ok := nodes["null"]; !ok {
+		nodes["null"] = &pn.Null{}
+	}
+	return nodes
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"git.yunify.com/quanxiang/workflow/apis"
@@ -57,12 +58,7 @@ func (s *pipelineRunService) SetConfig(conf *common.Config) {
 
 func (s *pipelineRunService) init() {
 	s.runner.logger = s.logger
-	s.runner.nodes = make(map[string]pn.Interface)
-	for _, n := range s.conf.Nodes {
-		s.runner.nodes[n.Type] = pn.New(n.Host, s.logger)
-	}
-
-	s.runner.nodes["null"] = &pn.Null{}
+	s.runner.nodes = newNodes(s.ctx, s.conf, s.logger)
 	s.runner.Run(s.ctx, s.conf.Parallel)
 
 	if s.conf.Retarder.Enable {
@@ -135,21 +131,167 @@ func (p *pipelineRunService) Exec(ctx context.Context, in *apis.ExecPipelineRun)
 	return nil
 }
 
+func (p *pipelineRunService) Rewind(ctx context.Context, in *apis.RewindPipelineRun) error {
+	// the run is not executed while it is rewound
+	unlock := p.runner.lock(in.ID)
+	defer unlock()
+
+	plr, err := p.pipelineRunRepo.Get(ctx, in.ID)
+	if err != nil {
+		level.Error(p.logger).Log("message", err.Error())
+		return errors.Wrap(err, "fail get pipepline run from database")
+	}
+	if plr == nil {
+		return errors.NewErr(http.StatusNotFound, &errors.CodeError{
+			Code:    http.StatusNotFound,
+			Message: "pipeline run not exists",
+		})
+	}
+	if plr.State.IsFinish() {
+		return errors.NewErr(http.StatusBadRequest, &errors.CodeError{
+			Code:    http.StatusBadRequest,
+			Message: "pipeline run is finish",
+		})
+	}
+
+	if in.From != "" {
+		last := plr.Status.NodeRun
+		if len(last) == 0 || last[len(last)-1].Name != in.From || last[len(last)-1].Status != v1alpha1.Pending {
+			return errors.NewErr(http.StatusConflict, &errors.CodeError{
+				Code:    http.StatusConflict,
+				Message: "pipeline run is not pending at node: " + in.From,
+			})
+		}
+	}
+
+	// node statuses are kept in the order of the pipeline nodes,
+	// so the status of the node at cursor and after it are dropped
+	cursor := 0
+	if in.Node != "" {
+		cursor = -1
+		for k, node := range plr.Pipeline.Spec.Nodes {
+			if node.Name == in.Node {
+				cursor = k
+				break
+			}
+		}
+		if cursor < 0 || cursor >= len(plr.Status.NodeRun) {
+			return errors.NewErr(http.StatusBadRequest, &errors.CodeError{
+				Code:    http.StatusBadRequest,
+				Message: "node has not been executed: " + in.Node,
+			})
+		}
+	}
+	rewound := plr.Status.NodeRun[cursor:]
+	plr.Spec.Communal = rewindCommunal(plr.Spec.Communal, plr.Status.NodeRun[:cursor], rewound)
+	plr.Status.NodeRun = plr.Status.NodeRun[:cursor]
+
+	err = p.pipelineRunRepo.Update(ctx, plr)
+	if err != nil {
+		return errors.Wrap(err, "fail update pipepline run to database")
+	}
+	// the nodes still pending are skipped, they drop the work they keep
+	for k, status := range rewound {
+		if status.Status == v1alpha1.Pending {
+			p.runner.complete(ctx, &plr.Pipeline.Spec.Nodes[cursor+k], plr, v1alpha1.Skip, "pipeline run rewound")
+		}
+	}
+	level.Info(p.logger).Log("message", "rewind pipeline run", "pipelineRunID", plr.ID, "node", in.Node)
+	p.runner.set(in.ID)
+	return nil
+}
+
+// rewindCommunal returns communal without the values written by the rewound node runs,
+// the values of the kept node runs are set back in their order.
+func rewindCommunal(communal []*v1alpha1.KeyAndValue, kept, rewound []*v1alpha1.NodeStatusSpec) []*v1alpha1.KeyAndValue {
+	dropped := make(map[string]struct{})
+	for _, status := range rewound {
+		for _, kv := range status.Communal {
+			dropped[kv.Key] = struct{}{}
+		}
+	}
+	if len(dropped) == 0 {
+		return communal
+	}
+	result := make([]*v1alpha1.KeyAndValue, 0, len(communal))
+	for _, kv := range communal {
+		if _, ok := dropped[kv.Key]; !ok {
+			result = append(result, kv)
+		}
+	}
+	for _, status := range kept {
+		for _, kv := range status.Communal {
+			if _, ok := dropped[kv.Key]; !ok {
+				continue
+			}
+			if cl := getKV(kv.Key, result); cl != nil {
+				cl.Value = kv.Value
+				continue
+			}
+			cp := *kv
+			result = append(result, &cp)
+		}
+	}
+	return result
+}
+
+// delayer executes a pipeline run again once its delay, in seconds, is over, like the retarder.
+type delayer interface {
+	Add(data retarder.Data, time int64) error
+}
+
 type runner struct {
 	logger          log.Logger
 	pipelineRunRepo database.PipelineRunRepo
-	retarder        *retarder.Retarder
+	retarder        delayer
 	ch              chan int64
 
 	delay int64
 
 	nodes map[string]pn.Interface
+
+	mu sync.Mutex
+	// locks are the locks of the pipeline runs being executed or rewound.
+	locks map[int64]*runLock
+}
+
+// runLock is the lock of a pipeline run, and the number of the callers holding or waiting for it.
+type runLock struct {
+	sync.Mutex
+	refs int
 }
 
 func (r *runner) set(id int64) {
 	r.ch <- id
 }
 
+// lock locks the pipeline run id, so that it is executed or rewound by a single caller at once,
+// until unlock is called.
+func (r *runner) lock(id int64) (unlock func()) {
+	r.mu.Lock()
+	if r.locks == nil {
+		r.locks = make(map[int64]*runLock)
+	}
+	l, ok := r.locks[id]
+	if !ok {
+		l = &runLock{}
+		r.locks[id] = l
+	}
+	l.refs++
+	r.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		r.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(r.locks, id)
+		}
+		r.mu.Unlock()
+	}
+}
+
 func (r *runner) getNode(_t string) pn.Interface {
 	i, ok := r.nodes[_t]
 	if !ok {
@@ -179,6 +321,15 @@ func (r *runner) Run(ctx context.Context, parallel int) {
 }
 
 func (r *runner) run(pipelineRunID int64) {
+	var next bool
+	defer func() {
+		// once unlocked, the runner receiving it would wait for the lock otherwise
+		if next {
+			r.set(pipelineRunID)
+		}
+	}()
+	defer r.lock(pipelineRunID)()
+
 	ctx := context.Background()
 	plr, err := r.pipelineRunRepo.Get(ctx, pipelineRunID)
 	if err != nil {
@@ -186,8 +337,6 @@ func (r *runner) run(pipelineRunID int64) {
 		return
 	}
 
-	// TODO: 增加业务锁，防止重复执行
-
 	if plr == nil {
 		level.Error(r.logger).Log("message", "fail get pipeline run", "pipelineRunID", pipelineRunID)
 		return
@@ -207,6 +356,10 @@ func (r *runner) run(pipelineRunID int64) {
 		err = r.exec(ctx, node, plr)
 		if err != nil {
 			level.Error(r.logger).Log("message", err, "pipelineRunID", plr.ID, "nodeName", node.Name)
+			// keep the error on the node status while waiting for the retry
+			if err := r.pipelineRunRepo.Update(ctx, plr); err != nil {
+				level.Error(r.logger).Log("message", err.Error(), "pipelineRunID", pipelineRunID)
+			}
 			// Join retry queue
 			if r.retarder != nil {
 				err = r.retarder.Add(pipelineRunID, r.delay)
@@ -233,33 +386,49 @@ func (r *runner) run(pipelineRunID int64) {
 		level.Error(r.logger).Log("message", err.Error(), "pipelineRunID", pipelineRunID)
 		return
 	}
+	if node != nil && plr.State == v1alpha1.PipelineRunKill {
+		r.complete(ctx, node, plr, v1alpha1.Kill, plr.Status.NodeRun[len(plr.Status.NodeRun)-1].Message)
+	}
 	if plr.Status.NodeRun[len(plr.Status.NodeRun)-1].Status != v1alpha1.Pending {
 		// try to exec next node
-		r.set(plr.ID)
+		next = true
 	}
 }
 
 func (r *runner) exec(ctx context.Context, node *v1alpha1.Node, plr *database.PipelineRun) error {
 	result, err := r.getNode(node.Spec.Type).Do(ctx, &pn.Request{
-		Params: r.parseParams(node.Spec.Params, plr),
-		Metadata: v1alpha1.Metadata{
-			Annotations: map[string]string{
-				"database.pipelineRun/id":       strconv.Itoa(int(plr.ID)),
-				"database.pipelineRunNode/name": node.Name,
-			},
-		},
+		Params:   r.parseParams(node.Spec.Params, plr),
+		Metadata: metadataOf(node, plr),
 	})
 
 	status := plr.Status.NodeRun[len(plr.Status.NodeRun)-1]
 	if err != nil {
+		// errors without a classification are treated as transient
 		level.Error(r.logger).Log("message", err)
+		status.Status = v1alpha1.Pending
 		status.Message = err.Error()
+		status.ErrorCode = pn.ErrCodeUnavailable
 		return err
 	} else {
 		status.Status = result.Status
 		status.Output = result.Out
 		status.Message = result.Message
+		status.ErrorCode = ""
+
+		if result.Error != nil {
+			status.ErrorCode = result.Error.Code
+			if result.Error.Retryable {
+				status.Status = v1alpha1.Pending
+				return result.Error
+			}
+			status.Status = v1alpha1.Kill
+		}
 
+		status.Communal = make([]*v1alpha1.KeyAndValue, 0, len(result.Communal))
+		for _, communal := range result.Communal {
+			kv := *communal
+			status.Communal = append(status.Communal, &kv)
+		}
 		for _, communal := range result.Communal {
 			cp := communal
 			cl := getKV(communal.Key, plr.Spec.Communal)
@@ -274,6 +443,7 @@ func (r *runner) exec(ctx context.Context, node *v1alpha1.Node, plr *database.Pi
 		case v1alpha1.Finish:
 			status.CompletionTime = time.Now().Unix()
 		case v1alpha1.Kill:
+			status.CompletionTime = time.Now().Unix()
 			plr.State = v1alpha1.PipelineRunKill
 		default:
 			status.Status = v1alpha1.Pending
@@ -284,6 +454,33 @@ func (r *runner) exec(ctx context.Context, node *v1alpha1.Node, plr *database.Pi
 	return nil
 }
 
+// complete tells the node of a node run ended with status, by killing or rewinding its pipeline run,
+// when it keeps work pending on its node runs. Failures are logged only, the pipeline run goes on.
+func (r *runner) complete(ctx context.Context, node *v1alpha1.Node, plr *database.PipelineRun, status v1alpha1.NodeStatus, message string) {
+	c, ok := r.getNode(node.Spec.Type).(pn.Completer)
+	if !ok {
+		return
+	}
+	err := c.Complete(ctx, &pn.CompleteRequest{
+		Metadata: metadataOf(node, plr),
+		Status:   status,
+		Message:  message,
+	})
+	if err != nil && !errors.Is(err, pn.ErrUnimplemented) {
+		level.Error(r.logger).Log("message", err, "pipelineRunID", plr.ID, "nodeName", node.Name)
+	}
+}
+
+// metadataOf returns the metadata of the node run of node in plr.
+func metadataOf(node *v1alpha1.Node, plr *database.PipelineRun) v1alpha1.Metadata {
+	return v1alpha1.Metadata{
+		Annotations: map[string]string{
+			"database.pipelineRun/id":       strconv.Itoa(int(plr.ID)),
+			"database.pipelineRunNode/name": node.Name,
+		},
+	}
+}
+
 func (r *runner) parseParams(input []*v1alpha1.KeyAndValue, plr *database.PipelineRun) (result []*v1alpha1.KeyAndValue) {
 	var getValueFromKV = func(name string, kvs []*v1alpha1.KeyAndValue) *string {
 		for _, kv := range kvs {
@@ -0,0 +1,348 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"gopkg.in/yaml.v3"
+
+	"git.yunify.com/quanxiang/workflow/apis"
+	"git.yunify.com/quanxiang/workflow/internal/common"
+	"git.yunify.com/quanxiang/workflow/internal/database"
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
+	"git.yunify.com/quanxiang/workflow/pkg/helper/errors"
+	"git.yunify.com/quanxiang/workflow/pkg/helper/retarder"
+	pn "git.yunify.com/quanxiang/workflow/pkg/node"
+)
+
+// memoryRuns keeps the pipeline runs in memory.
+type memoryRuns map[int64]*database.PipelineRun
+
+func (m memoryRuns) Create(_ context.Context, plr *database.PipelineRun) error {
+	plr.ID = int64(len(m) + 1)
+	m[plr.ID] = plr
+	return nil
+}
+
+func (m memoryRuns) Update(_ context.Context, plr *database.PipelineRun) error {
+	m[plr.ID] = plr
+	return nil
+}
+
+func (m memoryRuns) Get(_ context.Context, id int64) (*database.PipelineRun, error) {
+	return m[id], nil
+}
+
+func (m memoryRuns) ListRunning(context.Context) ([]int64, error) {
+	return nil, nil
+}
+
+func TestRunLocalNode(t *testing.T) {
+	conf := &common.Config{}
+	err := yaml.Unmarshal([]byte(`
+nodes:
+  - type: process-branch
+    local: true
+    config:
+      quanxiang_instances: []
+`), conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runs := memoryRuns{}
+	r := &runner{
+		logger:          log.NewNopLogger(),
+		pipelineRunRepo: runs,
+		ch:              make(chan int64, 10),
+		nodes:           newNodes(context.Background(), conf, log.NewNopLogger()),
+	}
+
+	branch := func(name string, ok string) v1alpha1.Node {
+		return v1alpha1.Node{
+			Name: name,
+			Spec: v1alpha1.NodeSpec{
+				Type: "null",
+				When: []v1alpha1.When{{
+					Input:    "$(task.branch.output.ok)",
+					Operator: "eq",
+					Values:   []string{ok},
+				}},
+			},
+		}
+	}
+	plr := &database.PipelineRun{
+		Pipeline: v1alpha1.Pipeline{
+			Name: "local",
+			Spec: v1alpha1.PipelineSpec{
+				Nodes: []v1alpha1.Node{
+					{
+						Name: "branch",
+						Spec: v1alpha1.NodeSpec{
+							Type:   "process-branch",
+							Params: []*v1alpha1.KeyAndValue{{Key: "rule", Value: "1 == 1"}},
+						},
+					},
+					branch("yes", "true"),
+					branch("no", "false"),
+				},
+			},
+		},
+	}
+	if err := runs.Create(context.Background(), plr); err != nil {
+		t.Fatal(err)
+	}
+
+	r.set(plr.ID)
+	for steps := 0; len(r.ch) != 0; steps++ {
+		if steps > 10 {
+			t.Fatal("pipeline run does not finish")
+		}
+		r.run(<-r.ch)
+	}
+
+	plr = runs[plr.ID]
+	if plr.State != v1alpha1.PipelineRunFinish {
+		t.Fatalf("state = %v, want finish", plr.State)
+	}
+	want := []v1alpha1.NodeStatus{v1alpha1.Finish, v1alpha1.Finish, v1alpha1.Skip}
+	if len(plr.Status.NodeRun) != len(want) {
+		t.Fatalf("%d node statuses, want %d", len(plr.Status.NodeRun), len(want))
+	}
+	for k, status := range plr.Status.NodeRun {
+		if status.Status != want[k] {
+			t.Errorf("node %s: status = %v, want %v", status.Name, status.Status, want[k])
+		}
+	}
+	if out := plr.Status.NodeRun[0].Output; len(out) != 1 || out[0].Value != "true" {
+		t.Errorf("branch output = %v", out)
+	}
+}
+
+// stubNode returns result and err whatever it is asked.
+type stubNode struct {
+	result *pn.Result
+	err    error
+}
+
+func (n stubNode) Do(context.Context, *pn.Request) (*pn.Result, error) {
+	return n.result, n.err
+}
+
+// completer is a stub node recording the node runs completed.
+type completer struct {
+	stubNode
+	completed []*pn.CompleteRequest
+}
+
+func (c *completer) Complete(_ context.Context, in *pn.CompleteRequest) error {
+	c.completed = append(c.completed, in)
+	return nil
+}
+
+// orderedRuns records the updates of the pipeline runs and the retries, in their order.
+type orderedRuns struct {
+	memoryRuns
+	calls *[]string
+}
+
+func (o orderedRuns) Update(ctx context.Context, plr *database.PipelineRun) error {
+	*o.calls = append(*o.calls, "update")
+	return o.memoryRuns.Update(ctx, plr)
+}
+
+func (o orderedRuns) Add(data retarder.Data, time int64) error {
+	*o.calls = append(*o.calls, "retry")
+	return nil
+}
+
+func TestRunNodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		node   stubNode
+		status v1alpha1.NodeStatus
+		state  v1alpha1.PipelineSatus
+		calls  []string
+	}{
+		{
+			name:   "permanent",
+			node:   stubNode{result: pn.Permanent(pn.ErrCodeInvalidParams, "bad params", nil)},
+			status: v1alpha1.Kill,
+			state:  v1alpha1.PipelineRunKill,
+			calls:  []string{"update"},
+		},
+		{
+			name:   "transient",
+			node:   stubNode{result: pn.Transient(pn.ErrCodeUpstream, "busy", nil)},
+			status: v1alpha1.Pending,
+			calls:  []string{"update", "retry"},
+		},
+		{
+			name:   "transport",
+			node:   stubNode{err: context.DeadlineExceeded},
+			status: v1alpha1.Pending,
+			calls:  []string{"update", "retry"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			runs := orderedRuns{memoryRuns: memoryRuns{}, calls: &calls}
+			r := &runner{
+				logger:          log.NewNopLogger(),
+				pipelineRunRepo: runs,
+				retarder:        runs,
+				ch:              make(chan int64, 10),
+				delay:           1,
+				nodes:           map[string]pn.Interface{"stub": tt.node},
+			}
+			plr := &database.PipelineRun{
+				Pipeline: v1alpha1.Pipeline{
+					Spec: v1alpha1.PipelineSpec{
+						Nodes: []v1alpha1.Node{{Name: "n1", Spec: v1alpha1.NodeSpec{Type: "stub"}}},
+					},
+				},
+			}
+			if err := runs.Create(context.Background(), plr); err != nil {
+				t.Fatal(err)
+			}
+
+			r.run(plr.ID)
+
+			plr = runs.memoryRuns[plr.ID]
+			status := plr.Status.NodeRun[0]
+			if status.Status != tt.status || plr.State != tt.state {
+				t.Errorf("status = %v, state = %v, want %v, %v", status.Status, plr.State, tt.status, tt.state)
+			}
+			if (status.Status == v1alpha1.Kill) != (status.CompletionTime != 0) {
+				t.Errorf("completion time = %d with status %v", status.CompletionTime, status.Status)
+			}
+			if status.ErrorCode == "" {
+				t.Error("expect the error code kept on the node status")
+			}
+			// the error is saved before the run is retried
+			if strings.Join(calls, ",") != strings.Join(tt.calls, ",") {
+				t.Errorf("calls = %v, want %v", calls, tt.calls)
+			}
+			if tt.status == v1alpha1.Pending && len(r.ch) != 0 {
+				t.Errorf("expect the run to wait for its retry")
+			}
+		})
+	}
+}
+
+func TestRewind(t *testing.T) {
+	runs := memoryRuns{}
+	pending := &completer{}
+	p := &pipelineRunService{
+		logger:          log.NewNopLogger(),
+		pipelineRunRepo: runs,
+		runner: runner{
+			logger:          log.NewNopLogger(),
+			pipelineRunRepo: runs,
+			ch:              make(chan int64, 10),
+			nodes:           map[string]pn.Interface{"pending": pending},
+		},
+	}
+	kv := func(key, value string) *v1alpha1.KeyAndValue {
+		return &v1alpha1.KeyAndValue{Key: key, Value: value}
+	}
+	plr := &database.PipelineRun{
+		Pipeline: v1alpha1.Pipeline{
+			Spec: v1alpha1.PipelineSpec{
+				Nodes: []v1alpha1.Node{{Name: "a"}, {Name: "b"}, {Name: "c", Spec: v1alpha1.NodeSpec{Type: "pending"}}},
+			},
+		},
+		Spec: v1alpha1.PipeplineRunSpec{
+			Communal: []*v1alpha1.KeyAndValue{kv("k1", "2"), kv("k2", "x"), kv("k3", "y")},
+		},
+		Status: v1alpha1.PipeplineRunStatus{
+			NodeRun: []*v1alpha1.NodeStatusSpec{
+				{Name: "a", Status: v1alpha1.Finish, Communal: []*v1alpha1.KeyAndValue{kv("k1", "1")}},
+				{Name: "b", Status: v1alpha1.Finish, Communal: []*v1alpha1.KeyAndValue{kv("k1", "2"), kv("k2", "x")}},
+				{Name: "c", Status: v1alpha1.Pending},
+			},
+		},
+	}
+	if err := runs.Create(context.Background(), plr); err != nil {
+		t.Fatal(err)
+	}
+
+	// the run is being executed, the rewind waits for it
+	unlock := p.runner.lock(plr.ID)
+	done := make(chan error)
+	go func() {
+		done <- p.Rewind(context.Background(), &apis.RewindPipelineRun{ID: plr.ID, Node: "b", From: "c"})
+	}()
+	select {
+	case <-done:
+		t.Fatal("expect the rewind to wait for the runner")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	plr = runs[plr.ID]
+	if len(plr.Status.NodeRun) != 1 || plr.Status.NodeRun[0].Name != "a" {
+		t.Fatalf("unexpected node runs %+v", plr.Status.NodeRun)
+	}
+	// the values of b are dropped, the one of a is set back and the others are kept
+	got := make([]string, 0, len(plr.Spec.Communal))
+	for _, kv := range plr.Spec.Communal {
+		got = append(got, kv.Key+"="+kv.Value)
+	}
+	if strings.Join(got, ",") != "k3=y,k1=1" {
+		t.Errorf("communal = %v", got)
+	}
+	if len(p.runner.ch) != 1 {
+		t.Error("expect the rewound run to be executed")
+	}
+	// the pending node drops its work
+	if len(pending.completed) != 1 || pending.completed[0].Status != v1alpha1.Skip ||
+		pending.completed[0].Metadata.Annotations["database.pipelineRunNode/name"] != "c" {
+		t.Errorf("unexpected completed node runs %+v", pending.completed)
+	}
+
+	// the same rewind delivered again finds the run moved on
+	err := p.Rewind(context.Background(), &apis.RewindPipelineRun{ID: plr.ID, Node: "b", From: "c"})
+	var ce *errors.Error
+	if !errors.As(err, &ce) || ce.Code != http.StatusConflict {
+		t.Errorf("expect a conflict, got %v", err)
+	}
+	if len(plr.Status.NodeRun) != 1 {
+		t.Errorf("expect the run not rewound again, got %+v", plr.Status.NodeRun)
+	}
+}
+
+func TestCompleteKilled(t *testing.T) {
+	runs := memoryRuns{}
+	killed := &completer{stubNode: stubNode{result: pn.Permanent(pn.ErrCodeNoAssignee, "nobody", nil)}}
+	r := &runner{
+		logger:          log.NewNopLogger(),
+		pipelineRunRepo: runs,
+		ch:              make(chan int64, 10),
+		nodes:           map[string]pn.Interface{"killed": killed},
+	}
+	plr := &database.PipelineRun{
+		Pipeline: v1alpha1.Pipeline{
+			Spec: v1alpha1.PipelineSpec{
+				Nodes: []v1alpha1.Node{{Name: "n1", Spec: v1alpha1.NodeSpec{Type: "killed"}}},
+			},
+		},
+	}
+	if err := runs.Create(context.Background(), plr); err != nil {
+		t.Fatal(err)
+	}
+
+	r.run(plr.ID)
+
+	if len(killed.completed) != 1 || killed.completed[0].Status != v1alpha1.Kill || killed.completed[0].Message != "nobody" {
+		t.Errorf("unexpected completed node runs %+v", killed.completed)
+	}
+}
@@ -34,6 +34,14 @@ type NodeStatusSpec struct {
 
 	// +optional
 	Message string `json:"message,omitempty"`
+
+	// ErrorCode is the code of the last error reported by the node.
+	// +optional
+	ErrorCode string `json:"errorCode,omitempty"`
+
+	// Communal are the communal values the node wrote, dropped when it is rewound.
+	// +optional
+	Communal []*KeyAndValue `json:"communal,omitempty"`
 }
 
 type PipelineSatus string
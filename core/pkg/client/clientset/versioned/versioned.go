@@ -21,15 +21,31 @@ import (
 	"github.com/go-kit/log"
 )
 
+// ErrConflict is returned when the pipeline run is not in the state a request expects,
+// like a rewind from a node the run moved on from.
+var ErrConflict = errors.New("pipeline run conflict")
+
+// IsConflict reports whether err, maybe returned after retries, is ErrConflict.
+func IsConflict(err error) bool {
+	var re lb.RetryError
+	if errors.As(err, &re) && re.Final != nil {
+		err = re.Final
+	}
+	return errors.Is(err, ErrConflict)
+}
+
 type Client interface {
 	Exec(ctx context.Context, in *apis.ExecPipeline) error
 
 	Save(ctx context.Context, in *apis.SavePipeline) error
 
 	ExecPipelineRun(ctx context.Context, in *apis.ExecPipelineRun) error
+
+	RewindPipelineRun(ctx context.Context, in *apis.RewindPipelineRun) error
 }
 
-func New(instance string, logger log.Logger) Client {
+// New returns a client of the workflow core. opts are applied to every request, e.g. to sign it.
+func New(instance string, logger log.Logger, opts ...httptransport.ClientOption) Client {
 	var endpoints apis.Endpoints
 	var instancer sd.FixedInstancer = sd.FixedInstancer{instance}
 
@@ -46,7 +62,7 @@ func New(instance string, logger log.Logger) Client {
 					err := s.Exec(ctx, req)
 					return nil, err
 				}
-			}), logger)
+			}, opts...), logger)
 			balancer := lb.NewRoundRobin(endpointer)
 			retry := lb.Retry(retryMax, retryTimeout, balancer)
 			endpoints.PostExecPipelineEndpoint = retry
@@ -59,7 +75,7 @@ func New(instance string, logger log.Logger) Client {
 					err := s.Save(ctx, req)
 					return nil, err
 				}
-			}), logger)
+			}, opts...), logger)
 			balancer := lb.NewRoundRobin(endpointer)
 			retry := lb.Retry(retryMax, retryTimeout, balancer)
 			endpoints.PostSavePipelineEndpoint = retry
@@ -72,21 +88,36 @@ func New(instance string, logger log.Logger) Client {
 					err := s.ExecPipelineRun(ctx, req)
 					return nil, err
 				}
-			}), logger)
+			}, opts...), logger)
 			balancer := lb.NewRoundRobin(endpointer)
 			retry := lb.Retry(retryMax, retryTimeout, balancer)
 			endpoints.PostExecpipelineRunEndpoint = retry
 
 		}
+		{
+			endpointer := sd.NewEndpointer(instancer, factoryFor(func(s Client) endpoint.Endpoint {
+				return func(ctx context.Context, request interface{}) (interface{}, error) {
+					req := request.(*apis.RewindPipelineRun)
+					err := s.RewindPipelineRun(ctx, req)
+					return nil, err
+				}
+			}, opts...), logger)
+			balancer := lb.NewRoundRobin(endpointer)
+			retry := lb.RetryWithCallback(retryTimeout, balancer, func(n int, err error) (bool, error) {
+				// a conflict does not go away by retrying
+				return n < retryMax && !errors.Is(err, ErrConflict), nil
+			})
+			endpoints.PostRewindPipelineRunEndpoint = retry
+		}
 
 	}
 
 	return endpoints
 }
 
-func factoryFor(makeEndpoint func(Client) endpoint.Endpoint) sd.Factory {
+func factoryFor(makeEndpoint func(Client) endpoint.Endpoint, opts ...httptransport.ClientOption) sd.Factory {
 	return func(instance string) (endpoint.Endpoint, io.Closer, error) {
-		service, err := NewClientEndPoints(instance)
+		service, err := NewClientEndPoints(instance, opts...)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -94,7 +125,7 @@ func factoryFor(makeEndpoint func(Client) endpoint.Endpoint) sd.Factory {
 	}
 }
 
-func NewClientEndPoints(instance string) (apis.Endpoints, error) {
+func NewClientEndPoints(instance string, opts ...httptransport.ClientOption) (apis.Endpoints, error) {
 	if !strings.HasPrefix(instance, "http") {
 		instance = "http://" + instance
 	}
@@ -104,7 +135,7 @@ func NewClientEndPoints(instance string) (apis.Endpoints, error) {
 	}
 	tgt.Path = ""
 
-	options := []httptransport.ClientOption{}
+	options := append([]httptransport.ClientOption{}, opts...)
 
 	return apis.Endpoints{
 		PostExecPipelineEndpoint: httptransport.NewClient("POST", tgt, func(ctx context.Context, r *http.Request, request interface{}) error {
@@ -143,6 +174,21 @@ func NewClientEndPoints(instance string) (apis.Endpoints, error) {
 			}
 			return response, err
 		}, options...).Endpoint(),
+		PostRewindPipelineRunEndpoint: httptransport.NewClient("POST", tgt, func(ctx context.Context, r *http.Request, request interface{}) error {
+			rewind := request.(*apis.RewindPipelineRun)
+			runID := strconv.FormatInt(rewind.ID, 10)
+			r.URL.Path = "/api/v1/pipelineRun/" + runID + "/rewind"
+
+			return encodeRequest(ctx, r, request)
+		}, func(ctx context.Context, resp *http.Response) (response interface{}, err error) {
+			if resp.StatusCode == http.StatusConflict {
+				return nil, ErrConflict
+			}
+			if resp.StatusCode != http.StatusOK {
+				return nil, errors.New(resp.Status)
+			}
+			return response, err
+		}, options...).Endpoint(),
 	}, nil
 }
 func encodeRequest(_ context.Context, req *http.Request, request interface{}) error {
@@ -2,8 +2,12 @@ package versioned
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/go-kit/log"
+
 	"git.yunify.com/quanxiang/workflow/apis"
 	"git.yunify.com/quanxiang/workflow/pkg/helper/logger"
 )
@@ -18,3 +22,21 @@ func TestExecPipeline(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestRewindConflict(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusConflict)
+	}))
+	defer server.Close()
+
+	c := New(server.URL, log.NewNopLogger())
+	err := c.RewindPipelineRun(context.Background(), &apis.RewindPipelineRun{ID: 1, Node: "a", From: "b"})
+	if !IsConflict(err) {
+		t.Errorf("expect a conflict, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expect a conflict not to be retried, got %d calls", calls)
+	}
+}
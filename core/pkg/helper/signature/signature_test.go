@@ -0,0 +1,145 @@
+package signature
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSigner(t *testing.T) {
+	old, err := New(Config{Keys: []string{"k1:old"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := New(Config{Keys: []string{"k2:new", "k1:old"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := New(Config{Keys: []string{"k1:other"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		signer *Signer
+		verify *Signer
+		tamper func(r *http.Request)
+		expect error
+	}{
+		{
+			name:   "same key",
+			signer: old,
+			verify: old,
+		},
+		{
+			name:   "rotated verifier accepts old key",
+			signer: old,
+			verify: rotated,
+		},
+		{
+			name:   "old verifier rejects new key",
+			signer: rotated,
+			verify: old,
+			expect: ErrUnknowKey,
+		},
+		{
+			name:   "different secret",
+			signer: old,
+			verify: other,
+			expect: ErrMismatch,
+		},
+		{
+			name:   "tampered body",
+			signer: old,
+			verify: old,
+			tamper: func(r *http.Request) {
+				r.Body = http.NoBody
+			},
+			expect: ErrMismatch,
+		},
+		{
+			name:   "tampered path",
+			signer: old,
+			verify: old,
+			tamper: func(r *http.Request) {
+				r.URL.Path = "/api/v1/pipelineRun/2/exec"
+			},
+			expect: ErrMismatch,
+		},
+		{
+			name:   "unsigned",
+			verify: old,
+			expect: ErrMissing,
+		},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", "/api/v1/pipelineRun/1/exec", bytes.NewBufferString(`{"id":1}`))
+		if err := test.signer.SignRequest(r); err != nil {
+			t.Fatal(err)
+		}
+		if test.tamper != nil {
+			test.tamper(r)
+		}
+		if err := test.verify.VerifyRequest(r); err != test.expect {
+			t.Errorf("%s: expect %v, got %v", test.name, test.expect, err)
+		}
+	}
+}
+
+func TestSignerExpired(t *testing.T) {
+	s, err := New(Config{Keys: []string{"k1:secret"}, MaxSkew: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("POST", "/api/v1/do", nil)
+	s.now = func() time.Time { return time.Now().Add(-time.Minute) }
+	if err := s.SignRequest(r); err != nil {
+		t.Fatal(err)
+	}
+	s.now = time.Now
+	if err := s.VerifyRequest(r); err != ErrExpired {
+		t.Errorf("expect %v, got %v", ErrExpired, err)
+	}
+}
+
+func TestSignerReplay(t *testing.T) {
+	s, err := New(Config{Keys: []string{"k1:secret"}, MaxSkew: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("POST", "/api/v1/pipelineRun/1/rewind", bytes.NewBufferString(`{"node":"n1"}`))
+	if err := s.SignRequest(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.VerifyRequest(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.VerifyRequest(r); err != ErrReplayed {
+		t.Errorf("expect %v, got %v", ErrReplayed, err)
+	}
+
+	// the nonce is signed too
+	r.Header.Set(HeaderNonce, "other")
+	if err := s.VerifyRequest(r); err != ErrMismatch {
+		t.Errorf("expect %v, got %v", ErrMismatch, err)
+	}
+
+	// the nonces are dropped once expired, their requests are refused as expired from then on
+	s.now = func() time.Time { return time.Now().Add(time.Minute) }
+	signed := httptest.NewRequest("POST", "/api/v1/do", nil)
+	if err := s.SignRequest(signed); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.VerifyRequest(signed); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.seen) != 1 {
+		t.Errorf("expect the expired nonce dropped, got %d nonces", len(s.seen))
+	}
+}
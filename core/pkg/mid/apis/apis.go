@@ -10,15 +10,16 @@ import (
 	"git.yunify.com/quanxiang/workflow/pkg/client/clientset/versioned"
 	"git.yunify.com/quanxiang/workflow/pkg/helper/errors"
 	"git.yunify.com/quanxiang/workflow/pkg/mid/service"
+	examineservice "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/service"
 	"github.com/gin-gonic/gin"
 	"github.com/go-kit/kit/transport"
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/go-kit/log"
 )
 
-func NewHTTPHandler(logger log.Logger, wl versioned.Client, trigger triggerclinet.Trigger, confMysql common.Mysql, workFlowInstance, homeHost string) http.Handler {
+func NewHTTPHandler(logger log.Logger, wl versioned.Client, trigger triggerclinet.Trigger, confMysql common.Mysql, workFlowInstance, homeHost string, admins []string, notify examineservice.Notify, clientOpts ...httptransport.ClientOption) http.Handler {
 	r := gin.Default()
-	e := NewEndPoints(logger, wl, trigger, confMysql, workFlowInstance, homeHost)
+	e := NewEndPoints(logger, wl, trigger, confMysql, workFlowInstance, homeHost, admins, notify, clientOpts...)
 
 	options := []httptransport.ServerOption{
 		httptransport.ServerErrorHandler(transport.NewLogErrorHandler(logger)),
@@ -190,11 +191,25 @@ func NewHTTPHandler(logger log.Logger, wl versioned.Client, trigger triggercline
 			).ServeHTTP(c.Writer, c.Request)
 		})
 		group.POST("/instance/ccToMeList", func(c *gin.Context) {
-
+			userID := c.GetHeader("User-Id")
 			httptransport.NewServer(
 				e.CCToMeListEndpoint,
 				func(ctx context.Context, r *http.Request) (request interface{}, err error) {
 					var req service.CCToMeListRequest
+					req.UserID = userID
+					return reqJSON(&req)(ctx, r)
+				},
+				responseJSON,
+				options...,
+			).ServeHTTP(c.Writer, c.Request)
+		})
+		group.POST("/instance/ccRead", func(c *gin.Context) {
+			userID := c.GetHeader("User-Id")
+			httptransport.NewServer(
+				e.CCReadEndpoint,
+				func(ctx context.Context, r *http.Request) (request interface{}, err error) {
+					var req service.CCReadRequest
+					req.UserID = userID
 					return reqJSON(&req)(ctx, r)
 				},
 				responseJSON,
@@ -286,15 +301,17 @@ func NewHTTPHandler(logger log.Logger, wl versioned.Client, trigger triggercline
 				options...,
 			).ServeHTTP(c.Writer, c.Request)
 		})
-		group.POST("/instance/cancel/:processInstanceId", func(c *gin.Context) {
+		group.POST("/instance/cancel/:processInstanceID", func(c *gin.Context) {
 
 			processInstanceID := c.Param("processInstanceID")
+			userID := c.GetHeader("User-Id")
 
 			httptransport.NewServer(
 				e.RecallEndpoint,
 				func(ctx context.Context, r *http.Request) (request interface{}, err error) {
 					var req service.RecallRequest
 					req.ProcessInstanceID = processInstanceID
+					req.UserID = userID
 					return reqJSON(&req)(ctx, r)
 				},
 				responseJSON,
@@ -19,8 +19,10 @@ import (
 	"git.yunify.com/quanxiang/workflow/pkg/client/clientset/versioned"
 	"git.yunify.com/quanxiang/workflow/pkg/mid/service"
 	"git.yunify.com/quanxiang/workflow/pkg/node"
+	examineservice "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/service"
 	quanxiangform "git.yunify.com/quanxiang/workflow/pkg/node/nodes/quanxiang_form"
 	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
 )
 
 type Endpoints struct {
@@ -44,6 +46,7 @@ type Endpoints struct {
 	GetFlowProcessEndpoint         endpoint.Endpoint //获取流程审核进度，processHistories
 	GetFormFieldPermissionEndpoint endpoint.Endpoint //获取表单字段权限，getFlowInstanceForm
 	CCToMeListEndpoint             endpoint.Endpoint //抄送给我的列表
+	CCReadEndpoint                 endpoint.Endpoint //抄送标记已读
 	ExaminedListEndpoint           endpoint.Endpoint //已审核的列表，reviewedList
 	PendingExamineListEndpoint     endpoint.Endpoint //待审核的，waitReviewList
 	PendingExamineCountEndpoint    endpoint.Endpoint //待审核的条数，getFlowInstanceCount
@@ -53,9 +56,9 @@ type Endpoints struct {
 	AppReplicationImportEndpoint endpoint.Endpoint
 }
 
-func NewEndPoints(logger log.Logger, wl versioned.Client, trigger triggerclient.Trigger, confMysql common.Mysql, workFlowInstance, homeHost string) Endpoints {
+func NewEndPoints(logger log.Logger, wl versioned.Client, trigger triggerclient.Trigger, confMysql common.Mysql, workFlowInstance, homeHost string, admins []string, notify examineservice.Notify, clientOpts ...httptransport.ClientOption) Endpoints {
 	end := Endpoints{}
-	s := service.NewOldFlow(logger, confMysql, workFlowInstance, homeHost)
+	s := service.NewOldFlow(logger, confMysql, workFlowInstance, homeHost, admins, notify, clientOpts...)
 	end.SaveFlowEndpoint = SaveFlowEndpoint(s, wl)
 	end.UpdateFlowStatusEndpoint = UpdateFlowStatusEndpoint(s, trigger)
 	end.DeleteFlowEndpoint = DeleteFlowEndpoint(s)
@@ -70,6 +73,7 @@ func NewEndPoints(logger log.Logger, wl versioned.Client, trigger triggerclient.
 	end.GetFlowProcessEndpoint = GetFlowProcessEndpoint(s)
 	end.GetFormFieldPermissionEndpoint = GetFormFieldPermissionEndpoint(s)
 	end.CCToMeListEndpoint = CCToMeListEndpoint(s)
+	end.CCReadEndpoint = CCReadEndpoint(s)
 	end.ExaminedListEndpoint = ExaminedListEndpoint(s)
 	end.PendingExamineListEndpoint = PendingExamineListEndpoint(s)
 	end.PendingExamineCountEndpoint = PendingExamineCountEndpoint(s)
@@ -156,6 +160,82 @@ func getNode(p *ProcessModel, id string) *ShapeModel {
 	}
 	return nil
 }
+
+// prefixIDs returns the ids of options, a list of objects with an id as picked in the flow
+// designer, each one prefixed by prefix.
+func prefixIDs(prefix string, options interface{}) []string {
+	list, _ := options.([]interface{})
+	ids := make([]string, 0, len(list))
+	for k := range list {
+		if option, ok := list[k].(map[string]interface{}); option != nil && ok {
+			if id, ok1 := option["id"].(string); id != "" && ok1 {
+				ids = append(ids, prefix+id)
+			}
+		}
+	}
+	return ids
+}
+
+// dealUsersOf returns the dealUsers entries of approvePersons, the approvers or recipients
+// picked in the flow designer, and the params of the flow variables they reference.
+func dealUsersOf(approvePersons map[string]interface{}) ([]string, []*v1alpha1.KeyAndValue) {
+	dealUsers := make([]string, 0)
+	params := make([]*v1alpha1.KeyAndValue, 0)
+	switch approvePersons["type"] {
+	case "person":
+		if users, ok := approvePersons["users"].([]interface{}); users != nil && ok {
+			for k := range users {
+				if u, ok1 := users[k].(map[string]interface{}); u != nil && ok1 {
+					dealUsers = append(dealUsers, "person."+u["id"].(string))
+				}
+			}
+		}
+	case "field":
+		if fields, ok := approvePersons["fields"].([]interface{}); fields != nil && ok {
+			for k := range fields {
+				dealUsers = append(dealUsers, "field."+fields[k].(string))
+			}
+		}
+	case "superior":
+		if lv, ok := approvePersons["level"].(float64); ok && lv > 1 {
+			dealUsers = append(dealUsers, fmt.Sprintf("leader.%d", int(lv)))
+		} else {
+			dealUsers = append(dealUsers, "leader")
+		}
+	case "processInitiator":
+		dealUsers = append(dealUsers, "formApplyUserID")
+	case "role":
+		dealUsers = append(dealUsers, prefixIDs("role.", approvePersons["roles"])...)
+	case "department":
+		if leaderOnly, _ := approvePersons["leaderOnly"].(bool); leaderOnly {
+			dealUsers = append(dealUsers, prefixIDs("depLeader.", approvePersons["departments"])...)
+		} else {
+			dealUsers = append(dealUsers, prefixIDs("dep.", approvePersons["departments"])...)
+		}
+	case "fieldDepartment":
+		if fields, ok := approvePersons["fields"].([]interface{}); fields != nil && ok {
+			for k := range fields {
+				dealUsers = append(dealUsers, "fieldDepLeader."+fields[k].(string))
+			}
+		}
+	case "variable":
+		if variables, ok := approvePersons["variables"].([]interface{}); variables != nil && ok {
+			for k := range variables {
+				code, _ := variables[k].(string)
+				if code == "" {
+					continue
+				}
+				dealUsers = append(dealUsers, "variable."+code)
+				params = append(params, &v1alpha1.KeyAndValue{
+					Key:   code,
+					Value: fmt.Sprintf("$(communal.%s)", code),
+				})
+			}
+		}
+	}
+	return dealUsers, params
+}
+
 func savePipline(ctx context.Context, flowID, appID, formID, flowBpm, triggerModel, flowCreatedBy string, s service.OldFlow, wl versioned.Client) error {
 	pipeline := &v1alpha1.Pipeline{}
 	//TODO: 流程变量
@@ -329,33 +409,75 @@ DONE:
 					}
 				}
 
+				if fieldPermission, ok := node.Data.BusinessData["fieldPermission"]; fieldPermission != nil && ok {
+					if b, err := json.Marshal(fieldPermission); err == nil {
+						pn.Spec.Params = append(pn.Spec.Params, &v1alpha1.KeyAndValue{
+							Key:   "fieldPermission",
+							Value: string(b),
+						})
+					}
+				}
+
 				dealUsers := make([]string, 0)
 				if basicConfig, ok := node.Data.BusinessData["basicConfig"].(map[string]interface{}); basicConfig != nil && ok {
 					pn.Spec.Params = append(pn.Spec.Params, &v1alpha1.KeyAndValue{
 						Key:   "taskType",
 						Value: basicConfig["multiplePersonWay"].(string),
 					})
+					if quorum, ok1 := basicConfig["quorum"]; quorum != nil && ok1 {
+						pn.Spec.Params = append(pn.Spec.Params, &v1alpha1.KeyAndValue{
+							Key:   "quorum",
+							Value: fmt.Sprint(quorum),
+						})
+					}
+					if remind, ok1 := basicConfig["remind"]; remind != nil && ok1 {
+						pn.Spec.Params = append(pn.Spec.Params, &v1alpha1.KeyAndValue{
+							Key:   "remind",
+							Value: fmt.Sprint(remind),
+						})
+					}
+					if timeLimit, ok1 := basicConfig["timeLimit"]; timeLimit != nil && ok1 {
+						pn.Spec.Params = append(pn.Spec.Params, &v1alpha1.KeyAndValue{
+							Key:   "timeLimit",
+							Value: fmt.Sprint(timeLimit),
+						})
+					}
 
 					if approvePersons, ok1 := basicConfig["approvePersons"].(map[string]interface{}); approvePersons != nil && ok1 {
-						switch approvePersons["type"] {
-						case "person":
-							if users, ok2 := approvePersons["users"].([]interface{}); users != nil && ok2 {
-								for k := range users {
-									if u, ok3 := users[k].(map[string]interface{}); u != nil && ok3 {
-										dealUsers = append(dealUsers, "person."+u["id"].(string))
-									}
-								}
-							}
-						case "field":
-							if fields, ok2 := approvePersons["fields"].([]interface{}); fields != nil && ok2 {
-								for k := range fields {
-									dealUsers = append(dealUsers, "field."+fields[k].(string))
-								}
+						users, params := dealUsersOf(approvePersons)
+						dealUsers = append(dealUsers, users...)
+						pn.Spec.Params = append(pn.Spec.Params, params...)
+					}
+					if rules, ok1 := basicConfig["autoApprove"].([]interface{}); rules != nil && ok1 {
+						list := make([]string, 0, len(rules))
+						for k := range rules {
+							if rule, ok2 := rules[k].(string); rule != "" && ok2 {
+								list = append(list, rule)
 							}
-						case "superior":
-							dealUsers = append(dealUsers, "leader")
-						case "processInitiator":
-							dealUsers = append(dealUsers, "formApplyUserID")
+						}
+						pn.Spec.Params = append(pn.Spec.Params, &v1alpha1.KeyAndValue{
+							Key:   "autoApprove",
+							Value: strings.Join(list, ","),
+						})
+					}
+					if noApprover, ok1 := basicConfig["noApprover"].(string); noApprover != "" && ok1 {
+						pn.Spec.Params = append(pn.Spec.Params, &v1alpha1.KeyAndValue{
+							Key:   "noApprover",
+							Value: noApprover,
+						})
+					}
+					if resubmit, ok1 := basicConfig["resubmit"].(string); resubmit != "" && ok1 {
+						pn.Spec.Params = append(pn.Spec.Params, &v1alpha1.KeyAndValue{
+							Key:   "resubmit",
+							Value: resubmit,
+						})
+					}
+					for _, option := range []string{"dedupe", "skipInitiator"} {
+						if on, _ := basicConfig[option].(bool); on {
+							pn.Spec.Params = append(pn.Spec.Params, &v1alpha1.KeyAndValue{
+								Key:   option,
+								Value: "true",
+							})
 						}
 					}
 				}
@@ -364,6 +486,42 @@ DONE:
 					Value: strings.Join(dealUsers, ","),
 				})
 
+			case "cc":
+				pn.Spec.Type = "cc"
+				pn.Spec.Params = []*v1alpha1.KeyAndValue{
+					{
+						Key:   "appID",
+						Value: "$(params.appID)",
+					},
+					{
+						Key:   "tableID",
+						Value: "$(params.tableID)",
+					},
+					{
+						Key:   "dataID",
+						Value: "$(params.dataID)",
+					},
+					{
+						Key:   "flowID",
+						Value: flowID,
+					},
+				}
+				dealUsers := make([]string, 0)
+				if approvePersons, ok := node.Data.BusinessData["approvePersons"].(map[string]interface{}); approvePersons != nil && ok {
+					users, params := dealUsersOf(approvePersons)
+					dealUsers = append(dealUsers, users...)
+					pn.Spec.Params = append(pn.Spec.Params, params...)
+				}
+				if on, _ := node.Data.BusinessData["skipInitiator"].(bool); on {
+					pn.Spec.Params = append(pn.Spec.Params, &v1alpha1.KeyAndValue{
+						Key:   "skipInitiator",
+						Value: "true",
+					})
+				}
+				pn.Spec.Params = append(pn.Spec.Params, &v1alpha1.KeyAndValue{
+					Key:   "dealUsers",
+					Value: strings.Join(dealUsers, ","),
+				})
 			case "processBranch":
 				pn.Spec.Type = "process-branch"
 				pn.Spec.Params = []*v1alpha1.KeyAndValue{
@@ -716,6 +874,15 @@ func CCToMeListEndpoint(s service.OldFlow) endpoint.Endpoint {
 	}
 }
 
+func CCReadEndpoint(s service.OldFlow) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(*service.CCReadRequest)
+		response, err := s.CCRead(ctx, req)
+		r := resp.Format(response, err)
+		return r, err
+	}
+}
+
 func ExaminedListEndpoint(s service.OldFlow) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(*service.ExaminedListRequest)
@@ -8,8 +8,11 @@ import (
 	"git.yunify.com/quanxiang/workflow/internal/common"
 	"git.yunify.com/quanxiang/workflow/pkg/client/clientset/versioned"
 	"git.yunify.com/quanxiang/workflow/pkg/helper/errors"
+	"git.yunify.com/quanxiang/workflow/pkg/helper/signature"
 	"git.yunify.com/quanxiang/workflow/pkg/log"
 	"git.yunify.com/quanxiang/workflow/pkg/mid/apis"
+	examineservice "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/service"
+	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/go-kit/log/level"
 	"gopkg.in/yaml.v3"
 	"net/http"
@@ -24,6 +27,10 @@ type Config struct {
 	WorkFlowInstance string `yaml:"workflow_instance"`
 	TriggerInstance  string `yaml:"trigger_instance"`
 	HomeHost         string `yaml:"home_host"`
+	// Admins may act on every approval task.
+	Admins []string `yaml:"admins"`
+	// Notify configures the messages about approval tasks.
+	Notify examineservice.Notify `yaml:"notify"`
 }
 
 func GetConfig(path string) (*Config, error) {
@@ -38,6 +45,10 @@ func GetConfig(path string) (*Config, error) {
 		return nil, errors.Wrap(err, "fail unmarshal config file")
 	}
 
+	if _, err := signature.New(conf.Auth); err != nil {
+		return nil, errors.Wrap(err, "fail parse auth config")
+	}
+
 	return conf, nil
 }
 
@@ -51,9 +62,12 @@ func main() {
 	}
 	logger := log.NewLogger(log.LogLevelDebug)
 	ctx := context.Background()
-	client := versioned.New(conf.WorkFlowInstance, logger)
+	// keys are validated by GetConfig
+	signer, _ := signature.New(conf.Auth)
+	sign := httptransport.ClientBefore(signer.RequestFunc())
+	client := versioned.New(conf.WorkFlowInstance, logger, sign)
 	trigger := triggerclient.New(conf.TriggerInstance, logger)
-	h := apis.NewHTTPHandler(logger, client, trigger, conf.Mysql, conf.WorkFlowInstance, conf.HomeHost)
+	h := apis.NewHTTPHandler(logger, client, trigger, conf.Mysql, conf.WorkFlowInstance, conf.HomeHost, conf.Admins, conf.Notify, sign)
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", conf.Port),
 		Handler: h,
@@ -12,9 +12,11 @@ import (
 	"git.yunify.com/quanxiang/workflow/pkg/mid/db"
 	oldflowmysql "git.yunify.com/quanxiang/workflow/pkg/mid/db/mysql"
 	"git.yunify.com/quanxiang/workflow/pkg/node"
+	examinedb "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
 	examineservice "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/service"
 	"git.yunify.com/quanxiang/workflow/pkg/thirdparty/quanxiang"
 	"github.com/go-kit/kit/log/level"
+	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/go-kit/log"
 	"github.com/quanxiang-cloud/cabin/id"
 	"github.com/quanxiang-cloud/cabin/time"
@@ -36,6 +38,7 @@ type OldFlow interface {
 	GetFlowProcess(ctx context.Context, req *GetFlowProcessRequest) (*GetFlowProcessResponse, error)
 	GetFormFieldPermission(ctx context.Context, req *GetFormFieldPermissionRequest) (*GetFormFieldPermissionResponse, error)
 	CCToMeList(ctx context.Context, req *CCToMeListRequest) (*CCToMeListResponse, error)
+	CCRead(ctx context.Context, req *CCReadRequest) (*CCReadResponse, error)
 	ExaminedList(ctx context.Context, req *ExaminedListRequest) (*ExaminedListResponse, error)
 	PendingExamineList(ctx context.Context, req *PendingExamineListRequest) (*PendingExamineListResponse, error)
 	PendingExamineCount(ctx context.Context, req *PendingExamineCountRequest) (*PendingExamineCountResponse, error)
@@ -63,12 +66,12 @@ const (
 	AppDeleteStatus  = "DELETE"
 )
 
-func NewOldFlow(logger log.Logger, mysqlConf common.Mysql, workFlowInstance, homeHost string) OldFlow {
+func NewOldFlow(logger log.Logger, mysqlConf common.Mysql, workFlowInstance, homeHost string, admins []string, notify examineservice.Notify, clientOpts ...httptransport.ClientOption) OldFlow {
 	newDB, err := oldflowmysql.NewDB(&mysqlConf)
 	if err != nil {
 		panic(err)
 	}
-	examineTask := examineservice.NewTask(newDB, nil, logger, workFlowInstance, homeHost)
+	examineTask := examineservice.NewTask(newDB, nil, logger, workFlowInstance, homeHost, admins, notify, clientOpts...)
 	quanxiang := quanxiang.New(nil, logger)
 
 	oldFlowRepo := oldflowmysql.NewOldFlow(newDB)
@@ -867,6 +870,7 @@ type HandleTaskModel struct {
 	Remark           string                 `json:"remark"`
 	TaskDefKey       string                 `json:"taskDefKey"`
 	AttachFiles      []AttachFileModel      `json:"attachFiles"`
+	Signature        string                 `json:"signature,omitempty"`
 	HandleUserIDs    []string               `json:"handleUserIds"`
 	CorrelationIDs   []string               `json:"correlationIds"`
 	FormData         map[string]interface{} `json:"formData"`
@@ -935,12 +939,15 @@ func (t *oldFlow) GetFlowProcess(ctx context.Context, req *GetFlowProcessRequest
 			level.Error(t.logger).Log("message", "GetFlowProcess", "getExamineNodes by flowID err ", err)
 			return nil, err
 		}
-		examMap := make(map[string]*examineservice.ExamineNodeInfo)
+		examMap := make(map[string][]*examineservice.ExamineNodeInfo)
 		for k := range exmaineTasks.Data {
-			examMap[exmaineTasks.Data[k].NodeDefKey] = &exmaineTasks.Data[k]
+			if exmaineTasks.Data[k].TaskID != task.Data.TaskID {
+				continue
+			}
+			examMap[exmaineTasks.Data[k].NodeDefKey] = append(examMap[exmaineTasks.Data[k].NodeDefKey], &exmaineTasks.Data[k])
 		}
 		for i := len(examineNodes) - 1; i >= 0; i-- {
-			for k, v := range examMap {
+			for k, tasks := range examMap {
 				instanceStep := &InstanceStep{}
 				if k == examineNodes[i].ID {
 
@@ -957,37 +964,20 @@ func (t *oldFlow) GetFlowProcess(ctx context.Context, req *GetFlowProcessRequest
 						instanceStep.TaskType = "OR_APPROVE"
 					case "and":
 						instanceStep.TaskType = "AND_APPROVE"
+					case examineservice.ExamineSequence:
+						instanceStep.TaskType = "SEQUENCE_APPROVE"
+					case examineservice.ExamineQuorum:
+						instanceStep.TaskType = "QUORUM_APPROVE"
 					}
 
+					v := tasks[0]
 					instanceStep.ProcessInstanceID = v.TaskID
 					instanceStep.CreateTime = time.Format(v.CreatedAt)
 					instanceStep.ModifyTime = time.Format(v.UpdatedAt)
 					instanceStep.TaskDefKey = examineNodes[i].ID
-					op := &OperationRecord{}
-					op.CreatorID = v.UserID
-					userInfo, _ := t.quanxiang.GetUserInfo(ctx, &quanxiang.GetUsersInfoRequest{
-						ID: v.UserID,
-					})
-					op.TaskDefKey = examineNodes[i].ID
-					op.ID = v.ID
-					op.InstanceStepID = v.TaskID
-					op.ProcessInstanceID = v.TaskID
-					op.TaskID = v.ID
-					op.CreatorName = userInfo.Data.Name
-					switch v.Result {
-					case "agree":
-						op.HandleType = "AGREE"
-					case "reject":
-						op.HandleType = "REFUSE"
-					}
-					switch v.NodeResult {
-					case string(v1alpha1.Finish):
-						op.Status = "COMPLETE"
-					case string(v1alpha1.Pending):
-						op.Status = "ACTIVE"
+					for _, v := range tasks {
+						instanceStep.OperationRecords = append(instanceStep.OperationRecords, t.operationRecords(ctx, examineNodes[i].ID, v)...)
 					}
-					op.HandleType = "UNTREATED"
-					instanceStep.OperationRecords = append(instanceStep.OperationRecords, op)
 					response.Data = append(response.Data, instanceStep)
 				} else {
 					if approvePersons, ok1 := examineNodes[i].Data.BusinessData["basicConfig"].(map[string]interface{})["approvePersons"].(map[string]interface{}); approvePersons != nil && ok1 {
@@ -1050,6 +1040,121 @@ func (t *oldFlow) GetFlowProcess(ctx context.Context, req *GetFlowProcessRequest
 	return response, nil
 }
 
+func (t *oldFlow) operationRecord(ctx context.Context, taskDefKey string, v *examineservice.ExamineNodeInfo) *OperationRecord {
+	op := &OperationRecord{}
+	op.CreatorID = v.UserID
+	userInfo, _ := t.quanxiang.GetUserInfo(ctx, &quanxiang.GetUsersInfoRequest{
+		ID: v.UserID,
+	})
+	op.TaskDefKey = taskDefKey
+	op.ID = v.ID
+	op.InstanceStepID = v.TaskID
+	op.ProcessInstanceID = v.TaskID
+	op.TaskID = v.ID
+	op.Remark = v.Remark
+	op.CreateTime = time.Format(v.CreatedAt)
+	op.ModifyTime = time.Format(v.UpdatedAt)
+	if userInfo != nil && userInfo.Data != nil {
+		op.CreatorName = userInfo.Data.Name
+	}
+	switch v.NodeResult {
+	case string(v1alpha1.Finish), examinedb.NodeResultArchived:
+		op.Status = "COMPLETE"
+	case string(v1alpha1.Pending):
+		op.Status = "ACTIVE"
+	}
+	switch v.Result {
+	case examineservice.ResultAgree:
+		op.HandleType = "AGREE"
+	case examineservice.ResultReject:
+		op.HandleType = "REFUSE"
+	case examineservice.ResultTransfer:
+		op.HandleType = "DELIVER"
+		op.HandleUserID = v.Substitute
+	case examineservice.ResultSendBack:
+		op.HandleType = "SEND_BACK"
+	case examineservice.ResultResubmit:
+		op.HandleType = "RESUBMIT"
+	default:
+		op.HandleType = "UNTREATED"
+		if v.ExamineType == examineservice.ExamineResubmit || v.ExamineType == examineservice.ExamineResubmitStart {
+			op.HandleType = "WAIT_RESUBMIT"
+		}
+	}
+	return op
+}
+
+// operationRecords returns a record per action taken on the task v,
+// followed by the one of v itself while it waits for its approver.
+func (t *oldFlow) operationRecords(ctx context.Context, taskDefKey string, v *examineservice.ExamineNodeInfo) []*OperationRecord {
+	if len(v.Actions) == 0 {
+		return []*OperationRecord{t.operationRecord(ctx, taskDefKey, v)}
+	}
+
+	records := make([]*OperationRecord, 0, len(v.Actions)+1)
+	for _, action := range v.Actions {
+		op := &OperationRecord{}
+		op.ID = action.ID
+		op.TaskDefKey = taskDefKey
+		op.InstanceStepID = v.TaskID
+		op.ProcessInstanceID = v.TaskID
+		op.TaskID = v.ID
+		op.Remark = action.Remark
+		op.Status = "COMPLETE"
+		op.CreatorID = action.UserID
+		op.CreateTime = time.Format(action.CreatedAt)
+		op.ModifyTime = time.Format(action.CreatedAt)
+		if userInfo, _ := t.quanxiang.GetUserInfo(ctx, &quanxiang.GetUsersInfoRequest{
+			ID: action.UserID,
+		}); userInfo != nil && userInfo.Data != nil {
+			op.CreatorName = userInfo.Data.Name
+		}
+		switch action.Action {
+		case examineservice.ResultAgree:
+			op.HandleType = "AGREE"
+		case examineservice.ResultReject:
+			op.HandleType = "REFUSE"
+		case examineservice.ResultTransfer:
+			op.HandleType = "DELIVER"
+			op.HandleUserID = action.Target
+		case examineservice.ResultSendBack:
+			op.HandleType = "SEND_BACK"
+			op.RelNodeDefKey = action.Target
+		case examineservice.ResultRecall:
+			op.HandleType = "CANCEL"
+		case examineservice.ActionUrge:
+			op.HandleType = "URGE"
+		case examineservice.ActionAddSigner:
+			op.HandleType = "ADD_SIGN"
+		case examineservice.ActionAutoAgree:
+			op.HandleType = "AUTO_REVIEW"
+		case examineservice.ResultResubmit:
+			op.HandleType = "RESUBMIT"
+		}
+		op.HandleTaskModel = HandleTaskModel{
+			HandleType:    op.HandleType,
+			Remark:        action.Remark,
+			TaskDefKey:    taskDefKey,
+			Signature:     action.Signature,
+			RelNodeDefKey: op.RelNodeDefKey,
+		}
+		if action.Action == examineservice.ActionAddSigner {
+			op.HandleTaskModel.HandleUserIDs = strings.Split(action.Target, ",")
+		}
+		for _, file := range action.Attachments {
+			op.HandleTaskModel.AttachFiles = append(op.HandleTaskModel.AttachFiles, AttachFileModel{
+				FileName: file.FileName,
+				FileURL:  file.FileURL,
+			})
+		}
+		records = append(records, op)
+	}
+	if v.NodeResult == string(v1alpha1.Pending) || v.NodeResult == examinedb.NodeResultWaiting {
+		records = append(records, t.operationRecord(ctx, taskDefKey, v))
+	}
+	return records
+}
+
 func getExamineNodes(ctx context.Context, flowBpm string) ([]ShapeModel, error) {
 
 	p := &ProcessModel{}
@@ -1281,9 +1386,10 @@ func getNode(p *ProcessModel, id string) *ShapeModel {
 type CCToMeListRequest struct {
 	ReqPage
 	AppID     string `json:"appId"`
-	Status    int8   `json:"status"` // status: 1 ，0, -1查全部
+	Status    int8   `json:"status"` // status: 1 已读，0 未读, -1查全部
 	Keyword   string `json:"keyword"`
 	OrderType string `json:"orderType"` // orderType: ASC|DESC
+	UserID    string
 }
 type ReqPage struct {
 	Page   int         `json:"page"`
@@ -1304,7 +1410,123 @@ type CCToMeListResponse struct {
 }
 
 func (t *oldFlow) CCToMeList(ctx context.Context, req *CCToMeListRequest) (*CCToMeListResponse, error) {
-	return &CCToMeListResponse{}, nil
+	var status string
+	switch req.Status {
+	case 1:
+		status = examineservice.CCRead
+	case 0:
+		status = examineservice.CCUnread
+	}
+	ccs, err := t.examineService.ListCC(ctx, &examineservice.ListCCRequest{
+		UserID: req.UserID,
+		AppID:  req.AppID,
+		Status: status,
+		Page:   req.Page,
+		Limit:  req.Size,
+	})
+	if err != nil {
+		return &CCToMeListResponse{}, err
+	}
+	resp := make([]PendingExamineData, 0, len(ccs.CCs))
+	for _, cc := range ccs.CCs {
+		formData, err := t.quanxiang.GetFormData(ctx, &quanxiang.GetFormDataRequest{
+			AppID:  cc.AppID,
+			FormID: cc.FormTableID,
+			DataID: cc.FormDataID,
+		})
+		if err != nil {
+			level.Error(t.logger).Log("message", "CCToMeList get qx form data", "appID", cc.AppID, "formID", cc.FormTableID, "dataID", cc.FormDataID, "err", err)
+			return &CCToMeListResponse{}, err
+		}
+		if formData == nil || len(formData.Entity) == 0 {
+			continue
+		}
+		formSchema, err := t.quanxiang.GetFormSchema(ctx, &quanxiang.GetFormSchemaRequest{
+			AppID:  cc.AppID,
+			FormID: cc.FormTableID,
+		})
+		if err != nil {
+			level.Error(t.logger).Log("message", "CCToMeList get qx form schema", "appID", cc.AppID, "formID", cc.FormTableID, "err", err)
+			return &CCToMeListResponse{}, err
+		}
+		if formSchema == nil {
+			continue
+		}
+		flow, err := t.oldFlowRepo.Get(ctx, cc.FlowID)
+		if err != nil {
+			level.Error(t.logger).Log("message", "CCToMeList get flow info err", cc.FlowID, err)
+			return &CCToMeListResponse{}, err
+		}
+		flowInfo := SaveFlowRequest{}
+		err = json.Unmarshal([]byte(flow.FlowJson), &flowInfo)
+		if err != nil {
+			level.Error(t.logger).Log("message", "CCToMeList get flow info decode err", cc.FlowID, err)
+			return &CCToMeListResponse{}, err
+		}
+
+		data := PendingExamineData{
+			ID:          cc.ID,
+			TaskDefKey:  cc.NodeDefKey,
+			ProcInstID:  cc.TaskID,
+			Name:        "抄送",
+			Description: "CC",
+			Assignee:    cc.UserID,
+			StartTime:   time.Format(cc.CreatedAt),
+			Handled:     "ACTIVE",
+			FlowInstanceEntity: FlowInstanceEntity{
+				ID:                cc.TaskID,
+				AppID:             cc.AppID,
+				FormID:            cc.FormTableID,
+				FormData:          formData.Entity,
+				FormSchema:        formSchema.Schema,
+				ProcessInstanceID: cc.TaskID,
+				Name:              flowInfo.Name,
+				FlowID:            flowInfo.ID,
+				CreateTime:        time.Format(cc.CreatedAt),
+				KeyFields:         strings.Split(flowInfo.KeyFields, ","),
+			},
+		}
+		if cc.ReadAt != 0 {
+			data.Handled = "DONE"
+			data.EndTime = time.Format(cc.ReadAt)
+		}
+		if v, ok := formData.Entity["creator_id"].(string); v != "" && ok {
+			data.FlowInstanceEntity.ApplyUserID = v
+			data.FlowInstanceEntity.CreatorID = v
+		}
+		if v, ok := formData.Entity["creator_name"].(string); v != "" && ok {
+			data.FlowInstanceEntity.ApplyUserName = v
+			data.FlowInstanceEntity.CreatorName = v
+		}
+		resp = append(resp, data)
+	}
+	return &CCToMeListResponse{
+		TotalCount: ccs.Total,
+		Data:       resp,
+	}, nil
+}
+
+type CCReadRequest struct {
+	// IDs are the ccs to mark as read, all the ccs of the user when empty.
+	IDs    []string `json:"ids"`
+	UserID string
+}
+type CCReadResponse struct {
+	Read int64 `json:"read"`
+}
+
+// CCRead marks the ccs of the user as read.
+func (t *oldFlow) CCRead(ctx context.Context, req *CCReadRequest) (*CCReadResponse, error) {
+	res, err := t.examineService.ReadCC(ctx, &examineservice.ReadCCRequest{
+		UserID: req.UserID,
+		IDs:    req.IDs,
+	})
+	if err != nil {
+		return &CCReadResponse{}, err
+	}
+	return &CCReadResponse{
+		Read: res.Read,
+	}, nil
 }
 
 type ExaminedListRequest struct {
@@ -1602,18 +1824,31 @@ type PendingExamineCountResponse struct {
 }
 
 func (t *oldFlow) PendingExamineCount(ctx context.Context, req *PendingExamineCountRequest) (*PendingExamineCountResponse, error) {
-	response, err := t.examineService.GetByUserID(ctx, &examineservice.GetByUserIDRequest{
-		UserID:     req.UserID,
-		NodeResult: string(v1alpha1.Pending),
-		Page:       1,
-		Limit:      999,
+	response, err := t.examineService.Stats(ctx, &examineservice.StatsRequest{
+		CallerID: req.UserID,
+		UserID:   req.UserID,
 	})
 	res := &PendingExamineCountResponse{}
 	if err != nil {
 		level.Error(t.logger).Log("message", "count examine num", "userid:"+req.UserID+" err", err)
 		return res, err
 	}
-	res.WaitHandleCount = response.Total
+	// the tasks transferred to the user also match, grouped under their first approver
+	for _, stats := range response.Stats {
+		res.WaitHandleCount += int(stats.Pending)
+		res.OverTimeCount += int(stats.Overdue)
+		res.UrgeCount += int(stats.Urged)
+	}
+	ccs, err := t.examineService.ListCC(ctx, &examineservice.ListCCRequest{
+		UserID: req.UserID,
+		Status: examineservice.CCUnread,
+		Limit:  1,
+	})
+	if err != nil {
+		level.Error(t.logger).Log("message", "count cc num", "userid:"+req.UserID+" err", err)
+		return res, err
+	}
+	res.CCToMeCount = int(ccs.Total)
 	return res, nil
 }
 
@@ -1755,7 +1990,7 @@ func (t *oldFlow) MyApplyList(ctx context.Context, req *MyApplyListRequest) (*My
 }
 
 type ExamineRequest struct {
-	UserID            string                   //header里面
+	UserID            string                   `json:"-"` //header里面
 	ProcessInstanceID string                   //url里面
 	TaskID            string                   //url里面
 	HandleType        string                   `json:"handleType"`
@@ -1763,6 +1998,7 @@ type ExamineRequest struct {
 	Remark            string                   `json:"remark"`
 	TaskDefKey        string                   `json:"taskDefKey"`
 	AttachFiles       []AttachFileModel        `json:"attachFiles"`
+	Signature         string                   `json:"signature"` // 手写签名文件地址
 	HandleUserIDs     []string                 `json:"handleUserIds"`
 	CorrelationIDs    []string                 `json:"correlationIds"`
 	FormData          *examineservice.FormData `json:"formData"`
@@ -1778,11 +2014,13 @@ func (t *oldFlow) Examine(ctx context.Context, req *ExamineRequest) (bool, error
 	switch req.HandleType {
 	case "AGREE":
 		_, err := t.examineService.Agree(ctx, &examineservice.AgreeRequest{
-			UserID:    req.UserID,
-			ExamineID: req.TaskID,
-			TaskID:    req.ProcessInstanceID,
-			ForMData:  req.FormData,
-			Remark:    req.Remark,
+			UserID:      req.UserID,
+			ExamineID:   req.TaskID,
+			TaskID:      req.ProcessInstanceID,
+			ForMData:    req.FormData,
+			Remark:      req.Remark,
+			Attachments: attachments(req.AttachFiles),
+			Signature:   req.Signature,
 		})
 		if err != nil {
 			return b, err
@@ -1791,9 +2029,23 @@ func (t *oldFlow) Examine(ctx context.Context, req *ExamineRequest) (bool, error
 
 	case "REFUSE":
 		_, err := t.examineService.Reject(ctx, &examineservice.RejectRequest{
+			UserID:      req.UserID,
+			ExamineID:   req.TaskID,
+			TaskID:      req.ProcessInstanceID,
+			Remark:      req.Remark,
+			Attachments: attachments(req.AttachFiles),
+			Signature:   req.Signature,
+			ForMData:    req.FormData,
+		})
+		if err != nil {
+			return b, err
+		}
+		b = true
+
+	case "RESUBMIT":
+		_, err := t.examineService.Resubmit(ctx, &examineservice.ResubmitRequest{
 			UserID:    req.UserID,
 			ExamineID: req.TaskID,
-			TaskID:    req.ProcessInstanceID,
 			Remark:    req.Remark,
 			ForMData:  req.FormData,
 		})
@@ -1805,9 +2057,20 @@ func (t *oldFlow) Examine(ctx context.Context, req *ExamineRequest) (bool, error
 	return b, err
 }
 
+func attachments(files []AttachFileModel) []examinedb.Attachment {
+	list := make([]examinedb.Attachment, 0, len(files))
+	for _, file := range files {
+		list = append(list, examinedb.Attachment{
+			FileName: file.FileName,
+			FileURL:  file.FileURL,
+		})
+	}
+	return list
+}
+
 type UrgeRequest struct {
 	ProcessInstanceID string `json:"processInstanceID" binding:"required"`
-	UserID            string
+	UserID            string `json:"-"`
 }
 type UrgeResponse struct {
 }
@@ -1819,6 +2082,7 @@ func (t *oldFlow) Urge(ctx context.Context, req *UrgeRequest) (*UrgeResponse, er
 
 type RecallRequest struct {
 	ProcessInstanceID string `json:"processInstanceID"`
+	UserID            string `json:"-"`
 }
 type RecallResponse struct {
 	bool
@@ -1826,7 +2090,7 @@ type RecallResponse struct {
 
 func (t *oldFlow) Recall(ctx context.Context, req *RecallRequest) (*RecallResponse, error) {
 	//todo 撤回流程时，如果流程没有完成可以撤回，完成了则不行
-	_, err := t.examineService.Recall(ctx, &examineservice.RecallRequest{TaskID: req.ProcessInstanceID})
+	_, err := t.examineService.Recall(ctx, &examineservice.RecallRequest{TaskID: req.ProcessInstanceID, UserID: req.UserID})
 	return &RecallResponse{}, err
 }
 
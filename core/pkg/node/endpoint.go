@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
@@ -18,16 +20,22 @@ import (
 )
 
 type Endpoints struct {
-	DoEndpoint endpoint.Endpoint
+	DoEndpoint       endpoint.Endpoint
+	DescribeEndpoint endpoint.Endpoint
+	CompleteEndpoint endpoint.Endpoint
 }
 
 func NewEndPoints(s Interface) Endpoints {
 	return Endpoints{
-		DoEndpoint: DoEndpoint(s),
+		DoEndpoint:       DoEndpoint(s),
+		DescribeEndpoint: DescribeEndpoint(s),
+		CompleteEndpoint: CompleteEndpoint(s),
 	}
 }
 
-func New(instance []string, logger log.Logger) Interface {
+// New returns a node client that calls the node services over HTTP.
+// opts are applied to every request, e.g. to sign it.
+func New(instance []string, logger log.Logger, opts ...httptransport.ClientOption) Interface {
 	var endpoints Endpoints
 	var instancer sd.FixedInstancer = sd.FixedInstancer(instance)
 
@@ -37,19 +45,43 @@ func New(instance []string, logger log.Logger) Interface {
 	)
 
 	{
-		factory := factoryFor(DoEndpoint)
+		factory := factoryFor(DoEndpoint, opts...)
 		endpointer := sd.NewEndpointer(instancer, factory, logger)
 		balancer := lb.NewRoundRobin(endpointer)
 		retry := lb.Retry(retryMax, retryTimeout, balancer)
 		endpoints.DoEndpoint = retry
 	}
+	{
+		factory := factoryFor(DescribeEndpoint, opts...)
+		endpointer := sd.NewEndpointer(instancer, factory, logger)
+		balancer := lb.NewRoundRobin(endpointer)
+		retry := lb.RetryWithCallback(retryTimeout, balancer, retryImplemented(retryMax))
+		endpoints.DescribeEndpoint = retry
+	}
+	{
+		factory := factoryFor(CompleteEndpoint, opts...)
+		endpointer := sd.NewEndpointer(instancer, factory, logger)
+		balancer := lb.NewRoundRobin(endpointer)
+		retry := lb.RetryWithCallback(retryTimeout, balancer, retryImplemented(retryMax))
+		endpoints.CompleteEndpoint = retry
+	}
 
 	return endpoints
 }
 
-func factoryFor(makeEndpoint func(Interface) endpoint.Endpoint) sd.Factory {
+// retryImplemented retries up to max times, but not the calls the node does not implement.
+func retryImplemented(max int) lb.Callback {
+	return func(n int, err error) (bool, error) {
+		if errors.Is(err, ErrUnimplemented) {
+			return false, nil
+		}
+		return n < max, nil
+	}
+}
+
+func factoryFor(makeEndpoint func(Interface) endpoint.Endpoint, opts ...httptransport.ClientOption) sd.Factory {
 	return func(instance string) (endpoint.Endpoint, io.Closer, error) {
-		service, err := NewClientEndPoints(instance)
+		service, err := NewClientEndPoints(instance, opts...)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -57,7 +89,7 @@ func factoryFor(makeEndpoint func(Interface) endpoint.Endpoint) sd.Factory {
 	}
 }
 
-func NewClientEndPoints(instance string) (Endpoints, error) {
+func NewClientEndPoints(instance string, opts ...httptransport.ClientOption) (Endpoints, error) {
 	if !strings.HasPrefix(instance, "http") {
 		instance = "http://" + instance
 	}
@@ -67,7 +99,7 @@ func NewClientEndPoints(instance string) (Endpoints, error) {
 	}
 	tgt.Path = ""
 
-	options := []httptransport.ClientOption{}
+	options := append([]httptransport.ClientOption{}, opts...)
 
 	return Endpoints{
 		DoEndpoint: httptransport.NewClient("POST", tgt, func(ctx context.Context, r *http.Request, request interface{}) error {
@@ -76,13 +108,49 @@ func NewClientEndPoints(instance string) (Endpoints, error) {
 
 			return encodeRequest(ctx, r, req)
 		}, func(ctx context.Context, resp *http.Response) (interface{}, error) {
+			if err := responseError(resp); err != nil {
+				return nil, err
+			}
 			var response *Result
 			err := json.NewDecoder(resp.Body).Decode(&response)
 			return response, err
 		}, options...).Endpoint(),
+		DescribeEndpoint: httptransport.NewClient("POST", tgt, func(ctx context.Context, r *http.Request, request interface{}) error {
+			r.URL.Path = "/api/v1/describe"
+
+			return encodeRequest(ctx, r, struct{}{})
+		}, func(ctx context.Context, resp *http.Response) (interface{}, error) {
+			if err := responseError(resp); err != nil {
+				return nil, err
+			}
+			var response *Description
+			err := json.NewDecoder(resp.Body).Decode(&response)
+			return response, err
+		}, options...).Endpoint(),
+		CompleteEndpoint: httptransport.NewClient("POST", tgt, func(ctx context.Context, r *http.Request, request interface{}) error {
+			r.URL.Path = "/api/v1/complete"
+			req := request.(*CompleteRequest)
+
+			return encodeRequest(ctx, r, req)
+		}, func(ctx context.Context, resp *http.Response) (interface{}, error) {
+			return struct{}{}, responseError(resp)
+		}, options...).Endpoint(),
 	}, nil
 }
 
+// responseError returns the error of a node that did not respond with 200,
+// ErrUnimplemented when it does not implement the call.
+func responseError(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotImplemented:
+		return ErrUnimplemented
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return fmt.Errorf("node responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
 // encodeRequest likewise JSON-encodes the request to the HTTP request body.
 // Don't use it directly as a transport/http.Client EncodeRequestFunc:
 // profilesvc endpoints require mutating the HTTP method and request path.
@@ -102,6 +170,28 @@ func DoEndpoint(s Interface) endpoint.Endpoint {
 		return s.Do(ctx, req)
 	}
 }
+
+func DescribeEndpoint(s Interface) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		d, ok := s.(Describer)
+		if !ok {
+			return nil, ErrUnimplemented
+		}
+		return d.Describe(ctx)
+	}
+}
+
+func CompleteEndpoint(s Interface) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		c, ok := s.(Completer)
+		if !ok {
+			return nil, ErrUnimplemented
+		}
+		req := request.(*CompleteRequest)
+		return struct{}{}, c.Complete(ctx, req)
+	}
+}
+
 func (e Endpoints) Do(ctx context.Context, in *Request) (*Result, error) {
 	resp, err := e.DoEndpoint(ctx, in)
 	if err != nil {
@@ -110,3 +200,28 @@ func (e Endpoints) Do(ctx context.Context, in *Request) (*Result, error) {
 
 	return resp.(*Result), nil
 }
+
+// Describe returns ErrUnimplemented when the node does not describe itself.
+func (e Endpoints) Describe(ctx context.Context) (*Description, error) {
+	resp, err := e.DescribeEndpoint(ctx, struct{}{})
+	if err != nil {
+		return nil, unwrapRetry(err)
+	}
+
+	return resp.(*Description), nil
+}
+
+// Complete returns ErrUnimplemented when the node keeps no work pending.
+func (e Endpoints) Complete(ctx context.Context, in *CompleteRequest) error {
+	_, err := e.CompleteEndpoint(ctx, in)
+	return unwrapRetry(err)
+}
+
+// unwrapRetry returns the last error of the retries of err, so that ErrUnimplemented can be told.
+func unwrapRetry(err error) error {
+	var re lb.RetryError
+	if errors.As(err, &re) && re.Final != nil {
+		return re.Final
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package node_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
+	"git.yunify.com/quanxiang/workflow/pkg/node"
+	"git.yunify.com/quanxiang/workflow/pkg/node/nodetest"
+	"git.yunify.com/quanxiang/workflow/pkg/node/pb"
+	"github.com/go-kit/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// pending keeps the node runs it is called for pending until they are completed.
+type pending struct {
+	completed map[string]v1alpha1.NodeStatus
+}
+
+func (p *pending) Do(ctx context.Context, in *node.Request) (*node.Result, error) {
+	return &node.Result{
+		Status: v1alpha1.Pending,
+	}, nil
+}
+
+func (p *pending) Complete(ctx context.Context, in *node.CompleteRequest) error {
+	p.completed[in.Metadata.Annotations["run"]] = in.Status
+	return nil
+}
+
+type client interface {
+	node.Describer
+	node.Completer
+}
+
+func testDescribeAndComplete(t *testing.T, newClient func(s node.Interface) client) {
+	null := newClient(&node.Null{})
+	d, err := null.Describe(context.Background())
+	if err != nil || d.Type != "null" {
+		t.Errorf("unexpected description %v, err %v", d, err)
+	}
+	err = null.Complete(context.Background(), &node.CompleteRequest{Status: v1alpha1.Kill})
+	if !errors.Is(err, node.ErrUnimplemented) {
+		t.Errorf("expect ErrUnimplemented completing null, got %v", err)
+	}
+
+	p := &pending{completed: make(map[string]v1alpha1.NodeStatus)}
+	c := newClient(p)
+	if _, err := c.Describe(context.Background()); !errors.Is(err, node.ErrUnimplemented) {
+		t.Errorf("expect ErrUnimplemented describing pending, got %v", err)
+	}
+	req := &node.CompleteRequest{Status: v1alpha1.Skip}
+	req.Metadata.Annotations = map[string]string{"run": "r1"}
+	if err := c.Complete(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if p.completed["r1"] != v1alpha1.Skip {
+		t.Errorf("unexpected completed %v", p.completed)
+	}
+}
+
+func TestDescribeAndCompleteHTTP(t *testing.T) {
+	testDescribeAndComplete(t, func(s node.Interface) client {
+		srv := nodetest.NewServer(t, s)
+		return node.New([]string{srv.URL}, log.NewNopLogger()).(client)
+	})
+}
+
+func TestDescribeAndCompleteGRPC(t *testing.T) {
+	testDescribeAndComplete(t, func(s node.Interface) client {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := grpc.NewServer()
+		pb.RegisterNodeServer(server, node.NewGRPCServer(s, log.NewNopLogger()))
+		go server.Serve(lis) // nolint: errcheck
+		t.Cleanup(server.Stop)
+
+		return node.NewGRPC([]string{lis.Addr().String()}, insecure.NewCredentials(), log.NewNopLogger()).(client)
+	})
+}
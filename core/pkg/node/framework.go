@@ -3,18 +3,20 @@ package node
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"os"
 	"os/signal"
 	"time"
 
+	"git.yunify.com/quanxiang/workflow/pkg/helper/signature"
 	"github.com/go-kit/kit/endpoint"
-
 	"github.com/go-kit/kit/transport"
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
 )
 
 func NewHTTPHandler(ctx context.Context, s Interface, logger log.Logger, opts ...Option) http.Handler {
@@ -28,7 +30,11 @@ func NewHTTPHandler(ctx context.Context, s Interface, logger log.Logger, opts ..
 				panic("encodeError with nil error")
 			}
 			w.Header().Set("Content-Type", "application/json; charset=utf-8")
-			w.WriteHeader(http.StatusInternalServerError)
+			if errors.Is(err, ErrUnimplemented) {
+				w.WriteHeader(http.StatusNotImplemented)
+			} else {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"error": err.Error(),
 			})
@@ -52,12 +58,62 @@ func NewHTTPHandler(ctx context.Context, s Interface, logger log.Logger, opts ..
 		options...,
 	))
 
+	r.Methods("POST").Path("/api/v1/describe").Handler(httptransport.NewServer(
+		e.DescribeEndpoint,
+		func(ctx context.Context, r *http.Request) (request interface{}, err error) {
+			return struct{}{}, nil
+		},
+		encodeJSON,
+		options...,
+	))
+
+	r.Methods("POST").Path("/api/v1/complete").Handler(httptransport.NewServer(
+		e.CompleteEndpoint,
+		func(ctx context.Context, r *http.Request) (request interface{}, err error) {
+			var req *CompleteRequest
+			if e := json.NewDecoder(r.Body).Decode(&req); e != nil {
+				return nil, e
+			}
+
+			return req, nil
+		},
+		encodeJSON,
+		options...,
+	))
+
+	for _, opt := range opts {
+		opt(r.NewRoute(), options...)
+	}
+
 	return r
 }
 
+func encodeJSON(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(response)
+}
+
 func Main(logger log.Logger, addr string) func(ctx context.Context, s Interface, opts ...Option) error {
+	return MainWithConfig(logger, addr, Config{})
+}
+
+// Config configures the optional gRPC listener and request signing of a node service.
+type Config struct {
+	GRPC GRPCConfig       `yaml:"grpc" envconfig:"GRPC"`
+	Auth signature.Config `yaml:"auth" envconfig:"AUTH"`
+}
+
+// MainWithConfig serves the node over HTTP and, when conf.GRPC.Port is set, over gRPC as well.
+// Requests must be signed when conf.Auth has keys.
+func MainWithConfig(logger log.Logger, addr string, conf Config) func(ctx context.Context, s Interface, opts ...Option) error {
 	return func(ctx context.Context, s Interface, opts ...Option) error {
-		h := NewHTTPHandler(ctx, s, logger, opts...)
+		signer, err := signature.New(conf.Auth)
+		if err != nil {
+			level.Error(logger).Log("message", err.Error())
+			return err
+		}
+
+		h := signer.Middleware(NewHTTPHandler(ctx, s, logger, opts...))
 		server := &http.Server{
 			Addr:    addr,
 			Handler: h,
@@ -76,9 +132,18 @@ func Main(logger log.Logger, addr string) func(ctx context.Context, s Interface,
 			server.Shutdown(shutdownCtx) // nolint: errcheck
 		}()
 
+		if conf.GRPC.Port != "" {
+			go func() {
+				if err := ServeGRPC(ctx, s, logger, conf.GRPC, grpc.UnaryInterceptor(VerifyUnaryServerInterceptor(signer))); err != nil {
+					level.Error(logger).Log("message", err.Error())
+					stop()
+				}
+			}()
+		}
+
 		level.Info(logger).Log("message", "Starting...", "addr", addr)
 
-		err := server.ListenAndServe()
+		err = server.ListenAndServe()
 		if err != http.ErrServerClosed {
 			level.Error(logger).Log("message", err.Error())
 			return err
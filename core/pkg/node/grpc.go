@@ -0,0 +1,473 @@
+package node
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	goerrors "errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
+	"git.yunify.com/quanxiang/workflow/pkg/helper/errors"
+	"git.yunify.com/quanxiang/workflow/pkg/helper/signature"
+	"git.yunify.com/quanxiang/workflow/pkg/node/pb"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/lb"
+	"github.com/go-kit/kit/transport"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// TLSConfig holds the certificates used for mutual TLS between core and nodes.
+// An empty CertFile disables TLS.
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file" envconfig:"CERT_FILE"`
+	KeyFile  string `yaml:"key_file" envconfig:"KEY_FILE"`
+	CAFile   string `yaml:"ca_file" envconfig:"CA_FILE"`
+}
+
+// GRPCConfig configures the optional gRPC listener of a node service.
+// An empty Port disables the listener.
+type GRPCConfig struct {
+	Port string    `yaml:"port" envconfig:"PORT"`
+	TLS  TLSConfig `yaml:"tls" envconfig:"TLS"`
+}
+
+// ServerCredentials returns credentials that require and verify client certificates.
+func (t TLSConfig) ServerCredentials() (credentials.TransportCredentials, error) {
+	if t.CertFile == "" {
+		return insecure.NewCredentials(), nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "fail load server certificate")
+	}
+	pool, err := loadCertPool(t.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+	}), nil
+}
+
+// ClientCredentials returns credentials that present a client certificate
+// and verify the node service against the CA.
+func (t TLSConfig) ClientCredentials() (credentials.TransportCredentials, error) {
+	if t.CertFile == "" {
+		return insecure.NewCredentials(), nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "fail load client certificate")
+	}
+	pool, err := loadCertPool(t.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS12,
+	}), nil
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	if caFile == "" {
+		return x509.SystemCertPool()
+	}
+
+	body, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "fail read ca file")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(body) {
+		return nil, errors.Wrap(os.ErrInvalid, "fail parse ca file")
+	}
+	return pool, nil
+}
+
+// NewGRPC returns a node client that calls the node services over gRPC.
+func NewGRPC(instance []string, creds credentials.TransportCredentials, logger log.Logger, opts ...grpc.DialOption) Interface {
+	var endpoints Endpoints
+	var instancer sd.FixedInstancer = sd.FixedInstancer(instance)
+
+	var (
+		retryMax     = 3
+		retryTimeout = 3 * time.Second
+	)
+
+	{
+		factory := grpcFactoryFor(DoEndpoint, append(opts, grpc.WithTransportCredentials(creds)))
+		endpointer := sd.NewEndpointer(instancer, factory, logger)
+		balancer := lb.NewRoundRobin(endpointer)
+		retry := lb.Retry(retryMax, retryTimeout, balancer)
+		endpoints.DoEndpoint = retry
+	}
+	{
+		factory := grpcFactoryFor(DescribeEndpoint, append(opts, grpc.WithTransportCredentials(creds)))
+		endpointer := sd.NewEndpointer(instancer, factory, logger)
+		balancer := lb.NewRoundRobin(endpointer)
+		retry := lb.RetryWithCallback(retryTimeout, balancer, retryImplemented(retryMax))
+		endpoints.DescribeEndpoint = retry
+	}
+	{
+		factory := grpcFactoryFor(CompleteEndpoint, append(opts, grpc.WithTransportCredentials(creds)))
+		endpointer := sd.NewEndpointer(instancer, factory, logger)
+		balancer := lb.NewRoundRobin(endpointer)
+		retry := lb.RetryWithCallback(retryTimeout, balancer, retryImplemented(retryMax))
+		endpoints.CompleteEndpoint = retry
+	}
+
+	return endpoints
+}
+
+func grpcFactoryFor(makeEndpoint func(Interface) endpoint.Endpoint, opts []grpc.DialOption) sd.Factory {
+	return func(instance string) (endpoint.Endpoint, io.Closer, error) {
+		conn, err := grpc.Dial(instance, opts...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return makeEndpoint(NewGRPCClientEndPoints(conn)), conn, nil
+	}
+}
+
+func NewGRPCClientEndPoints(conn *grpc.ClientConn) Endpoints {
+	return Endpoints{
+		DoEndpoint: grpctransport.NewClient(
+			conn,
+			"workflow.node.v1.Node",
+			"Do",
+			func(_ context.Context, request interface{}) (interface{}, error) {
+				return requestToPB(request.(*Request)), nil
+			},
+			func(_ context.Context, response interface{}) (interface{}, error) {
+				return resultFromPB(response.(*pb.DoResult)), nil
+			},
+			pb.DoResult{},
+		).Endpoint(),
+		DescribeEndpoint: unimplementedFromGRPC(grpctransport.NewClient(
+			conn,
+			"workflow.node.v1.Node",
+			"Describe",
+			func(_ context.Context, request interface{}) (interface{}, error) {
+				return &pb.DescribeRequest{}, nil
+			},
+			func(_ context.Context, response interface{}) (interface{}, error) {
+				return descriptionFromPB(response.(*pb.Description)), nil
+			},
+			pb.Description{},
+		).Endpoint()),
+		CompleteEndpoint: unimplementedFromGRPC(grpctransport.NewClient(
+			conn,
+			"workflow.node.v1.Node",
+			"Complete",
+			func(_ context.Context, request interface{}) (interface{}, error) {
+				return completeRequestToPB(request.(*CompleteRequest)), nil
+			},
+			func(_ context.Context, response interface{}) (interface{}, error) {
+				return struct{}{}, nil
+			},
+			pb.CompleteResult{},
+		).Endpoint()),
+	}
+}
+
+// unimplementedFromGRPC returns ErrUnimplemented for the calls the node answered with
+// codes.Unimplemented.
+func unimplementedFromGRPC(next endpoint.Endpoint) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		response, err := next(ctx, request)
+		if status.Code(err) == codes.Unimplemented {
+			return nil, ErrUnimplemented
+		}
+		return response, err
+	}
+}
+
+type grpcServer struct {
+	pb.UnimplementedNodeServer
+
+	do       grpctransport.Handler
+	describe grpctransport.Handler
+	complete grpctransport.Handler
+}
+
+func (g *grpcServer) Do(ctx context.Context, in *pb.DoRequest) (*pb.DoResult, error) {
+	_, resp, err := g.do.ServeGRPC(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.DoResult), nil
+}
+
+func (g *grpcServer) Describe(ctx context.Context, in *pb.DescribeRequest) (*pb.Description, error) {
+	_, resp, err := g.describe.ServeGRPC(ctx, in)
+	if err != nil {
+		return nil, unimplementedToGRPC(err)
+	}
+	return resp.(*pb.Description), nil
+}
+
+func (g *grpcServer) Complete(ctx context.Context, in *pb.CompleteRequest) (*pb.CompleteResult, error) {
+	_, resp, err := g.complete.ServeGRPC(ctx, in)
+	if err != nil {
+		return nil, unimplementedToGRPC(err)
+	}
+	return resp.(*pb.CompleteResult), nil
+}
+
+func unimplementedToGRPC(err error) error {
+	if goerrors.Is(err, ErrUnimplemented) {
+		return status.Error(codes.Unimplemented, err.Error())
+	}
+	return err
+}
+
+// NewGRPCServer exposes the node over gRPC, sharing the go-kit endpoints with the HTTP transport.
+func NewGRPCServer(s Interface, logger log.Logger) pb.NodeServer {
+	e := NewEndPoints(s)
+
+	return &grpcServer{
+		do: grpctransport.NewServer(
+			e.DoEndpoint,
+			func(_ context.Context, request interface{}) (interface{}, error) {
+				return requestFromPB(request.(*pb.DoRequest)), nil
+			},
+			func(_ context.Context, response interface{}) (interface{}, error) {
+				return resultToPB(response.(*Result)), nil
+			},
+			grpctransport.ServerErrorHandler(transport.NewLogErrorHandler(logger)),
+		),
+		describe: grpctransport.NewServer(
+			e.DescribeEndpoint,
+			func(_ context.Context, request interface{}) (interface{}, error) {
+				return struct{}{}, nil
+			},
+			func(_ context.Context, response interface{}) (interface{}, error) {
+				return descriptionToPB(response.(*Description)), nil
+			},
+			grpctransport.ServerErrorHandler(transport.NewLogErrorHandler(logger)),
+		),
+		complete: grpctransport.NewServer(
+			e.CompleteEndpoint,
+			func(_ context.Context, request interface{}) (interface{}, error) {
+				return completeRequestFromPB(request.(*pb.CompleteRequest)), nil
+			},
+			func(_ context.Context, response interface{}) (interface{}, error) {
+				return &pb.CompleteResult{}, nil
+			},
+			grpctransport.ServerErrorHandler(transport.NewLogErrorHandler(logger)),
+		),
+	}
+}
+
+// ServeGRPC serves the node over gRPC until ctx is done.
+func ServeGRPC(ctx context.Context, s Interface, logger log.Logger, conf GRPCConfig, opts ...grpc.ServerOption) error {
+	creds, err := conf.TLS.ServerCredentials()
+	if err != nil {
+		return err
+	}
+
+	lis, err := net.Listen("tcp", ":"+conf.Port)
+	if err != nil {
+		return errors.Wrap(err, "fail listen grpc port")
+	}
+
+	server := grpc.NewServer(append(opts, grpc.Creds(creds))...)
+	pb.RegisterNodeServer(server, NewGRPCServer(s, logger))
+
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+
+	level.Info(logger).Log("message", "Starting grpc...", "port", conf.Port)
+	return server.Serve(lis)
+}
+
+// SignUnaryClientInterceptor signs outgoing calls with signer, over the
+// deterministic encoding of the request. A nil signer sends calls unsigned.
+func SignUnaryClientInterceptor(signer *signature.Signer) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if signer == nil {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		body, err := proto.MarshalOptions{Deterministic: true}.Marshal(req.(proto.Message))
+		if err != nil {
+			return err
+		}
+		keyID, timestamp, nonce, sign := signer.Sign(http.MethodPost, method, body)
+		ctx = metadata.AppendToOutgoingContext(ctx,
+			signature.HeaderKeyID, keyID,
+			signature.HeaderTimestamp, timestamp,
+			signature.HeaderNonce, nonce,
+			signature.HeaderSignature, sign,
+		)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// VerifyUnaryServerInterceptor rejects calls without a valid signature.
+// A nil signer accepts every call.
+func VerifyUnaryServerInterceptor(signer *signature.Signer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if signer == nil {
+			return handler(ctx, req)
+		}
+
+		body, err := proto.MarshalOptions{Deterministic: true}.Marshal(req.(proto.Message))
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		var get = func(key string) string {
+			if values := md.Get(key); len(values) != 0 {
+				return values[0]
+			}
+			return ""
+		}
+		err = signer.Verify(http.MethodPost, info.FullMethod, body,
+			get(signature.HeaderKeyID),
+			get(signature.HeaderTimestamp),
+			get(signature.HeaderNonce),
+			get(signature.HeaderSignature),
+		)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return handler(ctx, req)
+	}
+}
+
+func requestToPB(in *Request) *pb.DoRequest {
+	return &pb.DoRequest{
+		Params: kvsToPB(in.Params),
+		Metadata: &pb.Metadata{
+			Annotations: in.Metadata.Annotations,
+		},
+	}
+}
+
+func requestFromPB(in *pb.DoRequest) *Request {
+	req := &Request{
+		Params: kvsFromPB(in.GetParams()),
+	}
+	req.Metadata.Annotations = in.GetMetadata().GetAnnotations()
+	return req
+}
+
+func resultToPB(in *Result) *pb.DoResult {
+	return &pb.DoResult{
+		Out:      kvsToPB(in.Out),
+		Communal: kvsToPB(in.Communal),
+		Status:   string(in.Status),
+		Message:  in.Message,
+		Error:    errorToPB(in.Error),
+	}
+}
+
+func resultFromPB(in *pb.DoResult) *Result {
+	return &Result{
+		Out:      kvsFromPB(in.GetOut()),
+		Communal: kvsFromPB(in.GetCommunal()),
+		Status:   v1alpha1.NodeStatus(in.GetStatus()),
+		Message:  in.GetMessage(),
+		Error:    errorFromPB(in.GetError()),
+	}
+}
+
+func descriptionToPB(in *Description) *pb.Description {
+	return &pb.Description{
+		Type:   in.Type,
+		Params: in.Params,
+		Out:    in.Out,
+	}
+}
+
+func descriptionFromPB(in *pb.Description) *Description {
+	return &Description{
+		Type:   in.GetType(),
+		Params: in.GetParams(),
+		Out:    in.GetOut(),
+	}
+}
+
+func completeRequestToPB(in *CompleteRequest) *pb.CompleteRequest {
+	return &pb.CompleteRequest{
+		Metadata: &pb.Metadata{
+			Annotations: in.Metadata.Annotations,
+		},
+		Status:  string(in.Status),
+		Message: in.Message,
+	}
+}
+
+func completeRequestFromPB(in *pb.CompleteRequest) *CompleteRequest {
+	req := &CompleteRequest{
+		Status:  v1alpha1.NodeStatus(in.GetStatus()),
+		Message: in.GetMessage(),
+	}
+	req.Metadata.Annotations = in.GetMetadata().GetAnnotations()
+	return req
+}
+
+func errorToPB(in *Error) *pb.Error {
+	if in == nil {
+		return nil
+	}
+	return &pb.Error{
+		Code:      in.Code,
+		Retryable: in.Retryable,
+		Details:   in.Details,
+	}
+}
+
+func errorFromPB(in *pb.Error) *Error {
+	if in == nil {
+		return nil
+	}
+	return &Error{
+		Code:      in.GetCode(),
+		Retryable: in.GetRetryable(),
+		Details:   in.GetDetails(),
+	}
+}
+
+func kvsToPB(kvs []*v1alpha1.KeyAndValue) []*pb.KeyAndValue {
+	result := make([]*pb.KeyAndValue, 0, len(kvs))
+	for _, kv := range kvs {
+		result = append(result, &pb.KeyAndValue{Key: kv.Key, Value: kv.Value})
+	}
+	return result
+}
+
+func kvsFromPB(kvs []*pb.KeyAndValue) []*v1alpha1.KeyAndValue {
+	result := make([]*v1alpha1.KeyAndValue, 0, len(kvs))
+	for _, kv := range kvs {
+		result = append(result, &v1alpha1.KeyAndValue{Key: kv.GetKey(), Value: kv.GetValue()})
+	}
+	return result
+}
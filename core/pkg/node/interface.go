@@ -2,10 +2,9 @@ package node
 
 import (
 	"context"
-	"net/http"
+	"errors"
 
 	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
-	"git.yunify.com/quanxiang/workflow/pkg/helper/errors"
 )
 
 type Result struct {
@@ -14,6 +13,65 @@ type Result struct {
 
 	Status  v1alpha1.NodeStatus `json:"status,omitempty"`
 	Message string              `json:"message,omitempty"`
+
+	// Error is set when the node failed. The runner retries the node later
+	// if it is retryable and kills the pipeline run otherwise.
+	Error *Error `json:"error,omitempty"`
+}
+
+// Error codes shared by the builtin nodes.
+const (
+	// ErrCodeInvalidParams means the node params or config are malformed.
+	ErrCodeInvalidParams = "InvalidParams"
+	// ErrCodeNotFound means data the node depends on does not exist.
+	ErrCodeNotFound = "NotFound"
+	// ErrCodeNoAssignee means no user could be resolved to handle the node.
+	ErrCodeNoAssignee = "NoAssignee"
+	// ErrCodeUnknownNode means no node implementation exists for the type.
+	ErrCodeUnknownNode = "UnknownNode"
+	// ErrCodeUpstream means a service called by the node returned an error response.
+	ErrCodeUpstream = "UpstreamError"
+	// ErrCodeUnavailable means a service the node depends on could not be reached.
+	ErrCodeUnavailable = "Unavailable"
+)
+
+// Error is the machine-readable cause of a failed node.
+type Error struct {
+	Code      string            `json:"code"`
+	Retryable bool              `json:"retryable,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Retryable {
+		return "retryable node error: " + e.Code
+	}
+	return "node error: " + e.Code
+}
+
+// Permanent returns a killed result that the runner must not retry.
+func Permanent(code string, message string, details map[string]string) *Result {
+	return &Result{
+		Status:  v1alpha1.Kill,
+		Message: message,
+		Error: &Error{
+			Code:    code,
+			Details: details,
+		},
+	}
+}
+
+// Transient returns a result that the runner retries later.
+func Transient(code string, message string, details map[string]string) *Result {
+	return &Result{
+		Status:  v1alpha1.Pending,
+		Message: message,
+		Error: &Error{
+			Code:      code,
+			Retryable: true,
+			Details:   details,
+		},
+	}
 }
 
 type Request struct {
@@ -25,16 +83,39 @@ type Interface interface {
 	Do(ctx context.Context, in *Request) (*Result, error)
 }
 
+// Description is what a node tells about itself.
+type Description struct {
+	Type   string   `json:"type"`
+	Params []string `json:"params,omitempty"`
+	Out    []string `json:"out,omitempty"`
+}
+
+// Describer is implemented by the nodes that describe themselves.
+type Describer interface {
+	Describe(ctx context.Context) (*Description, error)
+}
+
+// CompleteRequest ends the node run of Metadata, with Status Kill when its pipeline run was
+// killed or Skip when it was rewound.
+type CompleteRequest struct {
+	Metadata v1alpha1.Metadata
+	Status   v1alpha1.NodeStatus
+	Message  string
+}
+
+// Completer is implemented by the nodes that keep work pending on their node runs, to drop it
+// when a node run ends without them.
+type Completer interface {
+	Complete(ctx context.Context, in *CompleteRequest) error
+}
+
+// ErrUnimplemented is returned by the calls a node does not implement.
+var ErrUnimplemented = errors.New("node: call not implemented")
+
 type None struct{}
 
 func (n *None) Do(ctx context.Context, in *Request) (*Result, error) {
-	err := errors.NewErr(http.StatusInternalServerError, &errors.CodeError{
-		Code:    http.StatusNotFound,
-		Message: "Illegal node type",
-	})
-	return &Result{
-		Status: v1alpha1.Finish,
-	}, err
+	return Permanent(ErrCodeUnknownNode, "Illegal node type", nil), nil
 }
 
 type Null struct{}
@@ -44,3 +125,9 @@ func (n *Null) Do(ctx context.Context, in *Request) (*Result, error) {
 		Status: v1alpha1.Finish,
 	}, nil
 }
+
+func (n *Null) Describe(ctx context.Context) (*Description, error) {
+	return &Description{
+		Type: "null",
+	}, nil
+}
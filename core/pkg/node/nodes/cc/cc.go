@@ -0,0 +1,64 @@
+package cc
+
+import (
+	"context"
+	"os"
+
+	"git.yunify.com/quanxiang/workflow/pkg/helper/errors"
+	"git.yunify.com/quanxiang/workflow/pkg/node"
+	"git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db/mysql"
+	"git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/service"
+	"github.com/go-kit/log"
+	"gopkg.in/yaml.v3"
+)
+
+// Type is the type of the node.
+const Type = "cc"
+
+// Config of the cc node, its ccs are kept in the database of the examine node.
+type Config struct {
+	Port     int    `yaml:"port"`
+	LogLevel string `yaml:"log_level"`
+
+	Mysql      mysql.Mysql `yaml:"mysql"`
+	QxInstance []string    `yaml:"qx_instance"`
+	HomeHost   string      `yaml:"home_host"`
+	// Notify configures the messages to the users copied, only its channels and cc template are used.
+	Notify service.Notify `yaml:"notify"`
+
+	node.Config `yaml:",inline"`
+}
+
+func GetConfig(path string) (*Config, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "fail get config file")
+	}
+
+	conf := &Config{}
+	err = yaml.Unmarshal(body, conf)
+	if err != nil {
+		return nil, errors.Wrap(err, "fail unmarshal config file")
+	}
+
+	return conf, nil
+}
+
+// New returns the cc node of conf.
+func New(conf *Config, logger log.Logger) (service.CC, error) {
+	db, err := mysql.NewDB(&conf.Mysql)
+	if err != nil {
+		return nil, err
+	}
+	return service.NewCC(db, conf.QxInstance, logger, conf.HomeHost, conf.Notify), nil
+}
+
+func init() {
+	node.Register(Type, func(_ context.Context, logger log.Logger, decode func(interface{}) error) (node.Interface, error) {
+		conf := &Config{}
+		if err := decode(conf); err != nil {
+			return nil, err
+		}
+		return New(conf, logger)
+	})
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"git.yunify.com/quanxiang/workflow/pkg/log"
+	"git.yunify.com/quanxiang/workflow/pkg/node"
+	"git.yunify.com/quanxiang/workflow/pkg/node/nodes/cc"
+	"git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/apis"
+)
+
+var configPath string
+
+func main() {
+	flag.StringVar(&configPath, "c", "./config.yaml", "-c config path")
+	flag.Parse()
+	conf, err := cc.GetConfig(configPath)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	logger := log.NewLogger(conf.LogLevel)
+
+	s, err := cc.New(conf, logger)
+	if err != nil {
+		panic(err)
+	}
+	endPoints := apis.NewCCEndPoints(s)
+	node.MainWithConfig(logger, fmt.Sprintf(":%d", conf.Port), conf.Config)(context.Background(), endPoints, apis.CCRouter(endPoints)...)
+}
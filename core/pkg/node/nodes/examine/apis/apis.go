@@ -5,83 +5,152 @@ import (
 	"encoding/json"
 	"net/http"
 
+	httptransport "github.com/go-kit/kit/transport/http"
+	e "github.com/quanxiang-cloud/cabin/error"
+
+	"git.yunify.com/quanxiang/workflow/pkg/helper/errors"
 	"git.yunify.com/quanxiang/workflow/pkg/node"
 	"git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/service"
 )
 
+// userIDHeader holds the authenticated caller, set by the gateway like for the core API.
+const userIDHeader = "User-Id"
+
 func Router(endpoints Endpoints) []node.Option {
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(encodeError),
+	}
 	return []node.Option{
 		node.WithRouter("POST", "/api/v1/examine/agree", endpoints.AgreeEndpoint, func(ctx context.Context, r *http.Request) (request interface{}, err error) {
 			var req service.AgreeRequest
-			if e := json.NewDecoder(r.Body).Decode(&req); e != nil {
-				return nil, e
-			}
-
-			return &req, nil
-		},
-			func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
-				w.Header().Set("Content-Type", "application/json; charset=utf-8")
-				return json.NewEncoder(w).Encode(response)
-			}),
+			return reqUserJSON(&req, &req.UserID)(ctx, r)
+		}, responseJSON, options...),
 		node.WithRouter("POST", "/api/v1/examine/reject", endpoints.RejectEndpoint, func(ctx context.Context, r *http.Request) (request interface{}, err error) {
-			var req service.AgreeRequest
-			if e := json.NewDecoder(r.Body).Decode(&req); e != nil {
-				return nil, e
-			}
-
-			return &req, nil
-		},
-			func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
-				w.Header().Set("Content-Type", "application/json; charset=utf-8")
-				return json.NewEncoder(w).Encode(response)
-			}),
+			var req service.RejectRequest
+			return reqUserJSON(&req, &req.UserID)(ctx, r)
+		}, responseJSON, options...),
 		node.WithRouter("POST", "/api/v1/examine/recall", endpoints.RecallEndpoint, func(ctx context.Context, r *http.Request) (request interface{}, err error) {
-			var req service.AgreeRequest
-			if e := json.NewDecoder(r.Body).Decode(&req); e != nil {
-				return nil, e
-			}
-
-			return &req, nil
-		},
-			func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
-				w.Header().Set("Content-Type", "application/json; charset=utf-8")
-				return json.NewEncoder(w).Encode(response)
-			}),
+			var req service.RecallRequest
+			return reqUserJSON(&req, &req.UserID)(ctx, r)
+		}, responseJSON, options...),
 		node.WithRouter("POST", "/api/v1/examine/transfer", endpoints.TransferEndpoint, func(ctx context.Context, r *http.Request) (request interface{}, err error) {
-			var req service.AgreeRequest
-			if e := json.NewDecoder(r.Body).Decode(&req); e != nil {
-				return nil, e
-			}
-
-			return &req, nil
-		},
-			func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
-				w.Header().Set("Content-Type", "application/json; charset=utf-8")
-				return json.NewEncoder(w).Encode(response)
-			}),
+			var req service.TransferRequest
+			return reqUserJSON(&req, &req.UserID)(ctx, r)
+		}, responseJSON, options...),
+		node.WithRouter("POST", "/api/v1/examine/addSigner", endpoints.AddSignerEndpoint, func(ctx context.Context, r *http.Request) (request interface{}, err error) {
+			var req service.AddSignerRequest
+			return reqUserJSON(&req, &req.UserID)(ctx, r)
+		}, responseJSON, options...),
+		node.WithRouter("POST", "/api/v1/examine/sendBack", endpoints.SendBackEndpoint, func(ctx context.Context, r *http.Request) (request interface{}, err error) {
+			var req service.SendBackRequest
+			return reqUserJSON(&req, &req.UserID)(ctx, r)
+		}, responseJSON, options...),
+		node.WithRouter("POST", "/api/v1/examine/resubmit", endpoints.ResubmitEndpoint, func(ctx context.Context, r *http.Request) (request interface{}, err error) {
+			var req service.ResubmitRequest
+			return reqUserJSON(&req, &req.UserID)(ctx, r)
+		}, responseJSON, options...),
 		node.WithRouter("POST", "/api/v1/examine/urge", endpoints.UrgeEndpoint, func(ctx context.Context, r *http.Request) (request interface{}, err error) {
-			var req service.AgreeRequest
-			if e := json.NewDecoder(r.Body).Decode(&req); e != nil {
-				return nil, e
-			}
-
-			return &req, nil
-		},
-			func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
-				w.Header().Set("Content-Type", "application/json; charset=utf-8")
-				return json.NewEncoder(w).Encode(response)
-			}),
+			var req service.UrgeRequest
+			return reqUserJSON(&req, &req.UserID)(ctx, r)
+		}, responseJSON, options...),
+		node.WithRouter("POST", "/api/v1/examine/batch", endpoints.BatchEndpoint, func(ctx context.Context, r *http.Request) (request interface{}, err error) {
+			var req service.BatchRequest
+			return reqUserJSON(&req, &req.UserID)(ctx, r)
+		}, responseJSON, options...),
 		node.WithRouter("POST", "/api/v1/examine/list", endpoints.ListEndpoint, func(ctx context.Context, r *http.Request) (request interface{}, err error) {
-			var req service.AgreeRequest
-			if e := json.NewDecoder(r.Body).Decode(&req); e != nil {
-				return nil, e
+			var req service.ListRequest
+			return reqUserJSON(&req, &req.CallerID)(ctx, r)
+		}, responseJSON, options...),
+		node.WithRouter("POST", "/api/v1/examine/stats", endpoints.StatsEndpoint, func(ctx context.Context, r *http.Request) (request interface{}, err error) {
+			var req service.StatsRequest
+			return reqUserJSON(&req, &req.CallerID)(ctx, r)
+		}, responseJSON, options...),
+		node.WithRouter("POST", "/api/v1/examine/delegation/create", endpoints.CreateDelegationEndpoint, func(ctx context.Context, r *http.Request) (request interface{}, err error) {
+			var req service.CreateDelegationRequest
+			return reqUserJSON(&req, &req.UserID)(ctx, r)
+		}, responseJSON, options...),
+		node.WithRouter("POST", "/api/v1/examine/delegation/delete", endpoints.DeleteDelegationEndpoint, func(ctx context.Context, r *http.Request) (request interface{}, err error) {
+			var req service.DeleteDelegationRequest
+			return reqUserJSON(&req, &req.UserID)(ctx, r)
+		}, responseJSON, options...),
+		node.WithRouter("POST", "/api/v1/examine/delegation/list", endpoints.ListDelegationEndpoint, func(ctx context.Context, r *http.Request) (request interface{}, err error) {
+			var req service.ListDelegationRequest
+			return reqUserJSON(&req, &req.UserID)(ctx, r)
+		}, responseJSON, options...),
+	}
+}
+
+// encodeError writes err like the core API: the status of its code and a JSON body.
+// The errors of the examine service carry a cabin code, used as the status when it is one.
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+	if err == nil {
+		panic("encodeError with nil error")
+	}
+
+	var ce *errors.Error
+	if !errors.As(err, &ce) {
+		ce = &errors.Error{
+			Code: http.StatusInternalServerError,
+			Message: &errors.CodeError{
+				Message: err.Error(),
+			},
+		}
+		var se e.Error
+		if errors.As(err, &se) {
+			ce.Message = &errors.CodeError{
+				Code:    int(se.Code),
+				Message: se.Error(),
 			}
+			switch {
+			case se.Code == e.ErrParams:
+				ce.Code = http.StatusBadRequest
+			case se.Code >= http.StatusBadRequest && se.Code < 600:
+				ce.Code = int(se.Code)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(ce.Code)
+	if ce.Message != nil {
+		w.Write([]byte(ce.Message.JSON()))
+	}
+}
+
+func responseJSON(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if ur, ok := response.(ur); ok {
+		if err := ur.GetErr(); err != nil {
+			encodeError(ctx, err, w)
+			return nil
+		}
+		return json.NewEncoder(w).Encode(ur.GetData())
+	}
+	return json.NewEncoder(w).Encode(response)
+}
+
+// reqUserJSON decodes the body into v like reqJSON, and then sets userID, the caller of v, from the
+// User-Id header of the authenticated request, so that the body cannot name another caller.
+func reqUserJSON(v any, userID *string) httptransport.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (request interface{}, err error) {
+		if _, err := reqJSON(v)(ctx, r); err != nil {
+			return nil, err
+		}
+		*userID = r.Header.Get(userIDHeader)
+
+		return v, nil
+	}
+}
+
+func reqJSON(v any) httptransport.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (request interface{}, err error) {
+		if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+			return nil, errors.NewErr(http.StatusBadRequest, &errors.CodeError{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
 
-			return &req, nil
-		},
-			func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
-				w.Header().Set("Content-Type", "application/json; charset=utf-8")
-				return json.NewEncoder(w).Encode(response)
-			}),
+		return v, nil
 	}
 }
@@ -0,0 +1,40 @@
+package apis
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/service"
+)
+
+func TestReqUserJSON(t *testing.T) {
+	r := httptest.NewRequest("POST", "/api/v1/examine/agree", strings.NewReader(`{"TaskID":"t1","UserID":"admin"}`))
+	r.Header.Set(userIDHeader, "u1")
+
+	var req service.AgreeRequest
+	v, err := reqUserJSON(&req, &req.UserID)(context.Background(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the caller is the one of the header, never the one of the body
+	if got := v.(*service.AgreeRequest); got.TaskID != "t1" || got.UserID != "u1" {
+		t.Errorf("unexpected request %+v", got)
+	}
+}
+
+func TestReqUserJSONFilter(t *testing.T) {
+	r := httptest.NewRequest("POST", "/api/v1/examine/list", strings.NewReader(`{"UserID":"u2","CallerID":"admin"}`))
+	r.Header.Set(userIDHeader, "u1")
+
+	var req service.ListRequest
+	v, err := reqUserJSON(&req, &req.CallerID)(context.Background(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the approver filtered by is kept, the caller is the one of the header
+	if got := v.(*service.ListRequest); got.UserID != "u2" || got.CallerID != "u1" {
+		t.Errorf("unexpected request %+v", got)
+	}
+}
@@ -0,0 +1,59 @@
+package apis
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+
+	"git.yunify.com/quanxiang/workflow/pkg/node"
+	"git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/service"
+)
+
+type CCEndpoints struct {
+	node.Endpoints
+
+	ListCCEndpoint endpoint.Endpoint
+	ReadCCEndpoint endpoint.Endpoint
+}
+
+func NewCCEndPoints(s service.CC) CCEndpoints {
+	return CCEndpoints{
+		Endpoints:      node.NewEndPoints(s),
+		ListCCEndpoint: ListCCEndpoint(s),
+		ReadCCEndpoint: ReadCCEndpoint(s),
+	}
+}
+
+func ListCCEndpoint(s service.CC) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(*service.ListCCRequest)
+		response, err := s.ListCC(ctx, req)
+		return universalResponse{Data: response, Err: err}, nil
+	}
+}
+
+func ReadCCEndpoint(s service.CC) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(*service.ReadCCRequest)
+		response, err := s.ReadCC(ctx, req)
+		return universalResponse{Data: response, Err: err}, nil
+	}
+}
+
+func CCRouter(endpoints CCEndpoints) []node.Option {
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(encodeError),
+	}
+	return []node.Option{
+		node.WithRouter("POST", "/api/v1/cc/list", endpoints.ListCCEndpoint, func(ctx context.Context, r *http.Request) (request interface{}, err error) {
+			var req service.ListCCRequest
+			return reqUserJSON(&req, &req.UserID)(ctx, r)
+		}, responseJSON, options...),
+		node.WithRouter("POST", "/api/v1/cc/read", endpoints.ReadCCEndpoint, func(ctx context.Context, r *http.Request) (request interface{}, err error) {
+			var req service.ReadCCRequest
+			return reqUserJSON(&req, &req.UserID)(ctx, r)
+		}, responseJSON, options...),
+	}
+}
@@ -2,7 +2,6 @@ package apis
 
 import (
 	"context"
-	"github.com/quanxiang-cloud/cabin/tailormade/resp"
 
 	"git.yunify.com/quanxiang/workflow/pkg/node"
 	"git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/service"
@@ -12,12 +11,21 @@ import (
 type Endpoints struct {
 	node.Endpoints
 	// portal
-	AgreeEndpoint    endpoint.Endpoint
-	RejectEndpoint   endpoint.Endpoint
-	RecallEndpoint   endpoint.Endpoint
-	TransferEndpoint endpoint.Endpoint
-	UrgeEndpoint     endpoint.Endpoint
-	ListEndpoint     endpoint.Endpoint
+	AgreeEndpoint     endpoint.Endpoint
+	RejectEndpoint    endpoint.Endpoint
+	RecallEndpoint    endpoint.Endpoint
+	TransferEndpoint  endpoint.Endpoint
+	AddSignerEndpoint endpoint.Endpoint
+	SendBackEndpoint  endpoint.Endpoint
+	ResubmitEndpoint  endpoint.Endpoint
+	UrgeEndpoint      endpoint.Endpoint
+	BatchEndpoint     endpoint.Endpoint
+	ListEndpoint      endpoint.Endpoint
+	StatsEndpoint     endpoint.Endpoint
+
+	CreateDelegationEndpoint endpoint.Endpoint
+	DeleteDelegationEndpoint endpoint.Endpoint
+	ListDelegationEndpoint   endpoint.Endpoint
 }
 
 func NewEndPoints(s service.Task) Endpoints {
@@ -31,8 +39,15 @@ func NewEndPoints(s service.Task) Endpoints {
 	end.RecallEndpoint = RecallEndpoint(s)
 	end.UrgeEndpoint = UrgeEndpoint(s)
 	end.TransferEndpoint = TransferEndpoint(s)
-	end.UrgeEndpoint = UrgeEndpoint(s)
+	end.AddSignerEndpoint = AddSignerEndpoint(s)
+	end.SendBackEndpoint = SendBackEndpoint(s)
+	end.ResubmitEndpoint = ResubmitEndpoint(s)
+	end.BatchEndpoint = BatchEndpoint(s)
 	end.ListEndpoint = ListEndpoint(s)
+	end.StatsEndpoint = StatsEndpoint(s)
+	end.CreateDelegationEndpoint = CreateDelegationEndpoint(s)
+	end.DeleteDelegationEndpoint = DeleteDelegationEndpoint(s)
+	end.ListDelegationEndpoint = ListDelegationEndpoint(s)
 	return end
 }
 
@@ -40,16 +55,14 @@ func AgreeEndpoint(s service.Task) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(*service.AgreeRequest)
 		response, err := s.Agree(ctx, req)
-		r := resp.Format(response, err)
-		return r, nil
+		return universalResponse{Data: response, Err: err}, nil
 	}
 }
 func RejectEndpoint(s service.Task) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(*service.RejectRequest)
 		response, err := s.Reject(ctx, req)
-		r := resp.Format(response, err)
-		return r, nil
+		return universalResponse{Data: response, Err: err}, nil
 	}
 }
 
@@ -57,16 +70,22 @@ func RecallEndpoint(s service.Task) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(*service.RecallRequest)
 		response, err := s.Recall(ctx, req)
-		r := resp.Format(response, err)
-		return r, nil
+		return universalResponse{Data: response, Err: err}, nil
 	}
 }
 func UrgeEndpoint(s service.Task) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(*service.UrgeRequest)
 		response, err := s.Urge(ctx, req)
-		r := resp.Format(response, err)
-		return r, nil
+		return universalResponse{Data: response, Err: err}, nil
+	}
+}
+
+func BatchEndpoint(s service.Task) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(*service.BatchRequest)
+		response, err := s.Batch(ctx, req)
+		return universalResponse{Data: response, Err: err}, nil
 	}
 }
 
@@ -74,16 +93,90 @@ func TransferEndpoint(s service.Task) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(*service.TransferRequest)
 		response, err := s.Transfer(ctx, req)
-		r := resp.Format(response, err)
-		return r, err
+		return universalResponse{Data: response, Err: err}, nil
 	}
 }
 
 func ListEndpoint(s service.Task) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		//req := request.(*service.ListRequest)
-		//response, err := s.List(ctx, req)
-		//r := resp.Format(response, err)
-		return nil, nil
+		req := request.(*service.ListRequest)
+		response, err := s.List(ctx, req)
+		return universalResponse{Data: response, Err: err}, nil
+	}
+}
+
+func StatsEndpoint(s service.Task) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(*service.StatsRequest)
+		response, err := s.Stats(ctx, req)
+		return universalResponse{Data: response, Err: err}, nil
+	}
+}
+
+func AddSignerEndpoint(s service.Task) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(*service.AddSignerRequest)
+		response, err := s.AddSigner(ctx, req)
+		return universalResponse{Data: response, Err: err}, nil
+	}
+}
+
+func SendBackEndpoint(s service.Task) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(*service.SendBackRequest)
+		response, err := s.SendBack(ctx, req)
+		return universalResponse{Data: response, Err: err}, nil
+	}
+}
+
+func CreateDelegationEndpoint(s service.Task) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(*service.CreateDelegationRequest)
+		response, err := s.CreateDelegation(ctx, req)
+		return universalResponse{Data: response, Err: err}, nil
+	}
+}
+
+func DeleteDelegationEndpoint(s service.Task) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(*service.DeleteDelegationRequest)
+		response, err := s.DeleteDelegation(ctx, req)
+		return universalResponse{Data: response, Err: err}, nil
 	}
 }
+
+func ListDelegationEndpoint(s service.Task) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(*service.ListDelegationRequest)
+		response, err := s.ListDelegation(ctx, req)
+		return universalResponse{Data: response, Err: err}, nil
+	}
+}
+
+func ResubmitEndpoint(s service.Task) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(*service.ResubmitRequest)
+		response, err := s.Resubmit(ctx, req)
+		return universalResponse{Data: response, Err: err}, nil
+	}
+}
+
+type ur interface {
+	GetErr() error
+	GetData() interface{}
+}
+
+// universalResponse is the response of the endpoints, encoded by responseJSON
+// like the responses of the core API.
+type universalResponse struct {
+	Err  error       `json:"err,omitempty"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+func (u universalResponse) GetErr() error {
+	return u.Err
+}
+
+func (u universalResponse) GetData() interface{} {
+	return u.Data
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"git.yunify.com/quanxiang/workflow/pkg/log"
+	"git.yunify.com/quanxiang/workflow/pkg/node"
+	"git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine"
+	"git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/apis"
+)
+
+var configPath string
+
+func main() {
+	flag.StringVar(&configPath, "c", "./config.yaml", "-c config path")
+	flag.Parse()
+	conf, err := examine.GetConfig(configPath)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	logger := log.NewLogger(log.LogLevelDebug)
+
+	ctx := context.Background()
+	task, err := examine.New(ctx, conf, logger)
+	if err != nil {
+		panic(err)
+	}
+	endPoints := apis.NewEndPoints(task)
+	node.MainWithConfig(logger, fmt.Sprintf(":%d", conf.Port), conf.Config)(ctx, endPoints, apis.Router(endPoints)...)
+}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+)
+
+// Action is one step an approver or the initiator took on an approval task, kept as its history.
+type Action struct {
+	ID          string
+	TaskID      string //流程实例id
+	ExamineID   string //审核任务id
+	NodeDefKey  string
+	Action      string //agree｜reject｜transfer｜urge｜addSigner｜sendBack｜recall
+	UserID      string //操作人id
+	Target      string //转交人、加签人或退回的节点
+	Remark      string
+	Attachments []Attachment
+	Signature   string //手写签名文件地址
+	CreatedAt   int64
+}
+
+// Attachment references a file uploaded with an action.
+type Attachment struct {
+	FileName string `json:"fileName"`
+	FileURL  string `json:"fileUrl"`
+}
+
+type ActionRepo interface {
+	Create(ctx context.Context, data ...*Action) error
+	// ListByExamineIDs returns the actions on the tasks ids, the oldest first.
+	ListByExamineIDs(ctx context.Context, ids ...string) ([]Action, error)
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+)
+
+// CC is a copy of a run sent to a user by a cc node, to be read only.
+type CC struct {
+	ID          string
+	TaskID      string //流程实例id
+	FlowID      string
+	AppID       string
+	FormTableID string
+	FormDataID  string
+	NodeDefKey  string
+	UserID      string //抄送人id
+	CreatedBy   string //发起人id
+	ReadAt      int64  //阅读时间，0 未读
+	CreatedAt   int64
+}
+
+// CCFilter selects the ccs of CCRepo.List, the empty fields match every cc.
+type CCFilter struct {
+	UserID string
+	AppID  string
+	// Read keeps the read ccs when true and the unread ones when false.
+	Read *bool
+}
+
+type CCRepo interface {
+	Create(ctx context.Context, datas ...*CC) error
+	// List returns a page of the ccs matching filter, the newest first, and how many match.
+	List(ctx context.Context, filter *CCFilter, page, limit int) ([]CC, int, error)
+	ListByTaskIDAndNodeDefKey(ctx context.Context, taskID, nodeDefKey string) ([]CC, error)
+	// Read marks the unread ccs ids of userID, all of them when ids is empty, as read at,
+	// and returns how many it marked.
+	Read(ctx context.Context, userID string, ids []string, at int64) (int64, error)
+}
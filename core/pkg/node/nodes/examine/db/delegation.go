@@ -0,0 +1,28 @@
+package db
+
+import (
+	"context"
+)
+
+// Delegation routes the tasks of UserID to Delegate between StartAt and EndAt.
+// An empty AppID or FlowID matches every app or flow.
+type Delegation struct {
+	ID        string
+	UserID    string //委托人id
+	Delegate  string //代理人id
+	AppID     string
+	FlowID    string
+	StartAt   int64
+	EndAt     int64
+	Remark    string
+	CreatedAt int64
+}
+
+type DelegationRepo interface {
+	Create(ctx context.Context, data *Delegation) error
+	Delete(ctx context.Context, id, userID string) error
+	ListByUserID(ctx context.Context, userID string) ([]Delegation, error)
+	// GetActive returns the delegation of userID in effect at for the app and flow,
+	// preferring the most specific one, or nil.
+	GetActive(ctx context.Context, userID, appID, flowID string, at int64) (*Delegation, error)
+}
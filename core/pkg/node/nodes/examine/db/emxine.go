@@ -2,42 +2,131 @@ package db
 
 import (
 	"context"
+
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
 )
 
 type Task struct {
-	ID          string
+	ID              string
+	TaskID          string
+	FlowID          string
+	UserID          string
+	Substitute      string //转交给的审核人id
+	CreatedBy       string //发起人id
+	ExamineType     string //单人或或签审批：or,多人会签：and,依次审批：sequence,比例/人数审批：quorum
+	Result          string //agree｜reject｜recall｜transfer
+	NodeResult      string //Pending｜Finish｜Waiting｜Archived
+	CreatedAt       int64
+	UpdatedAt       int64
+	AppID           string
+	FormTableID     string
+	FormDataID      string
+	FormRef         string
+	UrgeTimes       int64 //催办次数
+	Remark          string
+	NodeDefKey      string
+	Quorum          string //quorum 审批通过所需人数，如 3 或 60%
+	DelegatedFrom   string //委托人id，任务由代理人代为审批时记录原审核人
+	SignerOf        string //加签人所属的审核任务id
+	RemindEvery     int64  //自动提醒间隔（毫秒），0 不提醒
+	RemindedAt      int64  //上次自动提醒时间
+	FieldPermission string //节点字段权限配置（JSON），为空时不限制
+	DueAt           int64  //处理期限（毫秒时间戳），0 不限
+}
+
+// Overdue reports whether the task is pending past its due time at, or was handled after it.
+func (t *Task) Overdue(at int64) bool {
+	if t.DueAt == 0 {
+		return false
+	}
+	switch t.NodeResult {
+	case string(v1alpha1.Pending):
+		return at > t.DueAt
+	case string(v1alpha1.Finish):
+		// tasks closed by the other approvers or by a recall were not handled late
+		return handled(t.Result) && t.UpdatedAt > t.DueAt
+	}
+	return false
+}
+
+// handled reports whether result was given by the approver of a task.
+func handled(result string) bool {
+	switch result {
+	case "agree", "reject", "transfer", "sendBack":
+		return true
+	}
+	return false
+}
+
+const (
+	// NodeResultWaiting parks a task until the tasks it waits for are done,
+	// e.g. the signers added before it.
+	NodeResultWaiting = "Waiting"
+	// NodeResultArchived marks the tasks of a round that was sent back,
+	// they are kept as history but no longer count for their node.
+	NodeResultArchived = "Archived"
+)
+
+// Groups of TaskRepo.Stats.
+const (
+	StatsByUser = "user"
+	StatsByApp  = "app"
+	StatsByFlow = "flow"
+)
+
+// TaskStats are the statistics of the tasks of one approver, app or flow, archived tasks left out.
+type TaskStats struct {
+	Key         string `json:"key"`         // the user, app or flow id
+	Pending     int64  `json:"pending"`     // pending tasks
+	Overdue     int64  `json:"overdue"`     // pending tasks past their due time
+	Urged       int64  `json:"urged"`       // pending tasks urged at least once
+	Handled     int64  `json:"handled"`     // tasks agreed, rejected, transferred or sent back by their approver
+	Late        int64  `json:"late"`        // handled tasks handled after their due time
+	AvgHandling int64  `json:"avgHandling"` // average milliseconds from the creation of the handled tasks to their handling
+}
+
+// TaskFilter selects the tasks of TaskRepo.List, its empty fields match every task.
+type TaskFilter struct {
+	UserID      string // the approver, or the substitute the task was transferred to
+	CreatedBy   string
 	TaskID      string
-	FlowID      string
-	UserID      string
-	Substitute  string //替代审核人的id
-	CreatedBy   string //发起人id
-	ExamineType string //单人或或签审批：or,多人会签：and
-	Result      string //agree｜reject
-	NodeResult  string //Pending｜Finish｜
-	CreatedAt   int64
-	UpdatedAt   int64
 	AppID       string
-	FormTableID string
-	FormDataID  string
-	FormRef     string
-	UrgeTimes   int64 //催办次数
-	Remark      string
-	NodeDefKey  string
+	FlowID      string
+	Result      string
+	NodeResult  string
+	CreatedFrom int64 // created_at from, included
+	CreatedTo   int64 // created_at to, excluded
 }
 
 type TaskRepo interface {
+	// Transaction runs fn in one transaction, the repos called with the context passed to fn join it.
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
 	InsertBranch(ctx context.Context, data ...*Task) (err error)
 	UpdateSubstitute(ctx context.Context, tasks *Task) error
 	UpdateResult(ctx context.Context, tasks *Task) error
 	UpdateByTaskID(ctx context.Context, tasks *Task) error
+	// UpdateByTaskIDAndNodeDefKey sets node_result of the pending and waiting tasks of one node.
+	UpdateByTaskIDAndNodeDefKey(ctx context.Context, tasks *Task) error
+	// RecallByTaskIDAndNodeDefKey sets result and node_result of the pending and waiting tasks of one node.
+	RecallByTaskIDAndNodeDefKey(ctx context.Context, tasks *Task) error
+	// UpdateSignerOf moves the signers added to the task from to the task to.
+	UpdateSignerOf(ctx context.Context, from, to string) error
 	GetByUserIDAndTaskID(ctx context.Context, userID, taskID string) (*Task, error)
 	ListByTaskID(ctx context.Context, taskID string) ([]Task, error)
 	ListByTaskIDAndNodeDefKey(ctx context.Context, taskID, nodeDefKey string) ([]Task, error)
 	ListByFlowID(ctx context.Context, flowID string) ([]Task, error)
 	UpdateUrgeTimes(ctx context.Context, tasks *Task) error
+	// ListRemindDue returns at most limit pending tasks whose reminder is due at.
+	ListRemindDue(ctx context.Context, at int64, limit int) ([]Task, error)
+	UpdateRemindedAt(ctx context.Context, tasks *Task) error
 	GetByID(ctx context.Context, id string) (*Task, error)
 	GetByUserID(ctx context.Context, userID, nodeType string, page, limit int) ([]Task, int, error)
 	GetByCreated(ctx context.Context, userID string, page, limit int) ([]Task, int, error)
+	// List returns a page of the tasks matching filter, the latest first, and how many match.
+	List(ctx context.Context, filter *TaskFilter, page, limit int) ([]Task, int, error)
+	// Stats returns the statistics of the tasks matching filter grouped by groupBy,
+	// StatsByUser, StatsByApp or StatsByFlow, the pending ones being overdue after at.
+	Stats(ctx context.Context, filter *TaskFilter, groupBy string, at int64) ([]TaskStats, error)
 	//----------
 	//CountTimeOut(ctx context.Context, userID, result, nodeType string) (int64, error)
 	//CountUrgeTimes(ctx context.Context, userID, result, nodeType string) (int64, error)
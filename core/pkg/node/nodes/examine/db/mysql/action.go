@@ -0,0 +1,102 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+)
+
+type action struct {
+	db *sql.DB
+}
+
+func NewAction(db *sql.DB) model.ActionRepo {
+	return &action{
+		db: db,
+	}
+}
+
+func (a *action) Create(ctx context.Context, datas ...*model.Action) error {
+	for k := range datas {
+		attachments, err := json.Marshal(datas[k].Attachments)
+		if err != nil {
+			return err
+		}
+		_, err = connOf(ctx, a.db).ExecContext(ctx,
+			"INSERT INTO `examine_action` (id,task_id,examine_id,node_def_key,action,user_id,target,remark,attachments,signature,created_at) VALUES (?,?,?,?,?,?,?,?,?,?,?)",
+			datas[k].ID,
+			datas[k].TaskID,
+			datas[k].ExamineID,
+			datas[k].NodeDefKey,
+			datas[k].Action,
+			datas[k].UserID,
+			datas[k].Target,
+			datas[k].Remark,
+			string(attachments),
+			datas[k].Signature,
+			datas[k].CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *action) ListByExamineIDs(ctx context.Context, ids ...string) ([]model.Action, error) {
+	list := make([]model.Action, 0)
+	if len(ids) == 0 {
+		return list, nil
+	}
+
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	rows, err := connOf(ctx, a.db).QueryContext(ctx,
+		"select id,task_id,examine_id,node_def_key,action,user_id,target,remark,attachments,signature,created_at from examine_action WHERE examine_id in (?"+
+			strings.Repeat(",?", len(ids)-1)+") order by created_at",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			data        model.Action
+			target      sql.NullString
+			remark      sql.NullString
+			attachments sql.NullString
+		)
+		err := rows.Scan(
+			&data.ID,
+			&data.TaskID,
+			&data.ExamineID,
+			&data.NodeDefKey,
+			&data.Action,
+			&data.UserID,
+			&target,
+			&remark,
+			&attachments,
+			&data.Signature,
+			&data.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		data.Target = target.String
+		data.Remark = remark.String
+		if attachments.String != "" {
+			if err := json.Unmarshal([]byte(attachments.String), &data.Attachments); err != nil {
+				return nil, err
+			}
+		}
+		list = append(list, data)
+	}
+	return list, rows.Err()
+}
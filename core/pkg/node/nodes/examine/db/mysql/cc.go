@@ -0,0 +1,137 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+)
+
+type cc struct {
+	db *sql.DB
+}
+
+func NewCC(db *sql.DB) model.CCRepo {
+	return &cc{
+		db: db,
+	}
+}
+
+const ccColumns = "id,task_id,flow_id,app_id,form_table_id,form_data_id,node_def_key,user_id,created_by,read_at,created_at"
+
+func (c *cc) Create(ctx context.Context, datas ...*model.CC) error {
+	for _, data := range datas {
+		_, err := connOf(ctx, c.db).ExecContext(ctx,
+			"INSERT INTO `examine_cc` ("+ccColumns+") VALUES (?,?,?,?,?,?,?,?,?,?,?)",
+			data.ID,
+			data.TaskID,
+			data.FlowID,
+			data.AppID,
+			data.FormTableID,
+			data.FormDataID,
+			data.NodeDefKey,
+			data.UserID,
+			data.CreatedBy,
+			data.ReadAt,
+			data.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *cc) List(ctx context.Context, filter *model.CCFilter, page, limit int) ([]model.CC, int, error) {
+	where, args := "WHERE 1=1", make([]interface{}, 0, 2)
+	if filter.UserID != "" {
+		where += " and user_id = ?"
+		args = append(args, filter.UserID)
+	}
+	if filter.AppID != "" {
+		where += " and app_id = ?"
+		args = append(args, filter.AppID)
+	}
+	if filter.Read != nil {
+		if *filter.Read {
+			where += " and read_at > 0"
+		} else {
+			where += " and read_at = 0"
+		}
+	}
+
+	var num int
+	err := connOf(ctx, c.db).QueryRowContext(ctx,
+		"select count(id) as total from examine_cc "+where,
+		args...,
+	).Scan(&num)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := connOf(ctx, c.db).QueryContext(ctx,
+		"select "+ccColumns+" from examine_cc "+where+" order by created_at desc limit ?,?",
+		append(args, (page-1)*limit, limit)...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	list, err := scanCC(rows)
+	return list, num, err
+}
+
+func (c *cc) ListByTaskIDAndNodeDefKey(ctx context.Context, taskID, nodeDefKey string) ([]model.CC, error) {
+	rows, err := connOf(ctx, c.db).QueryContext(ctx,
+		"select "+ccColumns+" from examine_cc WHERE task_id = ? and node_def_key = ?",
+		taskID, nodeDefKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanCC(rows)
+}
+
+func (c *cc) Read(ctx context.Context, userID string, ids []string, at int64) (int64, error) {
+	query, args := "UPDATE `examine_cc` SET read_at = ? WHERE user_id = ? and read_at = 0", []interface{}{at, userID}
+	if len(ids) != 0 {
+		query += " and id in (?" + strings.Repeat(",?", len(ids)-1) + ")"
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+	result, err := connOf(ctx, c.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func scanCC(rows *sql.Rows) ([]model.CC, error) {
+	list := make([]model.CC, 0)
+	for rows.Next() {
+		data := model.CC{}
+		err := rows.Scan(
+			&data.ID,
+			&data.TaskID,
+			&data.FlowID,
+			&data.AppID,
+			&data.FormTableID,
+			&data.FormDataID,
+			&data.NodeDefKey,
+			&data.UserID,
+			&data.CreatedBy,
+			&data.ReadAt,
+			&data.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, data)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,99 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+)
+
+type delegation struct {
+	db *sql.DB
+}
+
+func NewDelegation(db *sql.DB) model.DelegationRepo {
+	return &delegation{
+		db: db,
+	}
+}
+
+func (d *delegation) Create(ctx context.Context, data *model.Delegation) error {
+	_, err := connOf(ctx, d.db).ExecContext(ctx,
+		"INSERT INTO `examine_delegation` (id,user_id,delegate,app_id,flow_id,start_at,end_at,remark,created_at) VALUES (?,?,?,?,?,?,?,?,?)",
+		data.ID,
+		data.UserID,
+		data.Delegate,
+		data.AppID,
+		data.FlowID,
+		data.StartAt,
+		data.EndAt,
+		data.Remark,
+		data.CreatedAt,
+	)
+	return err
+}
+
+func (d *delegation) Delete(ctx context.Context, id, userID string) error {
+	_, err := connOf(ctx, d.db).ExecContext(ctx, "DELETE FROM `examine_delegation` WHERE id = ? and user_id = ?", id, userID)
+	return err
+}
+
+func (d *delegation) ListByUserID(ctx context.Context, userID string) ([]model.Delegation, error) {
+	rows, err := connOf(ctx, d.db).QueryContext(ctx,
+		"select id,user_id,delegate,app_id,flow_id,start_at,end_at,remark,created_at from examine_delegation WHERE user_id = ? order by start_at desc",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]model.Delegation, 0)
+	for rows.Next() {
+		data := model.Delegation{}
+		err := rows.Scan(
+			&data.ID,
+			&data.UserID,
+			&data.Delegate,
+			&data.AppID,
+			&data.FlowID,
+			&data.StartAt,
+			&data.EndAt,
+			&data.Remark,
+			&data.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, data)
+	}
+	return list, rows.Err()
+}
+
+func (d *delegation) GetActive(ctx context.Context, userID, appID, flowID string, at int64) (*model.Delegation, error) {
+	row := connOf(ctx, d.db).QueryRowContext(ctx,
+		"select id,user_id,delegate,app_id,flow_id,start_at,end_at,remark,created_at from examine_delegation "+
+			"WHERE user_id = ? and start_at <= ? and end_at >= ? and (app_id = '' or app_id = ?) and (flow_id = '' or flow_id = ?) "+
+			"order by flow_id desc, app_id desc, created_at desc limit 1",
+		userID, at, at, appID, flowID,
+	)
+	data := model.Delegation{}
+	err := row.Scan(
+		&data.ID,
+		&data.UserID,
+		&data.Delegate,
+		&data.AppID,
+		&data.FlowID,
+		&data.StartAt,
+		&data.EndAt,
+		&data.Remark,
+		&data.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &data, nil
+}
@@ -3,6 +3,9 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"fmt"
+
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
 	"git.yunify.com/quanxiang/workflow/pkg/helper/errors"
 	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
 )
@@ -17,14 +20,20 @@ func NewExamineNode(db *sql.DB) model.TaskRepo {
 	}
 }
 
+func (t *examineNode) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return transaction(ctx, t.db, fn)
+}
+
 func (t *examineNode) InsertBranch(ctx context.Context, datas ...*model.Task) (err error) {
-	tx, err := t.db.Begin()
-	if err != nil {
-		return err
-	}
+	return t.Transaction(ctx, func(ctx context.Context) error {
+		return t.insert(ctx, datas...)
+	})
+}
+
+func (t *examineNode) insert(ctx context.Context, datas ...*model.Task) error {
 	for k := range datas {
-		_, err := tx.ExecContext(ctx,
-			"INSERT INTO `examine_node` (id, task_id,flow_id,user_id,substitute,created_by,examine_type,`result`,node_result,created_at,updated_at,app_id,form_table_id,form_data_id,form_ref,urge_times,remark,node_def_key) VALUES (?, ?,?,?,?,?,?, ?,?,?,?,?, ?,?,?,?,?,?)",
+		_, err := connOf(ctx, t.db).ExecContext(ctx,
+			"INSERT INTO `examine_node` (id, task_id,flow_id,user_id,substitute,created_by,examine_type,`result`,node_result,created_at,updated_at,app_id,form_table_id,form_data_id,form_ref,urge_times,remark,node_def_key,quorum,delegated_from,signer_of,remind_every,reminded_at,field_permission,due_at) VALUES (?, ?,?,?,?,?,?, ?,?,?,?,?, ?,?,?,?,?,?,?,?,?,?,?,?,?)",
 			datas[k].ID,
 			datas[k].TaskID,
 			datas[k].FlowID,
@@ -43,19 +52,23 @@ func (t *examineNode) InsertBranch(ctx context.Context, datas ...*model.Task) (e
 			datas[k].UrgeTimes,
 			datas[k].Remark,
 			datas[k].NodeDefKey,
+			datas[k].Quorum,
+			datas[k].DelegatedFrom,
+			datas[k].SignerOf,
+			datas[k].RemindEvery,
+			datas[k].RemindedAt,
+			datas[k].FieldPermission,
+			datas[k].DueAt,
 		)
 		if err != nil {
-			tx.Rollback()
 			return errors.Wrap(err, "fail insert old flow")
 		}
 	}
-
-	tx.Commit()
 	return nil
 }
 
 func (t *examineNode) UpdateSubstitute(ctx context.Context, data *model.Task) error {
-	_, err := t.db.ExecContext(ctx, "UPDATE `examine_node` SET substitute = ?,updated_at=? WHERE id = ?",
+	_, err := connOf(ctx, t.db).ExecContext(ctx, "UPDATE `examine_node` SET substitute = ?,updated_at=? WHERE id = ?",
 		data.Substitute,
 		data.UpdatedAt,
 		data.ID,
@@ -66,7 +79,7 @@ func (t *examineNode) UpdateSubstitute(ctx context.Context, data *model.Task) er
 	return nil
 }
 func (t *examineNode) UpdateResult(ctx context.Context, data *model.Task) error {
-	_, err := t.db.ExecContext(ctx, "UPDATE `examine_node` SET `result` = ?,node_result=?,updated_at=?,remark=? WHERE id = ?",
+	_, err := connOf(ctx, t.db).ExecContext(ctx, "UPDATE `examine_node` SET `result` = ?,node_result=?,updated_at=?,remark=? WHERE id = ?",
 		data.Result,
 		data.NodeResult,
 		data.UpdatedAt,
@@ -79,10 +92,42 @@ func (t *examineNode) UpdateResult(ctx context.Context, data *model.Task) error
 	return nil
 }
 func (t *examineNode) UpdateByTaskID(ctx context.Context, data *model.Task) error {
-	_, err := t.db.ExecContext(ctx, "UPDATE `examine_node` SET `node_result` = ?,updated_at=? WHERE task_id = ?",
+	_, err := connOf(ctx, t.db).ExecContext(ctx, "UPDATE `examine_node` SET `node_result` = ?,updated_at=? WHERE task_id = ? and node_result <> ?",
+		data.NodeResult,
+		data.UpdatedAt,
+		data.TaskID,
+		model.NodeResultArchived,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *examineNode) UpdateByTaskIDAndNodeDefKey(ctx context.Context, data *model.Task) error {
+	_, err := connOf(ctx, t.db).ExecContext(ctx, "UPDATE `examine_node` SET `node_result` = ?,updated_at=? WHERE task_id = ? and node_def_key = ? and node_result in (?,?)",
+		data.NodeResult,
+		data.UpdatedAt,
+		data.TaskID,
+		data.NodeDefKey,
+		string(v1alpha1.Pending),
+		model.NodeResultWaiting,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *examineNode) RecallByTaskIDAndNodeDefKey(ctx context.Context, data *model.Task) error {
+	_, err := connOf(ctx, t.db).ExecContext(ctx, "UPDATE `examine_node` SET `result` = ?,node_result=?,updated_at=? WHERE task_id = ? and node_def_key = ? and node_result in (?,?)",
+		data.Result,
 		data.NodeResult,
 		data.UpdatedAt,
 		data.TaskID,
+		data.NodeDefKey,
+		string(v1alpha1.Pending),
+		model.NodeResultWaiting,
 	)
 	if err != nil {
 		return err
@@ -90,9 +135,17 @@ func (t *examineNode) UpdateByTaskID(ctx context.Context, data *model.Task) erro
 	return nil
 }
 
+func (t *examineNode) UpdateSignerOf(ctx context.Context, from, to string) error {
+	_, err := connOf(ctx, t.db).ExecContext(ctx, "UPDATE `examine_node` SET signer_of = ? WHERE signer_of = ?", to, from)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 func (t *examineNode) ListByTaskID(ctx context.Context, taskID string) ([]model.Task, error) {
-	rows, err := t.db.QueryContext(ctx,
-		"select id, task_id,flow_id,user_id,substitute,created_by,examine_type,`result`,node_result,created_at,updated_at,app_id,form_table_id,form_data_id,form_ref,urge_times,remark,node_def_key from examine_node  WHERE task_id = ?",
+	rows, err := connOf(ctx, t.db).QueryContext(ctx,
+		"select id, task_id,flow_id,user_id,substitute,created_by,examine_type,`result`,node_result,created_at,updated_at,app_id,form_table_id,form_data_id,form_ref,urge_times,remark,node_def_key,quorum,delegated_from,signer_of,remind_every,reminded_at,ifnull(field_permission,''),ifnull(due_at,0) from examine_node  WHERE task_id = ?",
 		taskID,
 	)
 	if err != nil {
@@ -120,6 +173,13 @@ func (t *examineNode) ListByTaskID(ctx context.Context, taskID string) ([]model.
 			&task.UrgeTimes,
 			&task.Remark,
 			&task.NodeDefKey,
+			&task.Quorum,
+			&task.DelegatedFrom,
+			&task.SignerOf,
+			&task.RemindEvery,
+			&task.RemindedAt,
+			&task.FieldPermission,
+			&task.DueAt,
 		)
 		if err != nil {
 			return nil, err
@@ -131,8 +191,8 @@ func (t *examineNode) ListByTaskID(ctx context.Context, taskID string) ([]model.
 }
 
 func (t *examineNode) ListByTaskIDAndNodeDefKey(ctx context.Context, taskID, nodeDefKey string) ([]model.Task, error) {
-	rows, err := t.db.QueryContext(ctx,
-		"select id, task_id,flow_id,user_id,substitute,created_by,examine_type,`result`,node_result,created_at,updated_at,app_id,form_table_id,form_data_id,form_ref,urge_times,remark,node_def_key from examine_node  WHERE task_id = ? and node_def_key=?",
+	rows, err := connOf(ctx, t.db).QueryContext(ctx,
+		"select id, task_id,flow_id,user_id,substitute,created_by,examine_type,`result`,node_result,created_at,updated_at,app_id,form_table_id,form_data_id,form_ref,urge_times,remark,node_def_key,quorum,delegated_from,signer_of,remind_every,reminded_at,ifnull(field_permission,''),ifnull(due_at,0) from examine_node  WHERE task_id = ? and node_def_key=?",
 		taskID,
 		nodeDefKey,
 	)
@@ -164,6 +224,13 @@ func (t *examineNode) ListByTaskIDAndNodeDefKey(ctx context.Context, taskID, nod
 			&task.UrgeTimes,
 			&task.Remark,
 			&task.NodeDefKey,
+			&task.Quorum,
+			&task.DelegatedFrom,
+			&task.SignerOf,
+			&task.RemindEvery,
+			&task.RemindedAt,
+			&task.FieldPermission,
+			&task.DueAt,
 		)
 		if err != nil {
 			return nil, err
@@ -175,8 +242,8 @@ func (t *examineNode) ListByTaskIDAndNodeDefKey(ctx context.Context, taskID, nod
 }
 
 func (t *examineNode) ListByFlowID(ctx context.Context, flowID string) ([]model.Task, error) {
-	rows, err := t.db.QueryContext(ctx,
-		"select id, task_id,flow_id,user_id,substitute,created_by,examine_type,`result`,node_result,created_at,updated_at,app_id,form_table_id,form_data_id,form_ref,urge_times,remark,node_def_key from examine_node  WHERE flow_id=? order by created_at desc",
+	rows, err := connOf(ctx, t.db).QueryContext(ctx,
+		"select id, task_id,flow_id,user_id,substitute,created_by,examine_type,`result`,node_result,created_at,updated_at,app_id,form_table_id,form_data_id,form_ref,urge_times,remark,node_def_key,quorum,delegated_from,signer_of,remind_every,reminded_at,ifnull(field_permission,''),ifnull(due_at,0) from examine_node  WHERE flow_id=? order by created_at desc",
 		flowID,
 	)
 	if err != nil {
@@ -204,6 +271,13 @@ func (t *examineNode) ListByFlowID(ctx context.Context, flowID string) ([]model.
 			&task.UrgeTimes,
 			&task.Remark,
 			&task.NodeDefKey,
+			&task.Quorum,
+			&task.DelegatedFrom,
+			&task.SignerOf,
+			&task.RemindEvery,
+			&task.RemindedAt,
+			&task.FieldPermission,
+			&task.DueAt,
 		)
 		if err != nil {
 			return nil, err
@@ -214,8 +288,8 @@ func (t *examineNode) ListByFlowID(ctx context.Context, flowID string) ([]model.
 	return tasks, nil
 }
 func (t *examineNode) GetByUserIDAndTaskID(ctx context.Context, userID, taskID string) (*model.Task, error) {
-	row := t.db.QueryRowContext(ctx,
-		"select id, task_id,flow_id,user_id,substitute,created_by,examine_type,`result`,node_result,created_at,updated_at,app_id,form_table_id,form_data_id,form_ref,urge_times,remark,node_def_key from examine_node  WHERE task_id = ? and user_id=?",
+	row := connOf(ctx, t.db).QueryRowContext(ctx,
+		"select id, task_id,flow_id,user_id,substitute,created_by,examine_type,`result`,node_result,created_at,updated_at,app_id,form_table_id,form_data_id,form_ref,urge_times,remark,node_def_key,quorum,delegated_from,signer_of,remind_every,reminded_at,ifnull(field_permission,''),ifnull(due_at,0) from examine_node  WHERE task_id = ? and user_id=?",
 		taskID, userID,
 	)
 	task := model.Task{}
@@ -238,6 +312,13 @@ func (t *examineNode) GetByUserIDAndTaskID(ctx context.Context, userID, taskID s
 		&task.UrgeTimes,
 		&task.Remark,
 		&task.NodeDefKey,
+		&task.Quorum,
+		&task.DelegatedFrom,
+		&task.SignerOf,
+		&task.RemindEvery,
+		&task.RemindedAt,
+		&task.FieldPermission,
+		&task.DueAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -249,7 +330,7 @@ func (t *examineNode) GetByUserIDAndTaskID(ctx context.Context, userID, taskID s
 }
 
 func (t *examineNode) UpdateUrgeTimes(ctx context.Context, data *model.Task) error {
-	_, err := t.db.ExecContext(ctx, "UPDATE `examine_node` SET `urge_times` = ?,updated_at=? WHERE id = ?",
+	_, err := connOf(ctx, t.db).ExecContext(ctx, "UPDATE `examine_node` SET `urge_times` = ?,updated_at=? WHERE id = ?",
 		data.UrgeTimes,
 		data.UpdatedAt,
 		data.ID,
@@ -260,9 +341,64 @@ func (t *examineNode) UpdateUrgeTimes(ctx context.Context, data *model.Task) err
 	return nil
 }
 
+func (t *examineNode) ListRemindDue(ctx context.Context, at int64, limit int) ([]model.Task, error) {
+	rows, err := connOf(ctx, t.db).QueryContext(ctx,
+		"select id, task_id,flow_id,user_id,substitute,created_by,examine_type,`result`,node_result,created_at,updated_at,app_id,form_table_id,form_data_id,form_ref,urge_times,remark,node_def_key,quorum,delegated_from,signer_of,remind_every,reminded_at,ifnull(field_permission,''),ifnull(due_at,0) from examine_node  WHERE node_result = ? and remind_every > 0 and greatest(created_at, reminded_at) + remind_every <= ? limit ?",
+		string(v1alpha1.Pending), at, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tasks := make([]model.Task, 0)
+	for rows.Next() {
+		task := model.Task{}
+		err := rows.Scan(
+			&task.ID,
+			&task.TaskID,
+			&task.FlowID,
+			&task.UserID,
+			&task.Substitute,
+			&task.CreatedBy,
+			&task.ExamineType,
+			&task.Result,
+			&task.NodeResult,
+			&task.CreatedAt,
+			&task.UpdatedAt,
+			&task.AppID,
+			&task.FormTableID,
+			&task.FormDataID,
+			&task.FormRef,
+			&task.UrgeTimes,
+			&task.Remark,
+			&task.NodeDefKey,
+			&task.Quorum,
+			&task.DelegatedFrom,
+			&task.SignerOf,
+			&task.RemindEvery,
+			&task.RemindedAt,
+			&task.FieldPermission,
+			&task.DueAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+func (t *examineNode) UpdateRemindedAt(ctx context.Context, data *model.Task) error {
+	_, err := connOf(ctx, t.db).ExecContext(ctx, "UPDATE `examine_node` SET reminded_at = ? WHERE id = ?",
+		data.RemindedAt,
+		data.ID,
+	)
+	return err
+}
+
 func (t *examineNode) GetByID(ctx context.Context, id string) (*model.Task, error) {
-	row := t.db.QueryRowContext(ctx,
-		"select id, task_id,flow_id,user_id,substitute,created_by,examine_type,`result`,node_result,created_at,updated_at,app_id,form_table_id,form_data_id,form_ref,urge_times,remark,node_def_key from examine_node  WHERE id = ?",
+	row := connOf(ctx, t.db).QueryRowContext(ctx,
+		"select id, task_id,flow_id,user_id,substitute,created_by,examine_type,`result`,node_result,created_at,updated_at,app_id,form_table_id,form_data_id,form_ref,urge_times,remark,node_def_key,quorum,delegated_from,signer_of,remind_every,reminded_at,ifnull(field_permission,''),ifnull(due_at,0) from examine_node  WHERE id = ?",
 		id,
 	)
 	task := model.Task{}
@@ -285,6 +421,13 @@ func (t *examineNode) GetByID(ctx context.Context, id string) (*model.Task, erro
 		&task.UrgeTimes,
 		&task.Remark,
 		&task.NodeDefKey,
+		&task.Quorum,
+		&task.DelegatedFrom,
+		&task.SignerOf,
+		&task.RemindEvery,
+		&task.RemindedAt,
+		&task.FieldPermission,
+		&task.DueAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -297,8 +440,8 @@ func (t *examineNode) GetByID(ctx context.Context, id string) (*model.Task, erro
 
 func (t *examineNode) GetByUserID(ctx context.Context, userID, nodeResult string, page, limit int) ([]model.Task, int, error) {
 	startIndex := (page - 1) * limit
-	rows, err := t.db.QueryContext(ctx,
-		"select id, task_id,flow_id,user_id,substitute,created_by,examine_type,`result`,node_result,created_at,updated_at,app_id,form_table_id,form_data_id,form_ref,urge_times,remark,node_def_key from examine_node  WHERE (user_id = ? or substitute=?) and node_result=? limit ?,?",
+	rows, err := connOf(ctx, t.db).QueryContext(ctx,
+		"select id, task_id,flow_id,user_id,substitute,created_by,examine_type,`result`,node_result,created_at,updated_at,app_id,form_table_id,form_data_id,form_ref,urge_times,remark,node_def_key,quorum,delegated_from,signer_of,remind_every,reminded_at,ifnull(field_permission,''),ifnull(due_at,0) from examine_node  WHERE (user_id = ? or substitute=?) and node_result=? limit ?,?",
 		userID,
 		userID,
 		nodeResult,
@@ -312,7 +455,7 @@ func (t *examineNode) GetByUserID(ctx context.Context, userID, nodeResult string
 		return nil, 0, err
 	}
 	var num int = 0
-	countRow := t.db.QueryRowContext(ctx,
+	countRow := connOf(ctx, t.db).QueryRowContext(ctx,
 		"select count(id) as total from examine_node  WHERE (user_id = ? or substitute=?) and node_result=?",
 		userID,
 		userID,
@@ -347,6 +490,13 @@ func (t *examineNode) GetByUserID(ctx context.Context, userID, nodeResult string
 			&task.UrgeTimes,
 			&task.Remark,
 			&task.NodeDefKey,
+			&task.Quorum,
+			&task.DelegatedFrom,
+			&task.SignerOf,
+			&task.RemindEvery,
+			&task.RemindedAt,
+			&task.FieldPermission,
+			&task.DueAt,
 		)
 		if err != nil {
 			return nil, 0, err
@@ -359,8 +509,8 @@ func (t *examineNode) GetByUserID(ctx context.Context, userID, nodeResult string
 
 func (t *examineNode) GetByCreated(ctx context.Context, userID string, page, limit int) ([]model.Task, int, error) {
 	startIndex := (page - 1) * limit
-	rows, err := t.db.QueryContext(ctx,
-		"select id, task_id,flow_id,user_id,substitute,created_by,examine_type,`result`,node_result,created_at,updated_at,app_id,form_table_id,form_data_id,form_ref,urge_times,remark,node_def_key from examine_node  WHERE created_by = ? limit ?,?",
+	rows, err := connOf(ctx, t.db).QueryContext(ctx,
+		"select id, task_id,flow_id,user_id,substitute,created_by,examine_type,`result`,node_result,created_at,updated_at,app_id,form_table_id,form_data_id,form_ref,urge_times,remark,node_def_key,quorum,delegated_from,signer_of,remind_every,reminded_at,ifnull(field_permission,''),ifnull(due_at,0) from examine_node  WHERE created_by = ? limit ?,?",
 		userID,
 		startIndex,
 		limit,
@@ -372,7 +522,7 @@ func (t *examineNode) GetByCreated(ctx context.Context, userID string, page, lim
 		return nil, 0, err
 	}
 	var num int = 0
-	countRow := t.db.QueryRowContext(ctx,
+	countRow := connOf(ctx, t.db).QueryRowContext(ctx,
 		"select count(id) as total from examine_node  WHERE created_by = ?",
 		userID,
 	)
@@ -405,6 +555,13 @@ func (t *examineNode) GetByCreated(ctx context.Context, userID string, page, lim
 			&task.UrgeTimes,
 			&task.Remark,
 			&task.NodeDefKey,
+			&task.Quorum,
+			&task.DelegatedFrom,
+			&task.SignerOf,
+			&task.RemindEvery,
+			&task.RemindedAt,
+			&task.FieldPermission,
+			&task.DueAt,
 		)
 		if err != nil {
 			return nil, 0, err
@@ -415,6 +572,158 @@ func (t *examineNode) GetByCreated(ctx context.Context, userID string, page, lim
 	return tasks, num, err
 }
 
+func (t *examineNode) List(ctx context.Context, filter *model.TaskFilter, page, limit int) ([]model.Task, int, error) {
+	where, args := whereOf(filter)
+
+	var num int = 0
+	err := connOf(ctx, t.db).QueryRowContext(ctx,
+		"select count(id) as total from examine_node "+where,
+		args...,
+	).Scan(&num)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	startIndex := (page - 1) * limit
+	rows, err := connOf(ctx, t.db).QueryContext(ctx,
+		"select id, task_id,flow_id,user_id,substitute,created_by,examine_type,`result`,node_result,created_at,updated_at,app_id,form_table_id,form_data_id,form_ref,urge_times,remark,node_def_key,quorum,delegated_from,signer_of,remind_every,reminded_at,ifnull(field_permission,''),ifnull(due_at,0) from examine_node "+where+" order by created_at desc limit ?,?",
+		append(args, startIndex, limit)...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	tasks := make([]model.Task, 0)
+	for rows.Next() {
+		task := model.Task{}
+		err := rows.Scan(
+			&task.ID,
+			&task.TaskID,
+			&task.FlowID,
+			&task.UserID,
+			&task.Substitute,
+			&task.CreatedBy,
+			&task.ExamineType,
+			&task.Result,
+			&task.NodeResult,
+			&task.CreatedAt,
+			&task.UpdatedAt,
+			&task.AppID,
+			&task.FormTableID,
+			&task.FormDataID,
+			&task.FormRef,
+			&task.UrgeTimes,
+			&task.Remark,
+			&task.NodeDefKey,
+			&task.Quorum,
+			&task.DelegatedFrom,
+			&task.SignerOf,
+			&task.RemindEvery,
+			&task.RemindedAt,
+			&task.FieldPermission,
+			&task.DueAt,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, num, rows.Err()
+}
+
+// whereOf returns the where clause selecting the tasks matching filter, and its arguments.
+func whereOf(filter *model.TaskFilter) (string, []interface{}) {
+	where, args := "WHERE 1=1", make([]interface{}, 0, 9)
+	if filter.UserID != "" {
+		where += " and (user_id = ? or substitute = ?)"
+		args = append(args, filter.UserID, filter.UserID)
+	}
+	for _, cond := range []struct {
+		column, value string
+	}{
+		{"created_by", filter.CreatedBy},
+		{"task_id", filter.TaskID},
+		{"app_id", filter.AppID},
+		{"flow_id", filter.FlowID},
+		{"`result`", filter.Result},
+		{"node_result", filter.NodeResult},
+	} {
+		if cond.value != "" {
+			where += " and " + cond.column + " = ?"
+			args = append(args, cond.value)
+		}
+	}
+	if filter.CreatedFrom != 0 {
+		where += " and created_at >= ?"
+		args = append(args, filter.CreatedFrom)
+	}
+	if filter.CreatedTo != 0 {
+		where += " and created_at < ?"
+		args = append(args, filter.CreatedTo)
+	}
+	return where, args
+}
+
+const statsColumns = "sum(node_result = ?)," +
+	"sum(node_result = ? and due_at > 0 and due_at < ?)," +
+	"sum(node_result = ? and urge_times > 0)," +
+	// results of the approvers, recall is the initiator's
+	"sum(node_result = ? and `result` in ('agree','reject','transfer','sendBack'))," +
+	"sum(node_result = ? and `result` in ('agree','reject','transfer','sendBack') and due_at > 0 and updated_at > due_at)," +
+	"ifnull(avg(case when node_result = ? and `result` in ('agree','reject','transfer','sendBack') then updated_at - created_at end),0)"
+
+func (t *examineNode) Stats(ctx context.Context, filter *model.TaskFilter, groupBy string, at int64) ([]model.TaskStats, error) {
+	var column string
+	switch groupBy {
+	case model.StatsByUser:
+		column = "user_id"
+	case model.StatsByApp:
+		column = "app_id"
+	case model.StatsByFlow:
+		column = "flow_id"
+	default:
+		return nil, fmt.Errorf("unknown stats group %q", groupBy)
+	}
+	where, args := whereOf(filter)
+	pending, finish := string(v1alpha1.Pending), string(v1alpha1.Finish)
+	args = append([]interface{}{pending, pending, at, pending, finish, finish, finish}, args...)
+	args = append(args, model.NodeResultArchived)
+
+	rows, err := connOf(ctx, t.db).QueryContext(ctx,
+		"select ifnull("+column+",''),"+statsColumns+" from examine_node "+where+" and node_result <> ? group by "+column,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]model.TaskStats, 0)
+	for rows.Next() {
+		var (
+			data        model.TaskStats
+			avgHandling float64
+		)
+		err := rows.Scan(
+			&data.Key,
+			&data.Pending,
+			&data.Overdue,
+			&data.Urged,
+			&data.Handled,
+			&data.Late,
+			&avgHandling,
+		)
+		if err != nil {
+			return nil, err
+		}
+		data.AvgHandling = int64(avgHandling)
+		list = append(list, data)
+	}
+	return list, rows.Err()
+}
+
 //-------------------------
 //
 //func (t *examineNode) CountTimeOut(ctx context.Context, db *gorm.DB, userID, result, nodeResult string) (int64, error) {
@@ -0,0 +1,99 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+)
+
+type outbox struct {
+	db *sql.DB
+}
+
+func NewOutbox(db *sql.DB) model.OutboxRepo {
+	return &outbox{
+		db: db,
+	}
+}
+
+func (o *outbox) Create(ctx context.Context, data *model.Outbox) error {
+	_, err := connOf(ctx, o.db).ExecContext(ctx,
+		"INSERT INTO `examine_outbox` (id,run_id,action,node,from_node,form,attempts,next_at,last_error,created_at) VALUES (?,?,?,?,?,?,?,?,?,?)",
+		data.ID,
+		data.RunID,
+		data.Action,
+		data.Node,
+		data.From,
+		data.Form,
+		data.Attempts,
+		data.NextAt,
+		data.LastError,
+		data.CreatedAt,
+	)
+	return err
+}
+
+func (o *outbox) Delete(ctx context.Context, id string) error {
+	_, err := connOf(ctx, o.db).ExecContext(ctx, "DELETE FROM `examine_outbox` WHERE id = ?", id)
+	return err
+}
+
+func (o *outbox) ListDue(ctx context.Context, at int64, limit int) ([]model.Outbox, error) {
+	rows, err := connOf(ctx, o.db).QueryContext(ctx,
+		"select id,run_id,action,node,from_node,form,attempts,next_at,last_error,created_at from examine_outbox WHERE next_at <= ? order by created_at limit ?",
+		at, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]model.Outbox, 0)
+	for rows.Next() {
+		data := model.Outbox{}
+		var form sql.NullString
+		err := rows.Scan(
+			&data.ID,
+			&data.RunID,
+			&data.Action,
+			&data.Node,
+			&data.From,
+			&form,
+			&data.Attempts,
+			&data.NextAt,
+			&data.LastError,
+			&data.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		data.Form = form.String
+		list = append(list, data)
+	}
+	return list, rows.Err()
+}
+
+func (o *outbox) UpdateAttempt(ctx context.Context, data *model.Outbox) error {
+	_, err := connOf(ctx, o.db).ExecContext(ctx, "UPDATE `examine_outbox` SET attempts = ?,next_at = ?,last_error = ?,form = ? WHERE id = ?",
+		data.Attempts,
+		data.NextAt,
+		data.LastError,
+		data.Form,
+		data.ID,
+	)
+	return err
+}
+
+func (o *outbox) Claim(ctx context.Context, id string, nextAt, lease int64) (bool, error) {
+	res, err := connOf(ctx, o.db).ExecContext(ctx, "UPDATE `examine_outbox` SET next_at = ? WHERE id = ? and next_at = ?",
+		lease,
+		id,
+		nextAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
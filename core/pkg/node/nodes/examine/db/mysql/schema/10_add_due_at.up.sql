@@ -0,0 +1,2 @@
+alter table examine_node
+    add column due_at bigint default 0;
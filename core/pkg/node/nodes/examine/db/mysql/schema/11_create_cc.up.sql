@@ -0,0 +1,16 @@
+create table examine_cc
+(
+    id            varchar(200) PRIMARY KEY,
+    task_id       varchar(200) not null,
+    flow_id       varchar(200) not null default '',
+    app_id        varchar(200) not null default '',
+    form_table_id varchar(200) not null default '',
+    form_data_id  varchar(200) not null default '',
+    node_def_key  varchar(200) not null default '',
+    user_id       varchar(200) not null,
+    created_by    varchar(200) not null default '',
+    read_at       bigint       not null default 0,
+    created_at    bigint,
+    index idx_user_id_created_at (user_id, created_at),
+    index idx_task_id (task_id, node_def_key)
+);
@@ -0,0 +1,2 @@
+alter table examine_outbox
+    add column form text;
@@ -0,0 +1,2 @@
+alter table examine_outbox
+    add column from_node varchar(200) not null default '';
@@ -0,0 +1,2 @@
+alter table examine_node
+    add column quorum varchar(20) not null default '';
@@ -0,0 +1,16 @@
+create table examine_delegation
+(
+    id         varchar(200) PRIMARY KEY,
+    user_id    varchar(200) not null,
+    delegate   varchar(200) not null,
+    app_id     varchar(200) not null default '',
+    flow_id    varchar(200) not null default '',
+    start_at   bigint       not null,
+    end_at     bigint       not null,
+    remark     text,
+    created_at bigint,
+    index idx_user_id (user_id)
+);
+
+alter table examine_node
+    add column delegated_from varchar(200) not null default '';
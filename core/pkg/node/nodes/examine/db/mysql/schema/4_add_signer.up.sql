@@ -0,0 +1,2 @@
+alter table examine_node
+    add column signer_of varchar(200) not null default '';
@@ -0,0 +1,12 @@
+create table examine_outbox
+(
+    id         varchar(200) PRIMARY KEY,
+    run_id     bigint       not null,
+    action     varchar(20)  not null,
+    node       varchar(200) not null default '',
+    attempts   bigint       not null default 0,
+    next_at    bigint       not null,
+    last_error text,
+    created_at bigint,
+    index idx_next_at (next_at)
+);
@@ -0,0 +1,3 @@
+alter table examine_node
+    add column remind_every bigint not null default 0,
+    add column reminded_at  bigint not null default 0;
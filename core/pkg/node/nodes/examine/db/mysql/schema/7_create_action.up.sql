@@ -0,0 +1,16 @@
+create table examine_action
+(
+    id           varchar(200) PRIMARY KEY,
+    task_id      varchar(200) not null,
+    examine_id   varchar(200) not null,
+    node_def_key varchar(200) not null default '',
+    action       varchar(20)  not null,
+    user_id      varchar(200) not null,
+    target       text,
+    remark       text,
+    attachments  text,
+    signature    varchar(500) not null default '',
+    created_at   bigint,
+    index idx_task_id (task_id),
+    index idx_examine_id (examine_id)
+);
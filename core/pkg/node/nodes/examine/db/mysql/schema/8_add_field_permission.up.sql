@@ -0,0 +1,2 @@
+alter table examine_node
+    add column field_permission text;
@@ -0,0 +1,4 @@
+create index idx_examine_node_user_id on examine_node (user_id);
+create index idx_examine_node_created_by on examine_node (created_by);
+create index idx_examine_node_task_id on examine_node (task_id, node_def_key);
+create index idx_examine_node_app_id on examine_node (app_id, created_at);
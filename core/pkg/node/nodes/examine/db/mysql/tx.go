@@ -0,0 +1,49 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+)
+
+// conn is what the repos run their statements on, the database or the transaction of the context.
+type conn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type txKey struct{}
+
+// connOf returns the transaction started by transaction for ctx, or db outside of one.
+func connOf(ctx context.Context, db *sql.DB) conn {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// transaction runs fn in a transaction of db, the repos called with the context passed to fn join it.
+// fn joins the transaction of ctx if there is one already.
+func transaction(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	return fn(context.WithValue(ctx, txKey{}, tx))
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"context"
+)
+
+const (
+	// OutboxExec resumes the pipeline run.
+	OutboxExec = "exec"
+	// OutboxRewind rewinds the pipeline run to Node.
+	OutboxRewind = "rewind"
+)
+
+// Outbox is a request to the workflow core, recorded with the approval state change that
+// causes it and sent once that change is committed, until it is delivered.
+type Outbox struct {
+	ID     string
+	RunID  int64
+	Action string //exec｜rewind
+	Node   string
+	// From is the node the run has to be pending at for OutboxRewind, so that it is rewound once only.
+	From string
+	// Form is the form data update applied before Action, none when empty.
+	Form      string
+	Attempts  int64
+	NextAt    int64 //下次投递时间
+	LastError string
+	CreatedAt int64
+}
+
+type OutboxRepo interface {
+	Create(ctx context.Context, data *Outbox) error
+	Delete(ctx context.Context, id string) error
+	// ListDue returns at most limit messages due at, the oldest first.
+	ListDue(ctx context.Context, at int64, limit int) ([]Outbox, error)
+	// UpdateAttempt records a failed delivery, and the form data update left to apply.
+	UpdateAttempt(ctx context.Context, data *Outbox) error
+	// Claim moves the message id due at nextAt to lease, and reports whether it did: false when
+	// another delivery claimed it or it is gone.
+	Claim(ctx context.Context, id string, nextAt, lease int64) (bool, error)
+}
@@ -0,0 +1,91 @@
+package examine
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"git.yunify.com/quanxiang/workflow/pkg/helper/errors"
+	"git.yunify.com/quanxiang/workflow/pkg/helper/signature"
+	"git.yunify.com/quanxiang/workflow/pkg/node"
+	"git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db/mysql"
+	"git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/service"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"gopkg.in/yaml.v3"
+)
+
+// Type is the type of the node.
+const Type = "approve"
+
+// Config of the examine node, from its config file for the node service and from the core config
+// when it is local.
+type Config struct {
+	Port     int    `yaml:"port"`
+	LogLevel string `yaml:"log_level"`
+
+	Mysql            mysql.Mysql `yaml:"mysql"`
+	QxInstance       []string    `yaml:"qx_instance"`
+	WorkFlowInstance string      `yaml:"work_flow_instance"`
+	HomeHost         string      `yaml:"home_host"`
+	// Admins may act on every approval task.
+	Admins []string `yaml:"admins"`
+	// Notify configures the messages about approval tasks.
+	Notify service.Notify `yaml:"notify"`
+	// RelayInterval is how often the pipeline resumes whose delivery failed are retried, 10s by default.
+	RelayInterval time.Duration `yaml:"relay_interval"`
+
+	node.Config `yaml:",inline"`
+}
+
+func GetConfig(path string) (*Config, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "fail get config file")
+	}
+
+	conf := &Config{}
+	err = yaml.Unmarshal(body, conf)
+	if err != nil {
+		return nil, errors.Wrap(err, "fail unmarshal config file")
+	}
+
+	return conf, nil
+}
+
+// New returns the examine node of conf. It resumes the runs whose delivery failed and reminds the
+// approvers of their tasks until ctx is done.
+func New(ctx context.Context, conf *Config, logger log.Logger) (service.Task, error) {
+	db, err := mysql.NewDB(&conf.Mysql)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := signature.New(conf.Auth)
+	if err != nil {
+		return nil, err
+	}
+	if signer == nil {
+		level.Warn(logger).Log("message", "request signing is disabled, the examine actions trust the User-Id header as is: "+
+			"set auth keys unless a gateway authenticates every request to the node")
+	}
+	task := service.NewTask(db, conf.QxInstance, logger, conf.WorkFlowInstance, conf.HomeHost, conf.Admins, conf.Notify,
+		httptransport.ClientBefore(signer.RequestFunc()))
+	relayInterval := conf.RelayInterval
+	if relayInterval <= 0 {
+		relayInterval = 10 * time.Second
+	}
+	go task.Relay(ctx, relayInterval)
+	go task.Remind(ctx, time.Minute)
+	return task, nil
+}
+
+func init() {
+	node.Register(Type, func(ctx context.Context, logger log.Logger, decode func(interface{}) error) (node.Interface, error) {
+		conf := &Config{}
+		if err := decode(conf); err != nil {
+			return nil, err
+		}
+		return New(ctx, conf, logger)
+	})
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+
+	"github.com/quanxiang-cloud/cabin/id"
+	"github.com/quanxiang-cloud/cabin/time"
+
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+)
+
+const (
+	// ActionUrge is recorded on the tasks urged by the initiator.
+	ActionUrge = "urge"
+	// ActionAddSigner is recorded on the task whose approver added signers.
+	ActionAddSigner = "addSigner"
+	// ActionAutoAgree is recorded on the tasks agreed by the auto-approve rules of their node.
+	ActionAutoAgree = "autoAgree"
+)
+
+// newAction returns the action of userID on task, the other actions are ResultAgree, ResultReject,
+// ResultTransfer, ResultSendBack and ResultRecall.
+func newAction(task *model.Task, action, userID string) *model.Action {
+	return &model.Action{
+		ID:         id.BaseUUID(),
+		TaskID:     task.TaskID,
+		ExamineID:  task.ID,
+		NodeDefKey: task.NodeDefKey,
+		Action:     action,
+		UserID:     userID,
+		CreatedAt:  time.NowUnix(),
+	}
+}
+
+// actionsOf returns the actions on tasks by task id.
+func (t *task) actionsOf(ctx context.Context, tasks ...model.Task) (map[string][]model.Action, error) {
+	ids := make([]string, 0, len(tasks))
+	for k := range tasks {
+		ids = append(ids, tasks[k].ID)
+	}
+	list, err := t.actionRepo.ListByExamineIDs(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+	actions := make(map[string][]model.Action, len(tasks))
+	for k := range list {
+		actions[list[k].ExamineID] = append(actions[list[k].ExamineID], list[k])
+	}
+	return actions, nil
+}
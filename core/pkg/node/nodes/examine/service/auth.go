@@ -0,0 +1,50 @@
+package service
+
+import (
+	"net/http"
+
+	"git.yunify.com/quanxiang/workflow/pkg/helper/errors"
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+)
+
+// forbidden is returned when the caller may not take an action on a task,
+// responded with 403 like the errors of the core API.
+func forbidden(message string) error {
+	return errors.NewErr(http.StatusForbidden, &errors.CodeError{
+		Code:    http.StatusForbidden,
+		Message: message,
+	})
+}
+
+func (t *task) isAdmin(userID string) bool {
+	_, ok := t.admins[userID]
+	return ok
+}
+
+// filterUser returns the approver callerID may filter the tasks by, any of them for the admins
+// and callerID only for the others.
+func (t *task) filterUser(callerID, userID string) (string, error) {
+	if callerID == "" {
+		return "", forbidden("the caller is required")
+	}
+	if t.isAdmin(callerID) {
+		return userID, nil
+	}
+	return callerID, nil
+}
+
+// canHandle reports whether userID may act on task as its approver.
+func (t *task) canHandle(task *model.Task, userID string) bool {
+	if userID == "" {
+		return false
+	}
+	return task.UserID == userID || task.Substitute == userID || t.isAdmin(userID)
+}
+
+// canManage reports whether userID may act on the run of task as its initiator.
+func (t *task) canManage(task *model.Task, userID string) bool {
+	if userID == "" {
+		return false
+	}
+	return task.CreatedBy == userID || t.isAdmin(userID)
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+
+	"github.com/quanxiang-cloud/cabin/time"
+
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+)
+
+// Rules of the autoApprove param of a node.
+const (
+	// AutoInitiator agrees the task of the initiator.
+	AutoInitiator = "initiator"
+	// AutoApproved agrees the tasks of the approvers who already agreed on another node of the run.
+	AutoApproved = "approved"
+)
+
+// Fallbacks of the noApprover param of a node, for when no approver is resolved.
+const (
+	// NoApproverKill stops the run, the default.
+	NoApproverKill = "kill"
+	// NoApproverAgree finishes the node as agreed.
+	NoApproverAgree = "agree"
+	// NoApproverAdmin gives the task to the first admin of the examine node.
+	NoApproverAdmin = "admin"
+)
+
+// assign inserts datas, the new tasks of the node of req, once its auto-approve rules
+// are applied, and tells their approvers.
+func (t *task) assign(ctx context.Context, req *DoRequest, datas ...*model.Task) error {
+	err := t.taskRepo.Transaction(ctx, func(ctx context.Context) error {
+		err := t.autoApprove(ctx, req, datas)
+		if err != nil {
+			return err
+		}
+		return t.taskRepo.InsertBranch(ctx, datas...)
+	})
+	if err != nil {
+		return err
+	}
+	t.notifyPending(datas...)
+	return nil
+}
+
+// autoApprove agrees the tasks of datas the auto-approve rules of the node of req apply to,
+// and records why. The other tasks of an or node are done as soon as one is agreed.
+func (t *task) autoApprove(ctx context.Context, req *DoRequest, datas []*model.Task) error {
+	rules := make(map[string]bool, len(req.AutoApprove))
+	for _, rule := range req.AutoApprove {
+		rules[rule] = true
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	approved := make(map[string]struct{})
+	if rules[AutoApproved] {
+		tasks, err := t.taskRepo.ListByTaskID(ctx, req.TaskID)
+		if err != nil {
+			return err
+		}
+		for _, task := range current(tasks) {
+			if task.NodeDefKey != req.NodeDefKey && task.Result == ResultAgree {
+				approved[task.UserID] = struct{}{}
+			}
+		}
+	}
+	is := func(task *model.Task, userID string) bool {
+		return userID != "" && (task.UserID == userID || task.DelegatedFrom == userID)
+	}
+	wasApproved := func(task *model.Task) bool {
+		_, ok := approved[task.UserID]
+		if !ok && task.DelegatedFrom != "" {
+			_, ok = approved[task.DelegatedFrom]
+		}
+		return ok
+	}
+
+	var agreed bool
+	for _, data := range datas {
+		var reason string
+		switch {
+		case rules[AutoInitiator] && is(data, req.CreatedBy):
+			reason = "审批人为发起人，自动通过"
+		case rules[AutoApproved] && wasApproved(data):
+			reason = "审批人已审批过，自动通过"
+		default:
+			continue
+		}
+		data.NodeResult = string(v1alpha1.Finish)
+		data.Result = ResultAgree
+		data.Remark = reason
+		data.UpdatedAt = time.NowUnix()
+
+		act := newAction(data, ActionAutoAgree, data.UserID)
+		act.Remark = reason
+		err := t.actionRepo.Create(ctx, act)
+		if err != nil {
+			return err
+		}
+		agreed = true
+	}
+
+	if agreed && req.TaskType == ExamineOr {
+		for _, data := range datas {
+			if data.NodeResult == string(v1alpha1.Pending) {
+				data.NodeResult = string(v1alpha1.Finish)
+				data.UpdatedAt = time.NowUnix()
+			}
+		}
+	}
+	return nil
+}
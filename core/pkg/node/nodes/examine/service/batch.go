@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	e "github.com/quanxiang-cloud/cabin/error"
+
+	herrors "git.yunify.com/quanxiang/workflow/pkg/helper/errors"
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+)
+
+const (
+	// batchMax is the most tasks a batch may decide.
+	batchMax = 100
+	// batchWorkers is how many tasks of a batch are decided at once.
+	batchWorkers = 8
+)
+
+type BatchRequest struct {
+	UserID     string
+	ExamineIDs []string
+	// Action is ResultAgree or ResultReject.
+	Action string
+	Remark string
+}
+
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+// BatchResult is the outcome of one task of a batch, Code is 0 when it was decided.
+type BatchResult struct {
+	ExamineID string `json:"examineID"`
+	Code      int64  `json:"code"`
+	Message   string `json:"msg,omitempty"`
+}
+
+// Batch agrees or rejects the tasks req.ExamineIDs of req.UserID concurrently, each one in its own
+// transaction like Agree and Reject, and reports the outcome of each one in order.
+// The runs of the decided tasks are resumed once all of them are committed, each run once.
+func (t *task) Batch(ctx context.Context, req *BatchRequest) (*BatchResponse, error) {
+	if req.Action != ResultAgree && req.Action != ResultReject {
+		return nil, e.NewErrorWithString(e.ErrParams, "action must be agree or reject")
+	}
+	if len(req.ExamineIDs) == 0 || len(req.ExamineIDs) > batchMax {
+		return nil, e.NewErrorWithString(e.ErrParams, "examineIDs must hold 1 to 100 tasks")
+	}
+
+	var (
+		results = make([]BatchResult, len(req.ExamineIDs))
+		tasks   = make([]*model.Task, len(req.ExamineIDs))
+		msgs    = make([]*model.Outbox, len(req.ExamineIDs))
+		workers = make(chan struct{}, batchWorkers)
+		wg      sync.WaitGroup
+	)
+	for k := range req.ExamineIDs {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func(k int) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			examineID := req.ExamineIDs[k]
+			task, msg, err := t.commitDecision(ctx, examineID, &model.Action{
+				Action: req.Action,
+				UserID: req.UserID,
+				Remark: req.Remark,
+			}, nil)
+			if err != nil {
+				level.Error(t.logger).Log("message", "examine batch", "examineID", examineID, "err", err.Error())
+			}
+			results[k] = batchResult(examineID, err)
+			tasks[k], msgs[k] = task, msg
+		}(k)
+	}
+	wg.Wait()
+
+	t.deliverOnce(ctx, msgs)
+	if req.Action == ResultAgree {
+		for _, task := range tasks {
+			if task != nil {
+				t.notifySigners(ctx, task)
+			}
+		}
+	}
+	return &BatchResponse{
+		Results: results,
+	}, nil
+}
+
+// deliverOnce delivers one of the messages of msgs to each run and drops the others,
+// resuming a run once is enough for its node to see all the committed results.
+// The messages updating form data are all delivered.
+func (t *task) deliverOnce(ctx context.Context, msgs []*model.Outbox) {
+	delivered := make(map[int64]struct{}, len(msgs))
+	for _, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+		if _, ok := delivered[msg.RunID]; ok && msg.Action == model.OutboxExec && msg.Form == "" {
+			if err := t.outboxRepo.Delete(ctx, msg.ID); err != nil {
+				level.Error(t.logger).Log("message", "examine delete outbox", "id", msg.ID, "err", err.Error())
+			}
+			continue
+		}
+		delivered[msg.RunID] = struct{}{}
+		_ = t.deliver(ctx, msg)
+	}
+}
+
+func batchResult(examineID string, err error) BatchResult {
+	r := BatchResult{
+		ExamineID: examineID,
+	}
+	if err == nil {
+		return r
+	}
+	r.Code, r.Message = e.Unknown, err.Error()
+	switch err := errors.Cause(err).(type) {
+	case e.Error:
+		r.Code = err.Code
+	case *e.Error:
+		r.Code = err.Code
+	case *herrors.Error:
+		r.Code = int64(err.Code)
+		if ce, ok := err.Message.(*herrors.CodeError); ok {
+			r.Message = ce.Message
+		}
+	}
+	return r
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	e "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/cabin/id"
+	"github.com/quanxiang-cloud/cabin/time"
+
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
+	"git.yunify.com/quanxiang/workflow/pkg/node"
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+)
+
+// CC is the service of the cc node, which copies a run to the users of its dealUsers
+// and finishes at once. The copies are read through ListCC and ReadCC.
+type CC interface {
+	node.Interface
+
+	ListCC(ctx context.Context, req *ListCCRequest) (*ListCCResponse, error)
+	ReadCC(ctx context.Context, req *ReadCCRequest) (*ReadCCResponse, error)
+}
+
+type ccNode struct {
+	*task
+}
+
+// NewCC returns the cc service, sharing the database of the examine service.
+func NewCC(db *sql.DB, instance []string, logger log.Logger, homeHost string, notify Notify) CC {
+	return &ccNode{
+		task: NewTask(db, instance, logger, "", homeHost, nil, notify).(*task),
+	}
+}
+
+// Do records a cc of the run for each user dealUsers resolves to, the way the approvers
+// of an examine node are resolved, and tells them. A node run again keeps its first ccs.
+func (c *ccNode) Do(ctx context.Context, in *node.Request) (*node.Result, error) {
+	req := new(DoRequest)
+	var s string
+	params := make(map[string]string, len(in.Params))
+	for k := range in.Params {
+		params[in.Params[k].Key] = in.Params[k].Value
+		switch in.Params[k].Key {
+		case AppID:
+			req.AppID = in.Params[k].Value
+		case FlowID:
+			req.FlowID = in.Params[k].Value
+		case FormTableID:
+			req.FormID = in.Params[k].Value
+		case FormDataID:
+			req.FormDataID = in.Params[k].Value
+		case CreatedBy:
+			req.CreatedBy = in.Params[k].Value
+		case DealUsers:
+			s = in.Params[k].Value
+		case SkipInitiator:
+			req.SkipInitiator = in.Params[k].Value == "true"
+		}
+	}
+	req.TaskID = in.Metadata.Annotations[TaskID]
+	req.NodeDefKey = in.Metadata.Annotations[NodeID]
+	if s == "" {
+		return node.Permanent(node.ErrCodeInvalidParams, "have no user to cc", nil), nil
+	}
+
+	ccs, err := c.ccRepo.ListByTaskIDAndNodeDefKey(ctx, req.TaskID, req.NodeDefKey)
+	if err != nil {
+		return &node.Result{Status: v1alpha1.Kill}, err
+	}
+	if len(ccs) != 0 {
+		return &node.Result{Status: v1alpha1.Finish}, nil
+	}
+
+	userIDs, createUserID, err := c.recipients(ctx, s, req, params)
+	if errors.Is(err, errNoFormData) {
+		level.Error(c.logger).Log("message", err, "formDataID", req.FormDataID)
+		return node.Permanent(node.ErrCodeNotFound, err.Error(), map[string]string{
+			"formDataID": req.FormDataID,
+		}), nil
+	}
+	if err != nil {
+		level.Error(c.logger).Log("message", err, "dealUsers", s)
+		return &node.Result{Status: v1alpha1.Kill}, err
+	}
+	if createUserID != "" {
+		req.CreatedBy = createUserID
+	}
+	userIDs = dedupe(userIDs)
+	if len(userIDs) == 0 {
+		level.Warn(c.logger).Log("message", "cc nobody", "id", req.TaskID, "dealUsers", s)
+		return &node.Result{Status: v1alpha1.Finish}, nil
+	}
+
+	now := time.NowUnix()
+	datas := make([]*model.CC, 0, len(userIDs))
+	for _, userID := range userIDs {
+		datas = append(datas, &model.CC{
+			ID:          id.BaseUUID(),
+			TaskID:      req.TaskID,
+			FlowID:      req.FlowID,
+			AppID:       req.AppID,
+			FormTableID: req.FormID,
+			FormDataID:  req.FormDataID,
+			NodeDefKey:  req.NodeDefKey,
+			UserID:      userID,
+			CreatedBy:   req.CreatedBy,
+			CreatedAt:   now,
+		})
+	}
+	err = c.taskRepo.Transaction(ctx, func(ctx context.Context) error {
+		return c.ccRepo.Create(ctx, datas...)
+	})
+	if err != nil {
+		return &node.Result{Status: v1alpha1.Kill}, err
+	}
+	level.Info(c.logger).Log("message", "cc do task", "id", req.TaskID, "userIDs", userIDs)
+	c.notifyCC(datas...)
+	return &node.Result{
+		Status: v1alpha1.Finish,
+	}, nil
+}
+
+// Status of ListCCRequest.
+const (
+	CCRead   = "read"
+	CCUnread = "unread"
+)
+
+type ListCCRequest struct {
+	UserID string
+	AppID  string
+	// Status is CCRead or CCUnread, every cc when empty.
+	Status string
+	Page   int
+	Limit  int
+}
+
+type ListCCResponse struct {
+	Total int64      `json:"total"`
+	CCs   []model.CC `json:"ccs"`
+}
+
+// ListCC returns a page of the ccs of req.UserID, the latest first.
+func (t *task) ListCC(ctx context.Context, req *ListCCRequest) (*ListCCResponse, error) {
+	if req.UserID == "" {
+		return nil, e.NewErrorWithString(e.ErrParams, "userID is required")
+	}
+	filter := &model.CCFilter{
+		UserID: req.UserID,
+		AppID:  req.AppID,
+	}
+	switch req.Status {
+	case "":
+	case CCRead, CCUnread:
+		read := req.Status == CCRead
+		filter.Read = &read
+	default:
+		return nil, e.NewErrorWithString(e.ErrParams, "status must be read or unread")
+	}
+	page, limit := req.Page, req.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	list, total, err := t.ccRepo.List(ctx, filter, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &ListCCResponse{
+		Total: int64(total),
+		CCs:   list,
+	}, nil
+}
+
+type ReadCCRequest struct {
+	UserID string
+	// IDs are the ccs to mark as read, all the ccs of UserID when empty.
+	IDs []string
+}
+
+type ReadCCResponse struct {
+	Read int64 `json:"read"`
+}
+
+// ReadCC marks the ccs req.IDs of req.UserID as read, the ccs of other users are left as they are.
+func (t *task) ReadCC(ctx context.Context, req *ReadCCRequest) (*ReadCCResponse, error) {
+	if req.UserID == "" {
+		return nil, e.NewErrorWithString(e.ErrParams, "userID is required")
+	}
+	n, err := t.ccRepo.Read(ctx, req.UserID, req.IDs, time.NowUnix())
+	if err != nil {
+		return nil, err
+	}
+	return &ReadCCResponse{
+		Read: n,
+	}, nil
+}
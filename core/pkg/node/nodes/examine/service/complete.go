@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+
+	"github.com/go-kit/log/level"
+	"github.com/quanxiang-cloud/cabin/time"
+
+	"git.yunify.com/quanxiang/workflow/pkg/node"
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+)
+
+// Describe is not implemented by the examine node, it shadows the one of the embedded endpoints.
+func (t *task) Describe(ctx context.Context) (*node.Description, error) {
+	return nil, node.ErrUnimplemented
+}
+
+// Complete archives the open tasks of the node run of in, killed or rewound by the core,
+// so that nobody is asked to handle them any more.
+func (t *task) Complete(ctx context.Context, in *node.CompleteRequest) error {
+	runID := in.Metadata.Annotations[TaskID]
+	nodeID := in.Metadata.Annotations[NodeID]
+	level.Info(t.logger).Log("message", "examine complete node", "id", runID, "nodeDefKey", nodeID, "status", in.Status)
+	return t.taskRepo.UpdateByTaskIDAndNodeDefKey(ctx, &model.Task{
+		TaskID:     runID,
+		NodeDefKey: nodeID,
+		NodeResult: model.NodeResultArchived,
+		UpdatedAt:  time.NowUnix(),
+	})
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/log"
+
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
+	"git.yunify.com/quanxiang/workflow/pkg/node"
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+)
+
+func TestComplete(t *testing.T) {
+	tasks := &memoryTasks{
+		tasks: []model.Task{
+			{ID: "e1", TaskID: "1", NodeDefKey: "n1", UserID: "u1", Result: ResultAgree, NodeResult: string(v1alpha1.Finish)},
+			{ID: "e2", TaskID: "1", NodeDefKey: "n1", UserID: "u2", NodeResult: string(v1alpha1.Pending)},
+			{ID: "e3", TaskID: "1", NodeDefKey: "n1", UserID: "u3", NodeResult: model.NodeResultWaiting},
+			{ID: "e4", TaskID: "1", NodeDefKey: "n2", UserID: "u4", NodeResult: string(v1alpha1.Pending)},
+		},
+	}
+	var s node.Interface = &task{logger: log.NewNopLogger(), taskRepo: tasks}
+
+	c, ok := s.(node.Completer)
+	if !ok {
+		t.Fatal("expect the examine node to be a completer")
+	}
+	err := c.Complete(context.Background(), &node.CompleteRequest{
+		Metadata: v1alpha1.Metadata{Annotations: map[string]string{TaskID: "1", NodeID: "n1"}},
+		Status:   v1alpha1.Kill,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{string(v1alpha1.Finish), model.NodeResultArchived, model.NodeResultArchived, string(v1alpha1.Pending)}
+	for k := range want {
+		if tasks.tasks[k].NodeResult != want[k] {
+			t.Errorf("task %s: node result = %s, want %s", tasks.tasks[k].ID, tasks.tasks[k].NodeResult, want[k])
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+
+	e "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/cabin/id"
+	"github.com/quanxiang-cloud/cabin/time"
+
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+)
+
+type CreateDelegationRequest struct {
+	UserID   string
+	Delegate string
+	AppID    string
+	FlowID   string
+	StartAt  int64
+	EndAt    int64
+	Remark   string
+}
+type CreateDelegationResponse struct {
+	ID string
+}
+
+// CreateDelegation routes the approvals of UserID created between StartAt and EndAt
+// to Delegate, optionally only those of one app or flow.
+func (t *task) CreateDelegation(ctx context.Context, req *CreateDelegationRequest) (*CreateDelegationResponse, error) {
+	if req.UserID == "" || req.Delegate == "" || req.Delegate == req.UserID {
+		return nil, e.NewErrorWithString(e.ErrParams, "代理人不能为空或为自己")
+	}
+	if req.EndAt <= req.StartAt {
+		return nil, e.NewErrorWithString(e.ErrParams, "代理结束时间必须晚于开始时间")
+	}
+	data := &model.Delegation{
+		ID:        id.BaseUUID(),
+		UserID:    req.UserID,
+		Delegate:  req.Delegate,
+		AppID:     req.AppID,
+		FlowID:    req.FlowID,
+		StartAt:   req.StartAt,
+		EndAt:     req.EndAt,
+		Remark:    req.Remark,
+		CreatedAt: time.NowUnix(),
+	}
+	err := t.delegationRepo.Create(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return &CreateDelegationResponse{
+		ID: data.ID,
+	}, nil
+}
+
+type DeleteDelegationRequest struct {
+	ID     string
+	UserID string
+}
+type DeleteDelegationResponse struct {
+}
+
+func (t *task) DeleteDelegation(ctx context.Context, req *DeleteDelegationRequest) (*DeleteDelegationResponse, error) {
+	err := t.delegationRepo.Delete(ctx, req.ID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &DeleteDelegationResponse{}, nil
+}
+
+type ListDelegationRequest struct {
+	UserID string
+}
+type ListDelegationResponse struct {
+	Data []model.Delegation
+}
+
+func (t *task) ListDelegation(ctx context.Context, req *ListDelegationRequest) (*ListDelegationResponse, error) {
+	list, err := t.delegationRepo.ListByUserID(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &ListDelegationResponse{
+		Data: list,
+	}, nil
+}
+
+// assignee is an approver of a node, with the approver they replace when delegated.
+type assignee struct {
+	userID        string
+	delegatedFrom string
+}
+
+// delegate replaces the approvers who have a delegation in effect by their delegates,
+// each delegate keeping the approver it replaces. Delegations are not followed
+// further than one step, so two users delegating to each other do not loop.
+func (t *task) delegate(ctx context.Context, req *DoRequest, userIDs []string) ([]assignee, error) {
+	now := time.NowUnix()
+	assignees := make([]assignee, 0, len(userIDs))
+	for _, userID := range userIDs {
+		d, err := t.delegationRepo.GetActive(ctx, userID, req.AppID, req.FlowID, now)
+		if err != nil {
+			return nil, err
+		}
+		if d == nil {
+			assignees = append(assignees, assignee{userID: userID})
+			continue
+		}
+		assignees = append(assignees, assignee{userID: d.Delegate, delegatedFrom: userID})
+	}
+	return assignees, nil
+}
+
+// dedupeAssignees returns assignees without the users assigned already, in order.
+func dedupeAssignees(assignees []assignee) []assignee {
+	seen := make(map[string]struct{}, len(assignees))
+	list := make([]assignee, 0, len(assignees))
+	for _, a := range assignees {
+		if _, ok := seen[a.userID]; ok {
+			continue
+		}
+		seen[a.userID] = struct{}{}
+		list = append(list, a)
+	}
+	return list
+}
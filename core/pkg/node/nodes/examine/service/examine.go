@@ -8,9 +8,10 @@ import (
 	"github.com/pkg/errors"
 	"strconv"
 	"strings"
+	stdtime "time"
 
-	"git.yunify.com/quanxiang/workflow/apis"
 	"git.yunify.com/quanxiang/workflow/pkg/client/clientset/versioned"
+	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/go-kit/log/level"
 
 	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
@@ -19,6 +20,7 @@ import (
 	"git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db/mysql"
 	"git.yunify.com/quanxiang/workflow/pkg/thirdparty/quanxiang"
 	"github.com/go-kit/log"
+	e "github.com/quanxiang-cloud/cabin/error"
 	"github.com/quanxiang-cloud/cabin/id"
 	"github.com/quanxiang-cloud/cabin/time"
 )
@@ -30,42 +32,84 @@ type Task interface {
 	Reject(ctx context.Context, req *RejectRequest) (*RejectResponse, error)
 	Recall(ctx context.Context, req *RecallRequest) (*RecallResponse, error)
 	Transfer(ctx context.Context, req *TransferRequest) (*TransferResponse, error)
+	AddSigner(ctx context.Context, req *AddSignerRequest) (*AddSignerResponse, error)
+	SendBack(ctx context.Context, req *SendBackRequest) (*SendBackResponse, error)
+	Resubmit(ctx context.Context, req *ResubmitRequest) (*ResubmitResponse, error)
 	Urge(ctx context.Context, req *UrgeRequest) (*UrgeResponse, error)
+	Batch(ctx context.Context, req *BatchRequest) (*BatchResponse, error)
 	Get(ctx context.Context, req *GetRequest) (*GetResponse, error)
 	GetByUserIDAndTaskID(ctx context.Context, req *GetByUserIDAndTaskIDRequest) (*GetByUserIDAndTaskIDResponse, error)
 	GetByFlowID(ctx context.Context, req *GetByFlowIDRequest) (*GetByFlowIDResponse, error)
 	GetByUserID(ctx context.Context, req *GetByUserIDRequest) (*GetByUserIDResponse, error)
 	GetByCreated(ctx context.Context, req *GetByCreatedRequest) (*GetByCreatedResponse, error)
-	//List(ctx context.Context, req *ListRequest) (*ListResponse, error)
+
+	CreateDelegation(ctx context.Context, req *CreateDelegationRequest) (*CreateDelegationResponse, error)
+	DeleteDelegation(ctx context.Context, req *DeleteDelegationRequest) (*DeleteDelegationResponse, error)
+	ListDelegation(ctx context.Context, req *ListDelegationRequest) (*ListDelegationResponse, error)
+
+	Relay(ctx context.Context, interval stdtime.Duration)
+	Remind(ctx context.Context, interval stdtime.Duration)
+	List(ctx context.Context, req *ListRequest) (*ListResponse, error)
+	Stats(ctx context.Context, req *StatsRequest) (*StatsResponse, error)
+	ListCC(ctx context.Context, req *ListCCRequest) (*ListCCResponse, error)
+	ReadCC(ctx context.Context, req *ReadCCRequest) (*ReadCCResponse, error)
 }
 
 const (
 	ResultAgree  = "agree"
 	ResultReject = "reject"
 	ResultRecall = "recall"
+	// ResultTransfer marks a task handed over to its Substitute,
+	// the substitute gets a new task for the same node.
+	ResultTransfer = "transfer"
+	// ResultSendBack marks the task whose approver sent the run back to an earlier node.
+	ResultSendBack = "sendBack"
 )
 
 type task struct {
 	node.Endpoints
-	logger     log.Logger
-	taskRepo   model.TaskRepo
-	qx         quanxiang.QuanXiang
-	piplineRun versioned.Client
-	homeHost   string
+	logger         log.Logger
+	taskRepo       model.TaskRepo
+	delegationRepo model.DelegationRepo
+	outboxRepo     model.OutboxRepo
+	actionRepo     model.ActionRepo
+	ccRepo         model.CCRepo
+	qx             quanxiang.QuanXiang
+	piplineRun     versioned.Client
+	homeHost       string
+	admins         map[string]struct{}
+	// admin, the first of admins, is the approver of the nodes falling back to NoApproverAdmin.
+	admin      string
+	notifyConf Notify
 }
 
-func NewTask(db *sql.DB, instance []string, logger log.Logger, workFlowInstance, homeHost string) Task {
+// NewTask returns the examine service. admins may act on every task, notify configures
+// the messages to the approvers and initiators, clientOpts are applied to the requests to the workflow core.
+func NewTask(db *sql.DB, instance []string, logger log.Logger, workFlowInstance, homeHost string, admins []string, notify Notify, clientOpts ...httptransport.ClientOption) Task {
 	qx := quanxiang.New(instance, logger)
 
-	client := versioned.New(workFlowInstance, logger)
-
-	return &task{
-		qx:         qx,
-		taskRepo:   mysql.NewExamineNode(db),
-		piplineRun: client,
-		logger:     logger,
-		homeHost:   homeHost,
+	client := versioned.New(workFlowInstance, logger, clientOpts...)
+
+	t := &task{
+		qx:             qx,
+		taskRepo:       mysql.NewExamineNode(db),
+		delegationRepo: mysql.NewDelegation(db),
+		outboxRepo:     mysql.NewOutbox(db),
+		actionRepo:     mysql.NewAction(db),
+		ccRepo:         mysql.NewCC(db),
+		piplineRun:     client,
+		logger:         logger,
+		homeHost:       homeHost,
+		admins:         make(map[string]struct{}, len(admins)),
+		notifyConf:     notify.withDefaults(),
 	}
+	for _, admin := range admins {
+		t.admins[admin] = struct{}{}
+	}
+	if len(admins) != 0 {
+		t.admin = admins[0]
+	}
+	return t
 }
 
 type DoRequest struct {
@@ -79,6 +123,23 @@ type DoRequest struct {
 	CreatedBy    string
 	SysAuditBool string
 	NodeDefKey   string
+	Quorum       string
+	RemindEvery  int64
+	TimeLimit    int64 // milliseconds the approvers have to handle their task, 0 for no limit
+	// FieldPermission is the JSON field permission config of the node.
+	FieldPermission string
+	// DelegatedFrom holds the approver each of UserID replaces, empty when not delegated.
+	DelegatedFrom []string
+	// Dedupe asks a single task of the approvers resolved more than once.
+	Dedupe bool
+	// SkipInitiator leaves the initiator out of the approvers.
+	SkipInitiator bool
+	// AutoApprove holds the rules agreeing tasks as soon as they are assigned, AutoInitiator and AutoApproved.
+	AutoApprove []string
+	// NoApprover is what happens when no approver is resolved, NoApproverKill when empty.
+	NoApprover string
+	// Resubmit returns the run to the initiator once the node is rejected, ResubmitNode or ResubmitStart.
+	Resubmit string
 }
 type DoResponse struct {
 	NodeType string
@@ -90,7 +151,7 @@ const (
 	NodeID       = "database.pipelineRunNode/name"
 	FlowID       = "flowID"
 	TaskType     = "taskType"
-	DealUsers    = "dealUsers" // leader.xxxx,field.xxxx,person.xxxx,field.xxxx
+	DealUsers    = "dealUsers" // leader.n,field.xxxx,person.xxxx,role.xxxx,dep.xxxx,depLeader.xxxx,variable.xxxx, see resolver.go
 	AppID        = "appID"
 	FormTableID  = "tableID"
 	FormDataID   = "dataID"
@@ -98,11 +159,27 @@ const (
 	ActionAgree  = "agree"
 	ActionReject = "reject"
 	SysAuditBool = "SYS_AUDIT_BOOL"
+	Quorum       = "quorum"    // 3 or 60%, used by ExamineQuorum
+	Remind       = "remind"    // hours like 24 or a duration like 30m, reminds the approvers until they act
+	TimeLimit    = "timeLimit" // hours like 24 or a duration like 30m, the tasks are overdue after it
+	// FieldPermission is the JSON fieldPermission of the node, keyed by field,
+	// agree and reject refuse form data changing the fields it does not let the approvers edit.
+	FieldPermission = "fieldPermission"
+	Dedupe          = "dedupe"        // true gives a single task to the approvers resolved more than once
+	SkipInitiator   = "skipInitiator" // true leaves the initiator out of the approvers
+	AutoApprove     = "autoApprove"   // initiator,approved: the tasks agreed as soon as they are assigned
+	NoApprover      = "noApprover"    // kill, agree or admin: what happens when no approver is resolved
+	Resubmit        = "resubmit"      // node or start: the initiator resubmits the run once the node is rejected
 )
 
 const (
 	ExamineOr  = "or"
 	ExamineAll = "and"
+	// ExamineSequence asks the approvers one after another, in the order of dealUsers.
+	ExamineSequence = "sequence"
+	// ExamineQuorum finishes once the quorum of approvers agree,
+	// or once the quorum can no longer be reached.
+	ExamineQuorum = "quorum"
 )
 
 func (t *task) Do(ctx context.Context, in *node.Request) (*node.Result, error) {
@@ -110,8 +187,11 @@ func (t *task) Do(ctx context.Context, in *node.Request) (*node.Result, error) {
 	res.Status = v1alpha1.Pending
 	req := new(DoRequest)
 	var s = ""
+	var remind, timeLimit string
+	params := make(map[string]string, len(in.Params))
 
 	for k := range in.Params {
+		params[in.Params[k].Key] = in.Params[k].Value
 		if in.Params[k].Key == AppID {
 			req.AppID = in.Params[k].Value
 		}
@@ -136,6 +216,33 @@ func (t *task) Do(ctx context.Context, in *node.Request) (*node.Result, error) {
 		if in.Params[k].Key == SysAuditBool {
 			req.SysAuditBool = in.Params[k].Value
 		}
+		if in.Params[k].Key == Quorum {
+			req.Quorum = in.Params[k].Value
+		}
+		if in.Params[k].Key == Remind {
+			remind = in.Params[k].Value
+		}
+		if in.Params[k].Key == TimeLimit {
+			timeLimit = in.Params[k].Value
+		}
+		if in.Params[k].Key == FieldPermission {
+			req.FieldPermission = in.Params[k].Value
+		}
+		if in.Params[k].Key == Dedupe {
+			req.Dedupe = in.Params[k].Value == "true"
+		}
+		if in.Params[k].Key == SkipInitiator {
+			req.SkipInitiator = in.Params[k].Value == "true"
+		}
+		if in.Params[k].Key == AutoApprove && in.Params[k].Value != "" {
+			req.AutoApprove = strings.Split(in.Params[k].Value, ",")
+		}
+		if in.Params[k].Key == NoApprover {
+			req.NoApprover = in.Params[k].Value
+		}
+		if in.Params[k].Key == Resubmit {
+			req.Resubmit = in.Params[k].Value
+		}
 	}
 
 	for k := range in.Params {
@@ -153,21 +260,61 @@ func (t *task) Do(ctx context.Context, in *node.Request) (*node.Result, error) {
 	req.TaskID = runID
 	req.NodeDefKey = nodeID
 	level.Info(t.logger).Log("message", "examine do task", "id", req.TaskID, "nodeDefKey", req.NodeDefKey)
-	userIDs, createUserID, err := t.resolutionDealObjects(ctx, s, req)
+	if s == "" {
+		return node.Permanent(node.ErrCodeInvalidParams, "have no user to deal", nil), nil
+	}
+	assignees, createUserID, err := t.resolutionDealObjects(ctx, s, req, params)
+	if errors.Is(err, errNoFormData) {
+		level.Error(t.logger).Log("message", err, "formDataID", req.FormDataID)
+		return node.Permanent(node.ErrCodeNotFound, err.Error(), map[string]string{
+			"formDataID": req.FormDataID,
+		}), nil
+	}
 	if err != nil {
 		level.Error(t.logger).Log("message", err, "userids", req.UserID)
 		res.Status = v1alpha1.Kill
 		return res, err
 	}
-	if len(userIDs) == 0 {
+	if len(assignees) == 0 && req.NoApprover == NoApproverAdmin && t.admin != "" {
+		level.Info(t.logger).Log("message", "examine no approver, fall back to admin", "id", req.TaskID, "admin", t.admin)
+		assignees = []assignee{{userID: t.admin}}
+	}
+	if len(assignees) == 0 && req.NoApprover != NoApproverAgree {
 		level.Error(t.logger).Log("message", err, "can not find user ", s)
-		res.Status = v1alpha1.Kill
-		res.Message = "审核节点解析人员为空" + "，解析字段为" + s
-		return res, nil
+		return node.Permanent(node.ErrCodeNoAssignee, "审核节点解析人员为空"+"，解析字段为"+s, map[string]string{
+			"dealUsers": s,
+		}), nil
+	}
+	if req.TaskType == ExamineQuorum {
+		if _, err := quorumOf(req.Quorum, len(assignees)); err != nil {
+			return node.Permanent(node.ErrCodeInvalidParams, err.Error(), map[string]string{
+				"quorum": req.Quorum,
+			}), nil
+		}
+	}
+	req.RemindEvery, err = remindOf(remind)
+	if err != nil {
+		return node.Permanent(node.ErrCodeInvalidParams, err.Error(), map[string]string{
+			"remind": remind,
+		}), nil
+	}
+	req.TimeLimit, err = millisOf(TimeLimit, timeLimit)
+	if err != nil {
+		return node.Permanent(node.ErrCodeInvalidParams, err.Error(), map[string]string{
+			"timeLimit": timeLimit,
+		}), nil
+	}
+	if req.Resubmit != "" && req.Resubmit != ResubmitNode && req.Resubmit != ResubmitStart {
+		return node.Permanent(node.ErrCodeInvalidParams, "invalid resubmit "+req.Resubmit, map[string]string{
+			"resubmit": req.Resubmit,
+		}), nil
 	}
 	req.CreatedBy = createUserID
-	level.Info(t.logger).Log("message", "examine do task", "dealUserIDs", userIDs)
-	req.UserID = append(req.UserID, userIDs...)
+	for _, a := range assignees {
+		req.UserID = append(req.UserID, a.userID)
+		req.DelegatedFrom = append(req.DelegatedFrom, a.delegatedFrom)
+	}
+	level.Info(t.logger).Log("message", "examine do task", "dealUserIDs", req.UserID)
 	response, err := t.do(ctx, req)
 	if err != nil {
 		res.Status = v1alpha1.Kill
@@ -201,7 +348,25 @@ func (t *task) Do(ctx context.Context, in *node.Request) (*node.Result, error) {
 	return res, nil
 }
 
-func (t *task) resolutionDealObjects(ctx context.Context, s string, req *DoRequest) ([]string, string, error) {
+var errNoFormData = errors.New("no form data")
+
+func (t *task) resolutionDealObjects(ctx context.Context, s string, req *DoRequest, params map[string]string) ([]assignee, string, error) {
+	ids, createUserID, err := t.recipients(ctx, s, req, params)
+	if err != nil {
+		return nil, "", err
+	}
+	assignees, err := t.delegate(ctx, req, ids)
+	if err != nil {
+		return nil, "", err
+	}
+	if req.Dedupe {
+		assignees = dedupeAssignees(assignees)
+	}
+	return assignees, createUserID, nil
+}
+
+// recipients returns the users dealUsers s resolves to from the form data of req, and its creator.
+func (t *task) recipients(ctx context.Context, s string, req *DoRequest, params map[string]string) ([]string, string, error) {
 	if s == "" {
 		return nil, "", errors.New("have no user to deal")
 	}
@@ -216,7 +381,7 @@ func (t *task) resolutionDealObjects(ctx context.Context, s string, req *DoReque
 		return nil, "", errors.Wrap(err, "get form data err")
 	}
 	if formData == nil || formData.Entity == nil {
-		return nil, "", errors.New("no form data")
+		return nil, "", errNoFormData
 	}
 	var createUserID = ""
 	if creatorID, ok := formData.Entity["creator_id"]; creatorID != nil && ok {
@@ -225,79 +390,25 @@ func (t *task) resolutionDealObjects(ctx context.Context, s string, req *DoReque
 		}
 
 	}
-	//todo 这里需要对人员id进行判断，是上级，还是表单字段
-	users := strings.Split(s, ",")
-	ids := make([]string, 0)
-	for k := range users {
-		split := strings.Split(users[k], ".")
-		if len(split) >= 1 {
-			switch split[0] {
-			case "leader":
-				//todo 朝org要用户信息，拿到leader的id
-
-				if creatorID, ok := formData.Entity["creator_id"]; creatorID != nil && ok {
-					if v, ok1 := creatorID.(string); ok1 {
-						userInfo, err := t.qx.GetUserInfo(ctx, &quanxiang.GetUsersInfoRequest{
-							ID: v,
-						})
-						if err != nil {
-							return nil, "", err
-						}
-						if userInfo != nil && userInfo.Data.ID != "" {
-							if len(userInfo.Data.Leader) > 0 && len(userInfo.Data.Leader[0]) > 0 {
-								ids = append(ids, userInfo.Data.Leader[0][0].ID)
-							} else {
-								ids = append(ids, req.CreatedBy)
-							}
-						}
-					} else {
-						ids = append(ids, req.CreatedBy)
-					}
-
-				} else {
-					ids = append(ids, req.CreatedBy)
-				}
-
-			case "field":
-				//todo 朝org要用户信息，拿到用户的id
-
-				if len(split) != 2 {
-					ids = append(ids, req.CreatedBy)
-				} else {
-					if v, ok := formData.Entity[split[1]].([]interface{}); v != nil && ok {
-						for kf := range v {
-							if userFeildMap, ok1 := v[kf].(map[string]interface{}); userFeildMap != nil && ok1 {
-								if userID, ok1 := userFeildMap["value"].(string); userID != "" && ok1 {
-									ids = append(ids, userID)
-								}
-							}
-						}
-
-					}
-				}
-			case "person":
-				ids = append(ids, split[1])
-			case "formApplyUserID":
-				if v, ok := formData.Entity["creator_id"].(string); v != "" && ok {
-					userInfo, err := t.qx.GetUserInfo(ctx, &quanxiang.GetUsersInfoRequest{
-						ID: v,
-					})
-					if err != nil {
-						return nil, "", err
-					}
-					if userInfo != nil {
-						ids = append(ids, userInfo.Data.ID)
-					} else {
-						ids = append(ids, req.CreatedBy)
-					}
-
-				} else {
-					ids = append(ids, req.CreatedBy)
-				}
-
-			}
-
+	ids, unknown, err := resolve(ctx, &Resolution{
+		QX:        t.qx,
+		Entity:    formData.Entity,
+		Initiator: createUserID,
+		CreatedBy: req.CreatedBy,
+		Params:    params,
+	}, s)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(unknown) != 0 {
+		level.Warn(t.logger).Log("message", "examine unknown deal users", "dealUsers", strings.Join(unknown, ","))
+	}
+	if req.SkipInitiator {
+		initiator := createUserID
+		if initiator == "" {
+			initiator = req.CreatedBy
 		}
+		ids = without(ids, initiator)
 	}
 	return ids, createUserID, nil
 }
@@ -309,60 +420,74 @@ func (t *task) do(ctx context.Context, req *DoRequest) (*DoResponse, error) {
 	if err != nil {
 		return res, err
 	}
+	tasks = current(tasks)
+	for k := range tasks {
+		if resubmitting(&tasks[k]) && tasks[k].NodeResult == string(v1alpha1.Pending) {
+			// paused until the initiator resubmits the run
+			res.NodeType = string(v1alpha1.Pending)
+			return res, nil
+		}
+	}
 
 	if len(tasks) == 0 {
-		datas := make([]*model.Task, 0, len(req.UserID))
-		for k := range req.UserID {
-			datas = append(datas, &model.Task{
-				ID:          id.BaseUUID(),
-				UserID:      req.UserID[k],
-				TaskID:      req.TaskID,
-				FlowID:      req.FlowID,
-				AppID:       req.AppID,
-				FormTableID: req.FormID,
-				FormDataID:  req.FormDataID,
-				NodeResult:  string(v1alpha1.Pending),
-				CreatedAt:   time.NowUnix(),
-				ExamineType: req.TaskType,
-				CreatedBy:   req.CreatedBy,
-				NodeDefKey:  req.NodeDefKey,
-			})
+		userIDs := req.UserID
+		if len(userIDs) == 0 {
+			// nobody to ask, the node falls back to NoApproverAgree
+			res.NodeType = string(v1alpha1.Finish)
+			res.Result = strconv.FormatBool(true)
+			return res, nil
 		}
-		res.NodeType = string(v1alpha1.Pending)
-		err := t.taskRepo.InsertBranch(ctx, datas...)
+		if req.TaskType == ExamineSequence {
+			// approvers of a sequence get their task one at a time
+			userIDs = userIDs[:1]
+		}
+		datas := make([]*model.Task, 0, len(userIDs))
+		for k := range userIDs {
+			datas = append(datas, newTask(req, userIDs[k], req.delegatedFrom(k)))
+		}
+		err := t.assign(ctx, req, datas...)
 		if err != nil {
 			return res, err
 		}
-		////todo 发送邮件提醒待审核的人
-		//for k := range datas {
-		//	t.sendEmail(ctx, req.UserID, req.TaskID, datas[k].ID)
-		//}
-
-		return res, nil
+		for k := range datas {
+			tasks = append(tasks, *datas[k])
+		}
 	}
-	var flag = true
 
-	for k := range tasks {
-		if tasks[k].NodeResult == string(v1alpha1.Pending) {
-			flag = false
+	finish, agree := evaluate(tasks)
+	for finish && agree && tasks[0].ExamineType == ExamineSequence {
+		next := nextApprover(tasks, req)
+		if next < 0 {
 			break
 		}
+		data := newTask(req, req.UserID[next], req.delegatedFrom(next))
+		err := t.assign(ctx, req, data)
+		if err != nil {
+			return res, err
+		}
+		// the next approver may have been agreed by the auto-approve rules too
+		tasks = append(tasks, *data)
+		finish, agree = evaluate(tasks)
 	}
-	var result = "true"
-	if flag {
-
-		//FIXME 考虑后期事物介入问题，在修改NodeResult的时候要注意
-		for k := range tasks {
-			if tasks[k].Result == ActionReject || tasks[k].Result == ResultRecall {
-				result = "false"
-				break
-			}
+	if finish && tasks[0].ExamineType == ExamineQuorum {
+		err := t.closeQuorum(ctx, &tasks[0])
+		if err != nil {
+			return res, err
 		}
 	}
 
-	if flag {
+	if finish && !agree && req.Resubmit != "" && req.CreatedBy != "" && !recalled(tasks) {
+		err := t.returnToInitiator(ctx, req)
+		if err != nil {
+			return res, err
+		}
+		res.NodeType = string(v1alpha1.Pending)
+		return res, nil
+	}
+	if finish {
 		res.NodeType = string(v1alpha1.Finish)
-		res.Result = result
+		res.Result = strconv.FormatBool(agree)
+		t.notifyResult(&tasks[0], agree)
 	} else {
 		res.NodeType = string(v1alpha1.Pending)
 	}
@@ -370,6 +495,207 @@ func (t *task) do(ctx context.Context, req *DoRequest) (*DoResponse, error) {
 
 }
 
+// delegatedFrom returns the approver the k-th of UserID replaces, empty when not delegated.
+func (req *DoRequest) delegatedFrom(k int) string {
+	if k < len(req.DelegatedFrom) {
+		return req.DelegatedFrom[k]
+	}
+	return ""
+}
+
+func newTask(req *DoRequest, userID, delegatedFrom string) *model.Task {
+	now := time.NowUnix()
+	var dueAt int64
+	if req.TimeLimit != 0 {
+		dueAt = now + req.TimeLimit
+	}
+	return &model.Task{
+		ID:          id.BaseUUID(),
+		UserID:      userID,
+		TaskID:      req.TaskID,
+		FlowID:      req.FlowID,
+		AppID:       req.AppID,
+		FormTableID: req.FormID,
+		FormDataID:  req.FormDataID,
+		NodeResult:  string(v1alpha1.Pending),
+		CreatedAt:   now,
+		ExamineType: req.TaskType,
+		CreatedBy:   req.CreatedBy,
+		NodeDefKey:  req.NodeDefKey,
+		Quorum:      req.Quorum,
+		RemindEvery: req.RemindEvery,
+
+		FieldPermission: req.FieldPermission,
+		DelegatedFrom:   delegatedFrom,
+		DueAt:           dueAt,
+	}
+}
+
+// evaluate reports whether the tasks of a node decide it, and if so, whether it is agreed.
+// Signers added by AddSigner have to agree whatever the examine type is.
+func evaluate(tasks []model.Task) (finish, agree bool) {
+	tasks = handling(tasks)
+
+	approvers := make([]model.Task, 0, len(tasks))
+	var signing bool
+	for k := range tasks {
+		if tasks[k].SignerOf == "" {
+			approvers = append(approvers, tasks[k])
+			continue
+		}
+		switch {
+		case rejected(tasks[k]):
+			return true, false
+		case tasks[k].NodeResult != string(v1alpha1.Finish):
+			signing = true
+		}
+	}
+
+	if len(approvers) > 0 && approvers[0].ExamineType == ExamineQuorum {
+		finish, agree = evaluateQuorum(approvers)
+	} else {
+		finish, agree = evaluateAll(approvers)
+	}
+	if finish && agree && signing {
+		return false, false
+	}
+	return finish, agree
+}
+
+// evaluateAll reports whether every task is finished,
+// and if so, whether none of them was rejected or recalled.
+func evaluateAll(tasks []model.Task) (finish, agree bool) {
+	for k := range tasks {
+		if tasks[k].NodeResult != string(v1alpha1.Finish) {
+			return false, false
+		}
+	}
+
+	for k := range tasks {
+		if rejected(tasks[k]) {
+			return true, false
+		}
+	}
+	return true, true
+}
+
+func rejected(task model.Task) bool {
+	return task.Result == ActionReject || task.Result == ResultRecall
+}
+
+// recalled reports whether the initiator recalled the run of tasks.
+func recalled(tasks []model.Task) bool {
+	for k := range tasks {
+		if tasks[k].Result == ResultRecall {
+			return true
+		}
+	}
+	return false
+}
+
+// current drops the tasks of rounds that were sent back.
+func current(tasks []model.Task) []model.Task {
+	list := make([]model.Task, 0, len(tasks))
+	for k := range tasks {
+		if tasks[k].NodeResult != model.NodeResultArchived {
+			list = append(list, tasks[k])
+		}
+	}
+	return list
+}
+
+// handling drops the tasks that no longer count for their node: the ones of rounds
+// that were sent back, the ones handed over by Transfer, their substitutes decide instead,
+// and the ones of the initiator to resubmit the run.
+func handling(tasks []model.Task) []model.Task {
+	tasks = current(tasks)
+	list := make([]model.Task, 0, len(tasks))
+	for k := range tasks {
+		if tasks[k].Result != ResultTransfer && !resubmitting(&tasks[k]) {
+			list = append(list, tasks[k])
+		}
+	}
+	return list
+}
+
+// evaluateQuorum finishes as agreed once the quorum agree,
+// and as rejected once too few approvers are left to reach it.
+func evaluateQuorum(tasks []model.Task) (finish, agree bool) {
+	required, err := quorumOf(tasks[0].Quorum, len(tasks))
+	if err != nil {
+		// checked in Do, only reached by rows written by hand
+		required = len(tasks)
+	}
+
+	var agrees, rejects int
+	for k := range tasks {
+		switch tasks[k].Result {
+		case ResultAgree:
+			agrees++
+		case ActionReject, ResultRecall:
+			rejects++
+		}
+	}
+	switch {
+	case agrees >= required:
+		return true, true
+	case len(tasks)-rejects < required:
+		return true, false
+	}
+	return false, false
+}
+
+// quorumOf returns how many of total approvers have to agree for q,
+// which is either a count like "3" or a percentage like "60%".
+func quorumOf(q string, total int) (int, error) {
+	var required int
+	if strings.HasSuffix(q, "%") {
+		percent, err := strconv.Atoi(strings.TrimSuffix(q, "%"))
+		if err != nil || percent <= 0 || percent > 100 {
+			return 0, fmt.Errorf("invalid quorum %q", q)
+		}
+		required = (percent*total + 99) / 100
+	} else {
+		n, err := strconv.Atoi(q)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid quorum %q", q)
+		}
+		required = n
+	}
+
+	if required > total {
+		required = total
+	}
+	if required < 1 {
+		required = 1
+	}
+	return required, nil
+}
+
+// nextApprover returns the index in req.UserID of the first approver of a sequence who has
+// no task yet, -1 when all of them have one. A delegated task stands for the approver it
+// replaces, so two approvers delegating to the same user get a task each.
+func nextApprover(tasks []model.Task, req *DoRequest) int {
+	assigned := make(map[string]struct{}, len(tasks))
+	for k := range tasks {
+		approver := tasks[k].UserID
+		if tasks[k].DelegatedFrom != "" {
+			approver = tasks[k].DelegatedFrom
+		}
+		assigned[approver] = struct{}{}
+	}
+	for k, userID := range req.UserID {
+		approver := userID
+		if from := req.delegatedFrom(k); from != "" {
+			approver = from
+		}
+		if _, ok := assigned[approver]; !ok {
+			return k
+		}
+	}
+	return -1
+}
+
 type ExamineRequest struct {
 	TaskID  string
 	UserID  string
@@ -398,11 +724,13 @@ func (t *task) Examine(ctx context.Context, req *ExamineRequest) (*ExamineRespon
 }
 
 type AgreeRequest struct {
-	ExamineID string
-	TaskID    string
-	UserID    string
-	Remark    string
-	ForMData  *FormData
+	ExamineID   string
+	TaskID      string
+	UserID      string
+	Remark      string
+	Attachments []model.Attachment
+	Signature   string //手写签名文件地址
+	ForMData    *FormData
 }
 type FormData struct {
 	Entity map[string]interface{}       `json:"entity"`
@@ -413,99 +741,155 @@ type AgreeResponse struct {
 }
 
 func (t *task) Agree(ctx context.Context, req *AgreeRequest) (*AgreeResponse, error) {
-	err := t.examineTask(ctx, req.ExamineID, ResultAgree, req.Remark)
-	if err != nil {
-		return &AgreeResponse{}, err
-	}
-	runID, err := strconv.ParseInt(req.TaskID, 10, 64)
-	if err != nil {
-		return &AgreeResponse{}, err
-	}
-	//todo 这里可能需要进行表单数据的更新
-	if req.ForMData != nil {
-		data, _ := t.taskRepo.GetByID(ctx, req.ExamineID)
-		_, err = t.qx.UpdateFormData(ctx, &quanxiang.UpdateFormDataRequest{
-			AppID:  data.AppID,
-			FormID: data.FormTableID,
-			Data: quanxiang.UpdateEntity{
-				Query: map[string]interface{}{
-					"term": map[string]interface{}{
-						"_id": data.FormDataID,
-					},
-				},
-				Entity: req.ForMData.Entity,
-				Ref:    req.ForMData.Ref,
-			},
-		})
-		if err != nil {
-			level.Error(t.logger).Log("message", "examine update form data  ", "err", err.Error())
-			return &AgreeResponse{}, err
-		}
-		level.Error(t.logger).Log("message", "examine update form data  ok")
-	}
-
-	err = t.piplineRun.ExecPipelineRun(ctx, &apis.ExecPipelineRun{
-		ID: runID,
-	})
+	err := t.decide(ctx, req.ExamineID, &model.Action{
+		Action:      ResultAgree,
+		UserID:      req.UserID,
+		Remark:      req.Remark,
+		Attachments: req.Attachments,
+		Signature:   req.Signature,
+	}, req.ForMData)
 	if err != nil {
-		level.Error(t.logger).Log("message", "examine exec pipline run ", "err", err.Error())
 		return &AgreeResponse{}, err
 	}
-	level.Info(t.logger).Log("message", "examine exec pipline run ok", "run id", runID)
-
 	return &AgreeResponse{}, nil
 }
 
 type RejectRequest struct {
-	ExamineID string
-	TaskID    string
-	UserID    string
-	Remark    string
-	ForMData  *FormData
+	ExamineID   string
+	TaskID      string
+	UserID      string
+	Remark      string
+	Attachments []model.Attachment
+	Signature   string //手写签名文件地址
+	ForMData    *FormData
 }
 type RejectResponse struct {
 }
 
 func (t *task) Reject(ctx context.Context, req *RejectRequest) (*RejectResponse, error) {
-	err := t.examineTask(ctx, req.ExamineID, ResultReject, req.Remark)
+	form := req.ForMData
+	if form != nil && form.Entity == nil {
+		form = nil
+	}
+	err := t.decide(ctx, req.ExamineID, &model.Action{
+		Action:      ResultReject,
+		UserID:      req.UserID,
+		Remark:      req.Remark,
+		Attachments: req.Attachments,
+		Signature:   req.Signature,
+	}, form)
 	if err != nil {
 		return nil, err
 	}
-	//todo 这里可能需要进行表单数据的更新
-	if req.ForMData != nil && req.ForMData.Entity != nil {
-		data, _ := t.taskRepo.GetByID(ctx, req.ExamineID)
-		_, err = t.qx.UpdateFormData(ctx, &quanxiang.UpdateFormDataRequest{
-			AppID:  data.AppID,
-			FormID: data.FormTableID,
-			Data: quanxiang.UpdateEntity{
-				Query: map[string]interface{}{
-					"term": map[string]interface{}{
-						"_id": data.FormDataID,
-					},
-				},
-				Entity: req.ForMData.Entity,
-				Ref:    req.ForMData.Ref,
-			},
-		})
+	return &RejectResponse{}, nil
+}
+
+// decide records act as the result of the task examineID, with the form data update and the resume of
+// the run in the outbox in one transaction, then updates the form data and resumes the run.
+// A failed update or resume is retried from the outbox by Relay.
+func (t *task) decide(ctx context.Context, examineID string, act *model.Action, form *FormData) error {
+	task, msg, err := t.commitDecision(ctx, examineID, act, form)
+	if err != nil {
+		return err
+	}
+
+	_ = t.deliver(ctx, msg)
+	if act.Action == ResultAgree {
+		t.notifySigners(ctx, task)
+	}
+	return nil
+}
+
+// commitDecision is the transaction of decide, it returns the decided task and the resume of its run
+// left to deliver.
+func (t *task) commitDecision(ctx context.Context, examineID string, act *model.Action, form *FormData) (*model.Task, *model.Outbox, error) {
+	var update string
+	if form != nil {
+		// checked before the transaction, the fields the node of the task may write do not change
+		current, err := t.taskRepo.GetByID(ctx, examineID)
 		if err != nil {
-			level.Error(t.logger).Log("message", "examine update form data  ", "err", err.Error())
-			return nil, err
+			return nil, nil, err
+		}
+		if current != nil {
+			form, err = t.writable(ctx, current, form)
+			if err != nil {
+				return nil, nil, err
+			}
+			update, err = formUpdateOf(current, form)
+			if err != nil {
+				return nil, nil, err
+			}
 		}
-		level.Error(t.logger).Log("message", "examine update form data  ok")
 	}
-	runID, err := strconv.ParseInt(req.TaskID, 10, 64)
+
+	var (
+		msg  *model.Outbox
+		task *model.Task
+	)
+	err := t.taskRepo.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		task, err = t.examineTask(ctx, act.UserID, examineID, act.Action, act.Remark)
+		if err != nil {
+			return err
+		}
+		record := newAction(task, act.Action, act.UserID)
+		record.Remark, record.Attachments, record.Signature = act.Remark, act.Attachments, act.Signature
+		err = t.actionRepo.Create(ctx, record)
+		if err != nil {
+			return err
+		}
+		runID, err := strconv.ParseInt(task.TaskID, 10, 64)
+		if err != nil {
+			return err
+		}
+		// the form data is updated by deliver once committed, before the run is resumed
+		msg, err = t.enqueue(ctx, &model.Outbox{RunID: runID, Action: model.OutboxExec, Form: update})
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	err = t.piplineRun.ExecPipelineRun(ctx, &apis.ExecPipelineRun{
-		ID: runID,
+	return task, msg, nil
+}
+
+// formUpdateOf returns the update of the form data of task to form, as kept by the outbox.
+func formUpdateOf(task *model.Task, form *FormData) (string, error) {
+	b, err := json.Marshal(&quanxiang.UpdateFormDataRequest{
+		AppID:  task.AppID,
+		FormID: task.FormTableID,
+		Data: quanxiang.UpdateEntity{
+			Query: map[string]interface{}{
+				"term": map[string]interface{}{
+					"_id": task.FormDataID,
+				},
+			},
+			Entity: form.Entity,
+			Ref:    form.Ref,
+		},
 	})
 	if err != nil {
-		level.Error(t.logger).Log("message", "examine exec pipline run ", "err", err.Error())
-		return nil, err
+		return "", errors.Wrap(err, "fail marshal form data")
 	}
-	level.Info(t.logger).Log("message", "examine exec pipline run ok", "run id", runID)
-	return &RejectResponse{}, nil
+	return string(b), nil
+}
+
+// updateFormData applies update, returned by formUpdateOf. Applying it again sets the same values.
+func (t *task) updateFormData(ctx context.Context, update string) error {
+	req := &quanxiang.UpdateFormDataRequest{}
+	dec := json.NewDecoder(strings.NewReader(update))
+	// the numbers of the form data are sent back as they were given
+	dec.UseNumber()
+	if err := dec.Decode(req); err != nil {
+		return errors.Wrap(err, "fail unmarshal form data")
+	}
+
+	_, err := t.qx.UpdateFormData(ctx, req)
+	if err != nil {
+		level.Error(t.logger).Log("message", "examine update form data  ", "err", err.Error())
+		return err
+	}
+	level.Info(t.logger).Log("message", "examine update form data  ok")
+	return nil
 }
 
 type RecallRequest struct {
@@ -516,24 +900,61 @@ type RecallRequest struct {
 type RecallResponse struct {
 }
 
+// Recall ends the node the run TaskID is pending on as rejected by its initiator, the result of its
+// pending tasks being recall so that the node does not return the run to the initiator, then resumes
+// the run like decide does.
 func (t *task) Recall(ctx context.Context, req *RecallRequest) (*RecallResponse, error) {
 	tasks, err := t.taskRepo.ListByTaskID(ctx, req.TaskID)
 	if err != nil {
 		return nil, err
 	}
-	if len(tasks) > 0 {
-		aboutTask := &model.Task{
+	if len(tasks) == 0 {
+		return &RecallResponse{}, nil
+	}
+	if !t.canManage(&tasks[0], req.UserID) {
+		return nil, forbidden("只有发起人可以撤回")
+	}
+
+	pending := make([]model.Task, 0, len(tasks))
+	for _, task := range current(tasks) {
+		if task.NodeResult == string(v1alpha1.Pending) {
+			pending = append(pending, task)
+		}
+	}
+	if len(pending) == 0 {
+		return &RecallResponse{}, nil
+	}
+
+	runID, err := strconv.ParseInt(req.TaskID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	var msg *model.Outbox
+	err = t.taskRepo.Transaction(ctx, func(ctx context.Context) error {
+		for k := range pending {
+			err := t.actionRepo.Create(ctx, newAction(&pending[k], ResultRecall, req.UserID))
+			if err != nil {
+				return err
+			}
+		}
+		err := t.taskRepo.RecallByTaskIDAndNodeDefKey(ctx, &model.Task{
 			TaskID:     req.TaskID,
+			NodeDefKey: pending[0].NodeDefKey,
 			Result:     ResultRecall,
 			NodeResult: string(v1alpha1.Finish),
-		}
-
-		err = t.taskRepo.UpdateByTaskID(ctx, aboutTask)
+			UpdatedAt:  time.NowUnix(),
+		})
 		if err != nil {
-			return nil, err
+			return err
 		}
+		msg, err = t.enqueue(ctx, &model.Outbox{RunID: runID, Action: model.OutboxExec})
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	_ = t.deliver(ctx, msg)
 	return &RecallResponse{}, nil
 }
 
@@ -541,37 +962,97 @@ type TransferRequest struct {
 	UserID     string
 	TaskID     string
 	Substitute string
+	Remark     string
 }
 type TransferResponse struct {
 }
 
+// Transfer hands the pending task of UserID in the run TaskID over to Substitute.
+// The task of UserID is finished as transferred and the substitute gets a new one.
 func (t *task) Transfer(ctx context.Context, req *TransferRequest) (*TransferResponse, error) {
-	task, err := t.taskRepo.GetByUserIDAndTaskID(ctx, req.UserID, req.TaskID)
+	if req.Substitute == "" || req.Substitute == req.UserID {
+		return nil, e.NewErrorWithString(e.ErrParams, "转交人不能为空或为自己")
+	}
+	tasks, err := t.taskRepo.ListByTaskID(ctx, req.TaskID)
 	if err != nil {
 		return nil, err
 	}
+	var task *model.Task
+	for k := range tasks {
+		if tasks[k].UserID == req.UserID && tasks[k].NodeResult == string(v1alpha1.Pending) && !resubmitting(&tasks[k]) {
+			task = &tasks[k]
+			break
+		}
+	}
 	if task == nil {
-		return nil, errors.New("当前任务与审核人不匹配")
+		return nil, forbidden("当前任务与审核人不匹配")
 	}
-	task.UpdatedAt = time.NowUnix()
-	task.Substitute = req.Substitute
-	err = t.taskRepo.UpdateSubstitute(ctx, task)
+	for k := range tasks {
+		if tasks[k].NodeDefKey == task.NodeDefKey && tasks[k].UserID == req.Substitute &&
+			tasks[k].NodeResult == string(v1alpha1.Pending) {
+			return nil, e.NewErrorWithString(e.ErrParams, "转交人已是当前节点的审核人")
+		}
+	}
+
+	now := time.NowUnix()
+	substitute := *task
+	substitute.ID = id.BaseUUID()
+	substitute.UserID = req.Substitute
+	substitute.Substitute = ""
+	substitute.CreatedAt = now
+	substitute.UpdatedAt = 0
+	substitute.UrgeTimes = 0
+	substitute.Remark = ""
+	substitute.RemindedAt = 0
+	err = t.taskRepo.Transaction(ctx, func(ctx context.Context) error {
+		err := t.taskRepo.InsertBranch(ctx, &substitute)
+		if err != nil {
+			return err
+		}
+		err = t.taskRepo.UpdateSignerOf(ctx, task.ID, substitute.ID)
+		if err != nil {
+			return err
+		}
+
+		task.UpdatedAt = now
+		task.Substitute = req.Substitute
+		err = t.taskRepo.UpdateSubstitute(ctx, task)
+		if err != nil {
+			return err
+		}
+		task.Result = ResultTransfer
+		task.NodeResult = string(v1alpha1.Finish)
+		task.Remark = req.Remark
+		err = t.taskRepo.UpdateResult(ctx, task)
+		if err != nil {
+			return err
+		}
+
+		record := newAction(task, ResultTransfer, req.UserID)
+		record.Target, record.Remark = req.Substitute, req.Remark
+		return t.actionRepo.Create(ctx, record)
+	})
 	if err != nil {
 		return nil, err
 	}
+	t.notifyPending(&substitute)
 	return &TransferResponse{}, nil
 }
 
-func (t *task) examineTask(ctx context.Context, examineID, result, remark string) error {
+// examineTask records the result of the task examineID and closes its node if that decides it.
+func (t *task) examineTask(ctx context.Context, userID, examineID, result, remark string) (*model.Task, error) {
 	task, err := t.taskRepo.GetByID(ctx, examineID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if task == nil {
-		return errors.New("当前任务与审核人不匹配")
+	if task == nil || !t.canHandle(task, userID) {
+		return nil, forbidden("当前任务与审核人不匹配")
 	}
-	if task.NodeResult == string(v1alpha1.Finish) {
-		return errors.New("任务已经被执行完成，具体请看任务进度详情")
+	if task.NodeResult != string(v1alpha1.Pending) {
+		return nil, errors.New("任务已经被执行完成或尚未轮到处理，具体请看任务进度详情")
+	}
+	if resubmitting(task) {
+		return nil, errors.New("流程已退回发起人，请重新提交")
 	}
 	task.Result = result
 	task.Remark = remark
@@ -579,53 +1060,131 @@ func (t *task) examineTask(ctx context.Context, examineID, result, remark string
 	task.NodeResult = string(v1alpha1.Finish)
 	err = t.taskRepo.UpdateResult(ctx, task)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	tasks, err := t.taskRepo.ListByTaskIDAndNodeDefKey(ctx, task.TaskID, task.NodeDefKey)
+	if err != nil {
+		return nil, err
 	}
+	tasks = current(tasks)
+	// signers added after the task get their turn once it is agreed
+	waiting, err := t.releaseSigners(ctx, task, tasks)
+	if err != nil {
+		return nil, err
+	}
+	if task.SignerOf != "" {
+		return task, t.signed(ctx, task, tasks)
+	}
+
 	aboutTask := &model.Task{
-		TaskID: task.TaskID,
-		Result: result,
+		TaskID:     task.TaskID,
+		NodeDefKey: task.NodeDefKey,
+		Result:     result,
+		UpdatedAt:  time.NowUnix(),
 	}
 	switch task.ExamineType {
-	case ExamineAll:
+	case ExamineAll, ExamineSequence:
 		if result == ActionReject {
 			aboutTask.NodeResult = string(v1alpha1.Finish)
 		}
 	case ExamineOr:
+		if result == ResultAgree && waiting {
+			// the node is decided by the signers added after the task, the other approvers are done
+			return task, t.finish(ctx, tasks, func(other *model.Task) bool {
+				return other.SignerOf == "" && other.NodeResult == string(v1alpha1.Pending)
+			})
+		}
 		aboutTask.NodeResult = string(v1alpha1.Finish)
 	}
 	if aboutTask.NodeResult != "" {
-		err = t.taskRepo.UpdateByTaskID(ctx, aboutTask)
+		// the other tasks of the node only, the other nodes and the resubmission are not decided here
+		err = t.taskRepo.UpdateByTaskIDAndNodeDefKey(ctx, aboutTask)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
-	return nil
+	if task.ExamineType == ExamineQuorum {
+		return task, t.closeQuorum(ctx, task)
+	}
+	return task, nil
+}
+
+// closeQuorum finishes the pending tasks of a quorum node once its result is decided.
+func (t *task) closeQuorum(ctx context.Context, task *model.Task) error {
+	tasks, err := t.taskRepo.ListByTaskIDAndNodeDefKey(ctx, task.TaskID, task.NodeDefKey)
+	if err != nil {
+		return err
+	}
+	if finish, _ := evaluate(tasks); !finish {
+		return nil
+	}
+	return t.taskRepo.UpdateByTaskIDAndNodeDefKey(ctx, &model.Task{
+		TaskID:     task.TaskID,
+		NodeDefKey: task.NodeDefKey,
+		NodeResult: string(v1alpha1.Finish),
+		UpdatedAt:  time.NowUnix(),
+	})
 }
 
 type ListRequest struct {
-	UserID         string
-	CreatedBy      string
-	TaskID         string
-	NodeType       string
-	Result         string
-	Page           int
-	Limit          int
-	FlowInStanceID string
+	// CallerID lists the tasks of its own only, unless an admin.
+	CallerID    string `json:"-"`
+	UserID      string // the approver
+	CreatedBy   string
+	TaskID      string
+	AppID       string
+	NodeType    string // node result, Pending｜Finish｜Waiting｜Archived
+	Result      string
+	CreatedFrom int64 // created at from, in milliseconds, included
+	CreatedTo   int64 // created at to, in milliseconds, excluded
+	Page        int
+	Limit       int
 }
 type ListResponse struct {
-	Total int64        `json:"total,omitempty"`
-	Tasks []model.Task `json:"tasks,omitempty" json:"tasks,omitempty"`
+	Total int64        `json:"total"`
+	Tasks []model.Task `json:"tasks"`
 }
 
-//TODO 需要重写
-//func (t *task) List(ctx context.Context, req *ListRequest) (*ListResponse, error) {
-//	list, total, err := t.taskRepo.List(ctx, t.db, req.DealUsers, req.CreatedBy, req.FlowInStanceID, req.TaskID, req.Result, req.NodeType, req.Page, req.Limit)
-//	res := &ListResponse{
-//		Total: total,
-//		Tasks: list,
-//	}
-//	return res, err
-//}
+const (
+	defaultLimit = 10
+	maxLimit     = 100
+)
+
+// List returns a page of the tasks matching the filters of req, the latest first.
+func (t *task) List(ctx context.Context, req *ListRequest) (*ListResponse, error) {
+	userID, err := t.filterUser(req.CallerID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	page, limit := req.Page, req.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	list, total, err := t.taskRepo.List(ctx, &model.TaskFilter{
+		UserID:      userID,
+		CreatedBy:   req.CreatedBy,
+		TaskID:      req.TaskID,
+		AppID:       req.AppID,
+		Result:      req.Result,
+		NodeResult:  req.NodeType,
+		CreatedFrom: req.CreatedFrom,
+		CreatedTo:   req.CreatedTo,
+	}, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &ListResponse{
+		Total: int64(total),
+		Tasks: list,
+	}, nil
+}
 
 type UrgeRequest struct {
 	UserID   string
@@ -638,20 +1197,44 @@ type UrgeRequest struct {
 type UrgeResponse struct {
 }
 
+// Urge reminds the approvers of the pending tasks of the run TaskID, on behalf of its initiator.
 func (t *task) Urge(ctx context.Context, req *UrgeRequest) (*UrgeResponse, error) {
-	task, err := t.taskRepo.GetByUserIDAndTaskID(ctx, req.UserID, req.TaskID)
+	tasks, err := t.taskRepo.ListByTaskID(ctx, req.TaskID)
 	if err != nil {
 		return nil, err
 	}
-	if task == nil {
-		return nil, errors.New("当前任务与审核人不匹配")
+	if len(tasks) == 0 {
+		return nil, errors.New("审核任务不存在")
 	}
-	task.UpdatedAt = time.NowUnix()
-	task.UrgeTimes = task.UrgeTimes + 1
-	err = t.taskRepo.UpdateUrgeTimes(ctx, task)
+	if !t.canManage(&tasks[0], req.UserID) {
+		return nil, forbidden("只有发起人可以催办")
+	}
+	urged := make([]*model.Task, 0, len(tasks))
+	err = t.taskRepo.Transaction(ctx, func(ctx context.Context) error {
+		for k := range tasks {
+			if tasks[k].NodeResult != string(v1alpha1.Pending) || resubmitting(&tasks[k]) {
+				continue
+			}
+			urged = append(urged, &tasks[k])
+			err := t.actionRepo.Create(ctx, newAction(&tasks[k], ActionUrge, req.UserID))
+			if err != nil {
+				return err
+			}
+			tasks[k].UpdatedAt = time.NowUnix()
+			tasks[k].UrgeTimes = tasks[k].UrgeTimes + 1
+			err = t.taskRepo.UpdateUrgeTimes(ctx, &tasks[k])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	for _, task := range urged {
+		t.notifyUrge(task)
+	}
 	return &UrgeResponse{}, nil
 }
 
@@ -659,24 +1242,27 @@ type GetRequest struct {
 	ID string
 }
 type GetResponse struct {
-	ID          string
-	TaskID      string
-	UserID      string
-	Substitute  string //替代审核人的id
-	CreatedBy   string //发起人id
-	ExamineType string //单人或或签审批：or,多人会签：and
-	Result      string //agree｜reject
-	NodeResult  string //Pending｜Finish｜
-	CreatedAt   int64
-	UpdatedAt   int64
-	AppID       string
-	FormTableID string
-	FormDataID  string
-	FormRef     string
-	UrgeTimes   int64 //催办次数
-	Remark      string
-	FlowID      string
-	NodeDefKey  string
+	ID            string
+	TaskID        string
+	UserID        string
+	Substitute    string //转交给的审核人id
+	CreatedBy     string //发起人id
+	ExamineType   string //单人或或签审批：or,多人会签：and,依次审批：sequence
+	Result        string //agree｜reject｜recall｜transfer
+	NodeResult    string //Pending｜Finish｜
+	CreatedAt     int64
+	UpdatedAt     int64
+	AppID         string
+	FormTableID   string
+	FormDataID    string
+	FormRef       string
+	UrgeTimes     int64 //催办次数
+	Remark        string
+	FlowID        string
+	NodeDefKey    string
+	DelegatedFrom string //委托人id
+	DueAt         int64  //处理期限，0 不限
+	Overdue       bool   //已超时未处理，或超时后才处理
 }
 
 func (t *task) Get(ctx context.Context, req *GetRequest) (*GetResponse, error) {
@@ -688,24 +1274,27 @@ func (t *task) Get(ctx context.Context, req *GetRequest) (*GetResponse, error) {
 		return nil, nil
 	}
 	return &GetResponse{
-		ID:          res.ID,
-		TaskID:      res.TaskID,
-		UserID:      res.UserID,
-		Substitute:  res.Substitute,
-		CreatedBy:   res.CreatedBy,
-		ExamineType: res.ExamineType,
-		Result:      res.Result,
-		NodeResult:  res.NodeResult,
-		CreatedAt:   res.CreatedAt,
-		UpdatedAt:   res.UpdatedAt,
-		AppID:       res.AppID,
-		FormTableID: res.FormTableID,
-		FormDataID:  res.FormDataID,
-		FormRef:     res.FormRef,
-		UrgeTimes:   res.UrgeTimes,
-		Remark:      res.Remark,
-		FlowID:      res.FlowID,
-		NodeDefKey:  res.NodeDefKey,
+		ID:            res.ID,
+		TaskID:        res.TaskID,
+		UserID:        res.UserID,
+		Substitute:    res.Substitute,
+		CreatedBy:     res.CreatedBy,
+		ExamineType:   res.ExamineType,
+		Result:        res.Result,
+		NodeResult:    res.NodeResult,
+		CreatedAt:     res.CreatedAt,
+		UpdatedAt:     res.UpdatedAt,
+		AppID:         res.AppID,
+		FormTableID:   res.FormTableID,
+		FormDataID:    res.FormDataID,
+		FormRef:       res.FormRef,
+		UrgeTimes:     res.UrgeTimes,
+		Remark:        res.Remark,
+		FlowID:        res.FlowID,
+		NodeDefKey:    res.NodeDefKey,
+		DelegatedFrom: res.DelegatedFrom,
+		DueAt:         res.DueAt,
+		Overdue:       res.Overdue(time.NowUnix()),
 	}, nil
 }
 
@@ -721,24 +1310,29 @@ type GetByUserIDResponse struct {
 }
 
 type ExamineNodeInfo struct {
-	ID          string
-	TaskID      string
-	UserID      string
-	Substitute  string //替代审核人的id
-	CreatedBy   string //发起人id
-	ExamineType string //单人或或签审批：or,多人会签：and
-	Result      string //agree｜reject
-	NodeResult  string //Pending｜Finish｜
-	CreatedAt   int64
-	UpdatedAt   int64
-	AppID       string
-	FormTableID string
-	FormDataID  string
-	FormRef     string
-	UrgeTimes   int64 //催办次数
-	Remark      string
-	FlowID      string
-	NodeDefKey  string
+	ID            string
+	TaskID        string
+	UserID        string
+	Substitute    string //转交给的审核人id
+	CreatedBy     string //发起人id
+	ExamineType   string //单人或或签审批：or,多人会签：and,依次审批：sequence
+	Result        string //agree｜reject｜recall｜transfer
+	NodeResult    string //Pending｜Finish｜
+	CreatedAt     int64
+	UpdatedAt     int64
+	AppID         string
+	FormTableID   string
+	FormDataID    string
+	FormRef       string
+	UrgeTimes     int64 //催办次数
+	Remark        string
+	FlowID        string
+	NodeDefKey    string
+	DelegatedFrom string //委托人id
+	DueAt         int64  //处理期限，0 不限
+	Overdue       bool   //已超时未处理，或超时后才处理
+	// Actions are the actions taken on the task, the oldest first.
+	Actions []model.Action
 }
 
 func (t *task) GetByUserID(ctx context.Context, req *GetByUserIDRequest) (*GetByUserIDResponse, error) {
@@ -749,24 +1343,27 @@ func (t *task) GetByUserID(ctx context.Context, req *GetByUserIDRequest) (*GetBy
 	response := &GetByUserIDResponse{}
 	for k := range list {
 		response.Data = append(response.Data, ExamineNodeInfo{
-			ID:          list[k].ID,
-			TaskID:      list[k].TaskID,
-			UserID:      list[k].UserID,
-			Substitute:  list[k].Substitute,
-			CreatedBy:   list[k].CreatedBy,
-			ExamineType: list[k].ExamineType,
-			Result:      list[k].Result,
-			NodeResult:  list[k].NodeResult,
-			CreatedAt:   list[k].CreatedAt,
-			UpdatedAt:   list[k].UpdatedAt,
-			AppID:       list[k].AppID,
-			FormTableID: list[k].FormTableID,
-			FormDataID:  list[k].FormDataID,
-			FormRef:     list[k].FormRef,
-			UrgeTimes:   list[k].UrgeTimes,
-			Remark:      list[k].Remark,
-			FlowID:      list[k].FlowID,
-			NodeDefKey:  list[k].NodeDefKey,
+			ID:            list[k].ID,
+			TaskID:        list[k].TaskID,
+			UserID:        list[k].UserID,
+			Substitute:    list[k].Substitute,
+			CreatedBy:     list[k].CreatedBy,
+			ExamineType:   list[k].ExamineType,
+			Result:        list[k].Result,
+			NodeResult:    list[k].NodeResult,
+			CreatedAt:     list[k].CreatedAt,
+			UpdatedAt:     list[k].UpdatedAt,
+			AppID:         list[k].AppID,
+			FormTableID:   list[k].FormTableID,
+			FormDataID:    list[k].FormDataID,
+			FormRef:       list[k].FormRef,
+			UrgeTimes:     list[k].UrgeTimes,
+			Remark:        list[k].Remark,
+			FlowID:        list[k].FlowID,
+			NodeDefKey:    list[k].NodeDefKey,
+			DelegatedFrom: list[k].DelegatedFrom,
+			DueAt:         list[k].DueAt,
+			Overdue:       list[k].Overdue(time.NowUnix()),
 		})
 	}
 	response.Total = total
@@ -791,24 +1388,27 @@ func (t *task) GetByCreated(ctx context.Context, req *GetByCreatedRequest) (*Get
 	response := &GetByCreatedResponse{}
 	for k := range list {
 		response.Data = append(response.Data, ExamineNodeInfo{
-			ID:          list[k].ID,
-			TaskID:      list[k].TaskID,
-			UserID:      list[k].UserID,
-			Substitute:  list[k].Substitute,
-			CreatedBy:   list[k].CreatedBy,
-			ExamineType: list[k].ExamineType,
-			Result:      list[k].Result,
-			NodeResult:  list[k].NodeResult,
-			CreatedAt:   list[k].CreatedAt,
-			UpdatedAt:   list[k].UpdatedAt,
-			AppID:       list[k].AppID,
-			FormTableID: list[k].FormTableID,
-			FormDataID:  list[k].FormDataID,
-			FormRef:     list[k].FormRef,
-			UrgeTimes:   list[k].UrgeTimes,
-			Remark:      list[k].Remark,
-			FlowID:      list[k].FlowID,
-			NodeDefKey:  list[k].NodeDefKey,
+			ID:            list[k].ID,
+			TaskID:        list[k].TaskID,
+			UserID:        list[k].UserID,
+			Substitute:    list[k].Substitute,
+			CreatedBy:     list[k].CreatedBy,
+			ExamineType:   list[k].ExamineType,
+			Result:        list[k].Result,
+			NodeResult:    list[k].NodeResult,
+			CreatedAt:     list[k].CreatedAt,
+			UpdatedAt:     list[k].UpdatedAt,
+			AppID:         list[k].AppID,
+			FormTableID:   list[k].FormTableID,
+			FormDataID:    list[k].FormDataID,
+			FormRef:       list[k].FormRef,
+			UrgeTimes:     list[k].UrgeTimes,
+			Remark:        list[k].Remark,
+			FlowID:        list[k].FlowID,
+			NodeDefKey:    list[k].NodeDefKey,
+			DelegatedFrom: list[k].DelegatedFrom,
+			DueAt:         list[k].DueAt,
+			Overdue:       list[k].Overdue(time.NowUnix()),
 		})
 	}
 	response.Total = total
@@ -828,26 +1428,37 @@ func (t *task) GetByUserIDAndTaskID(ctx context.Context, req *GetByUserIDAndTask
 	if err != nil {
 		return nil, err
 	}
+	if data == nil {
+		return nil, nil
+	}
+	actions, err := t.actionsOf(ctx, *data)
+	if err != nil {
+		return nil, err
+	}
 	response := &GetByUserIDAndTaskIDResponse{}
 	response.Data = &ExamineNodeInfo{
-		ID:          data.ID,
-		TaskID:      data.TaskID,
-		UserID:      data.UserID,
-		Substitute:  data.Substitute,
-		CreatedBy:   data.CreatedBy,
-		ExamineType: data.ExamineType,
-		Result:      data.Result,
-		NodeResult:  data.NodeResult,
-		CreatedAt:   data.CreatedAt,
-		UpdatedAt:   data.UpdatedAt,
-		AppID:       data.AppID,
-		FormTableID: data.FormTableID,
-		FormDataID:  data.FormDataID,
-		FormRef:     data.FormRef,
-		UrgeTimes:   data.UrgeTimes,
-		Remark:      data.Remark,
-		FlowID:      data.FlowID,
-		NodeDefKey:  data.NodeDefKey,
+		ID:            data.ID,
+		TaskID:        data.TaskID,
+		UserID:        data.UserID,
+		Substitute:    data.Substitute,
+		CreatedBy:     data.CreatedBy,
+		ExamineType:   data.ExamineType,
+		Result:        data.Result,
+		NodeResult:    data.NodeResult,
+		CreatedAt:     data.CreatedAt,
+		UpdatedAt:     data.UpdatedAt,
+		AppID:         data.AppID,
+		FormTableID:   data.FormTableID,
+		FormDataID:    data.FormDataID,
+		FormRef:       data.FormRef,
+		UrgeTimes:     data.UrgeTimes,
+		Remark:        data.Remark,
+		FlowID:        data.FlowID,
+		NodeDefKey:    data.NodeDefKey,
+		DelegatedFrom: data.DelegatedFrom,
+		DueAt:         data.DueAt,
+		Overdue:       data.Overdue(time.NowUnix()),
+		Actions:       actions[data.ID],
 	}
 	return response, nil
 }
@@ -866,69 +1477,37 @@ func (t *task) GetByFlowID(ctx context.Context, req *GetByFlowIDRequest) (*GetBy
 	if err != nil {
 		return nil, err
 	}
+	actions, err := t.actionsOf(ctx, tasks...)
+	if err != nil {
+		return nil, err
+	}
 	response := &GetByFlowIDResponse{}
 	for k := range tasks {
 		resp := ExamineNodeInfo{
-			ID:          tasks[k].ID,
-			TaskID:      tasks[k].TaskID,
-			UserID:      tasks[k].UserID,
-			Substitute:  tasks[k].Substitute,
-			CreatedBy:   tasks[k].CreatedBy,
-			ExamineType: tasks[k].ExamineType,
-			Result:      tasks[k].Result,
-			NodeResult:  tasks[k].NodeResult,
-			CreatedAt:   tasks[k].CreatedAt,
-			UpdatedAt:   tasks[k].UpdatedAt,
-			AppID:       tasks[k].AppID,
-			FormTableID: tasks[k].FormTableID,
-			FormDataID:  tasks[k].FormDataID,
-			FormRef:     tasks[k].FormRef,
-			UrgeTimes:   tasks[k].UrgeTimes,
-			Remark:      tasks[k].Remark,
-			FlowID:      tasks[k].FlowID,
-			NodeDefKey:  tasks[k].NodeDefKey,
+			ID:            tasks[k].ID,
+			TaskID:        tasks[k].TaskID,
+			UserID:        tasks[k].UserID,
+			Substitute:    tasks[k].Substitute,
+			CreatedBy:     tasks[k].CreatedBy,
+			ExamineType:   tasks[k].ExamineType,
+			Result:        tasks[k].Result,
+			NodeResult:    tasks[k].NodeResult,
+			CreatedAt:     tasks[k].CreatedAt,
+			UpdatedAt:     tasks[k].UpdatedAt,
+			AppID:         tasks[k].AppID,
+			FormTableID:   tasks[k].FormTableID,
+			FormDataID:    tasks[k].FormDataID,
+			FormRef:       tasks[k].FormRef,
+			UrgeTimes:     tasks[k].UrgeTimes,
+			Remark:        tasks[k].Remark,
+			FlowID:        tasks[k].FlowID,
+			NodeDefKey:    tasks[k].NodeDefKey,
+			DelegatedFrom: tasks[k].DelegatedFrom,
+			DueAt:         tasks[k].DueAt,
+			Overdue:       tasks[k].Overdue(time.NowUnix()),
+			Actions:       actions[tasks[k].ID],
 		}
 		response.Data = append(response.Data, resp)
 	}
 	return response, nil
 }
-func (t *task) sendEmail(ctx context.Context, userIDs []string, runID, taskID string) {
-	ctx = context.Background()
-	sendReqs := make([]*quanxiang.CreateReq, 0)
-	for k := range userIDs {
-		usersInfo, err := t.qx.GetUserInfo(ctx, &quanxiang.GetUsersInfoRequest{
-			ID: userIDs[k],
-		})
-		if err != nil {
-			level.Error(t.logger).Log("message", "examine do send message", "userID", userIDs[k], "err", err)
-			continue
-		}
-		if usersInfo != nil && usersInfo.Data.ID != "" {
-			m := new(quanxiang.CreateReq)
-			email := new(quanxiang.Email)
-			content := "您有新的" + "流程的审批，请点击查看：" + t.homeHost + "/approvals/" +
-				runID + "/" + taskID + "/WAIT_HANDLE_PAGE"
-
-			email.Title = "审批提醒"
-
-			email.Content = &quanxiang.Content{
-				Content:    content,
-				TemplateID: "quanliang",
-			}
-			email.To = []string{usersInfo.Data.Email}
-			//todo 这里还需要对content进行处理，可能是从表单字段取值又或者是公式计算
-			m.Email = email
-			sendReqs = append(sendReqs, m)
-		}
-
-		if len(sendReqs) > 0 {
-			_, err := t.qx.SendMessage(ctx, sendReqs)
-			if err != nil {
-				level.Error(t.logger).Log("message", "examine do send message", "err", err)
-				continue
-			}
-			level.Info(t.logger).Log("message", "examine do send message ok", "email", usersInfo.Data.Email)
-		}
-
-	}
-}
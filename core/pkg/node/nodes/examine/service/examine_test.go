@@ -0,0 +1,360 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	e "github.com/quanxiang-cloud/cabin/error"
+
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+	"git.yunify.com/quanxiang/workflow/pkg/node/nodetest"
+	"git.yunify.com/quanxiang/workflow/pkg/thirdparty/quanxiang"
+)
+
+func TestEvaluateQuorum(t *testing.T) {
+	tasks := func(quorum string, results ...string) []model.Task {
+		list := make([]model.Task, 0, len(results))
+		for _, result := range results {
+			task := model.Task{
+				ExamineType: ExamineQuorum,
+				Quorum:      quorum,
+				Result:      result,
+				NodeResult:  string(v1alpha1.Finish),
+			}
+			if result == "" {
+				task.NodeResult = string(v1alpha1.Pending)
+			}
+			list = append(list, task)
+		}
+		return list
+	}
+
+	tests := []struct {
+		name   string
+		tasks  []model.Task
+		finish bool
+		agree  bool
+	}{
+		{
+			name:  "3 of 5 waiting",
+			tasks: tasks("3", ResultAgree, ResultAgree, "", "", ""),
+		},
+		{
+			name:   "3 of 5 agreed",
+			tasks:  tasks("3", ResultAgree, ResultAgree, ResultAgree, "", ""),
+			finish: true,
+			agree:  true,
+		},
+		{
+			name:  "3 of 5 still reachable",
+			tasks: tasks("3", ResultReject, ResultReject, "", "", ""),
+		},
+		{
+			name:   "3 of 5 unreachable",
+			tasks:  tasks("3", ResultAgree, ResultReject, ResultReject, ResultRecall, ""),
+			finish: true,
+		},
+		{
+			name:   "60% of 4 rounds up to 3",
+			tasks:  tasks("60%", ResultAgree, ResultAgree, ResultReject, ResultReject),
+			finish: true,
+		},
+		{
+			name:   "transferred tasks do not count",
+			tasks:  tasks("100%", ResultAgree, ResultTransfer, ResultAgree),
+			finish: true,
+			agree:  true,
+		},
+		{
+			name:   "quorum above assignees needs all",
+			tasks:  tasks("5", ResultAgree, ResultAgree),
+			finish: true,
+			agree:  true,
+		},
+	}
+
+	for _, test := range tests {
+		finish, agree := evaluate(test.tasks)
+		if finish != test.finish || agree != test.agree {
+			t.Errorf("%s: expect finish %v agree %v, got %v %v", test.name, test.finish, test.agree, finish, agree)
+		}
+	}
+}
+
+func TestQuorumOf(t *testing.T) {
+	for _, q := range []string{"", "0", "-1", "x", "0%", "101%", "%"} {
+		if _, err := quorumOf(q, 5); err == nil {
+			t.Errorf("expect error for quorum %q", q)
+		}
+	}
+}
+
+func TestEvaluateSigners(t *testing.T) {
+	approver := model.Task{ID: "p", ExamineType: ExamineOr, Result: ResultAgree, NodeResult: string(v1alpha1.Finish)}
+	signer := func(result, nodeResult string) model.Task {
+		return model.Task{SignerOf: "p", ExamineType: ExamineOr, Result: result, NodeResult: nodeResult}
+	}
+
+	tests := []struct {
+		name   string
+		tasks  []model.Task
+		finish bool
+		agree  bool
+	}{
+		{
+			name:  "signer after the approver pending",
+			tasks: []model.Task{approver, signer("", string(v1alpha1.Pending))},
+		},
+		{
+			name:   "signer agreed",
+			tasks:  []model.Task{approver, signer(ResultAgree, string(v1alpha1.Finish))},
+			finish: true,
+			agree:  true,
+		},
+		{
+			name:   "signer refused",
+			tasks:  []model.Task{approver, signer(ResultReject, string(v1alpha1.Finish))},
+			finish: true,
+		},
+		{
+			name: "approver waits for the signers added before",
+			tasks: []model.Task{
+				{ID: "p", ExamineType: ExamineOr, NodeResult: model.NodeResultWaiting},
+				signer(ResultAgree, string(v1alpha1.Finish)),
+			},
+		},
+		{
+			name: "archived round does not count",
+			tasks: []model.Task{
+				approver,
+				{ExamineType: ExamineOr, Result: ResultReject, NodeResult: model.NodeResultArchived},
+			},
+			finish: true,
+			agree:  true,
+		},
+	}
+
+	for _, test := range tests {
+		finish, agree := evaluate(test.tasks)
+		if finish != test.finish || agree != test.agree {
+			t.Errorf("%s: expect finish %v agree %v, got %v %v", test.name, test.finish, test.agree, finish, agree)
+		}
+	}
+}
+
+func TestRemindOf(t *testing.T) {
+	tests := map[string]int64{
+		"":    0,
+		"24":  24 * 3600 * 1000,
+		"0.5": 1800 * 1000,
+		"30m": 1800 * 1000,
+	}
+	for s, expect := range tests {
+		got, err := remindOf(s)
+		if err != nil || got != expect {
+			t.Errorf("remind %q: expect %d, got %d %v", s, expect, got, err)
+		}
+	}
+	for _, s := range []string{"x", "0", "-1", "30s"} {
+		if _, err := remindOf(s); err == nil {
+			t.Errorf("expect error for remind %q", s)
+		}
+	}
+}
+
+func TestRefusedFields(t *testing.T) {
+	permissions := `{"amount":{"x-internal":{"permission":1}},"remark":{"x-internal":{"permission":3}}}`
+	form := &FormData{
+		Entity: map[string]interface{}{"amount": 100, "remark": "ok", "_id": "1"},
+	}
+
+	refused, err := refusedFields(permissions, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(refused, ",") != "_id,amount" {
+		t.Errorf("expect _id,amount refused, got %v", refused)
+	}
+
+	refused, err = refusedFields("", form)
+	if err != nil || len(refused) != 0 {
+		t.Errorf("expect no field refused without config, got %v %v", refused, err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	qx := nodetest.NewQuanXiang()
+	leaderOf := func(id string) [][]quanxiang.Leader {
+		return [][]quanxiang.Leader{{{ID: id}}}
+	}
+	qx.PutUser(&quanxiang.UserData{ID: "u1", Leader: leaderOf("u2")})
+	qx.PutUser(&quanxiang.UserData{ID: "u2", Leader: leaderOf("u3")})
+	qx.PutUser(&quanxiang.UserData{ID: "u3"})
+	qx.PutUser(&quanxiang.UserData{ID: "u4", Dep: [][]quanxiang.DepOneResponse{{{ID: "d1"}}}})
+	qx.DepLeaders["d1"] = []string{"u3"}
+	qx.Roles["r1"] = []string{"u4", "u2"}
+
+	r := &Resolution{
+		QX: qx,
+		Entity: map[string]interface{}{
+			"users": []interface{}{map[string]interface{}{"value": "u4"}},
+			"dep":   []interface{}{map[string]interface{}{"value": "d1"}},
+		},
+		Initiator: "u1",
+		CreatedBy: "u1",
+		Params:    map[string]string{"v1": "u2, u5"},
+	}
+	tests := map[string]string{
+		"leader":                              "u2",
+		"leader.3":                            "u2,u3",
+		"person.u9,field.users":               "u9,u4",
+		"role.r1,dep.d1,depLeader.d1":         "u4,u2,u4,u3",
+		"fieldDepLeader.dep,variable.v1":      "u3,u2,u5",
+		"formApplyUserID,unknown.x,leader.1":  "u1,u2",
+		"field.missing,variable.missing,dep.": "",
+	}
+	for dealUsers, expect := range tests {
+		ids, _, err := resolve(context.Background(), r, dealUsers)
+		if err != nil {
+			t.Errorf("resolve %s: %v", dealUsers, err)
+			continue
+		}
+		if got := strings.Join(ids, ","); got != expect {
+			t.Errorf("resolve %s: expect %s, got %s", dealUsers, expect, got)
+		}
+	}
+	if _, _, err := resolve(context.Background(), r, "leader.0"); err == nil {
+		t.Errorf("expect error for leader.0")
+	}
+
+	ids := []string{"u2", "u1", "u2", "u3"}
+	if got := strings.Join(dedupe(without(ids, "u1")), ","); got != "u2,u3" {
+		t.Errorf("expect u2,u3, got %s", got)
+	}
+}
+
+func TestBatchResult(t *testing.T) {
+	if r := batchResult("1", nil); r.Code != 0 || r.Message != "" {
+		t.Errorf("expect success, got %+v", r)
+	}
+	if r := batchResult("1", errors.Wrap(forbidden("no"), "examine")); r.Code != http.StatusForbidden || r.Message != "no" {
+		t.Errorf("expect code %d, got %+v", http.StatusForbidden, r)
+	}
+	if r := batchResult("1", errors.New("boom")); r.Code != e.Unknown || r.Message != "boom" {
+		t.Errorf("expect unknown error, got %+v", r)
+	}
+}
+
+func TestOverdue(t *testing.T) {
+	tests := []struct {
+		task   model.Task
+		expect bool
+	}{
+		{model.Task{NodeResult: string(v1alpha1.Pending)}, false},
+		{model.Task{NodeResult: string(v1alpha1.Pending), DueAt: 100}, true},
+		{model.Task{NodeResult: string(v1alpha1.Pending), DueAt: 300}, false},
+		{model.Task{NodeResult: string(v1alpha1.Finish), Result: ResultAgree, DueAt: 100, UpdatedAt: 150}, true},
+		{model.Task{NodeResult: string(v1alpha1.Finish), Result: ResultAgree, DueAt: 100, UpdatedAt: 50}, false},
+		{model.Task{NodeResult: string(v1alpha1.Finish), Result: ResultRecall, DueAt: 100, UpdatedAt: 150}, false},
+		{model.Task{NodeResult: string(v1alpha1.Finish), DueAt: 100, UpdatedAt: 150}, false},
+		{model.Task{NodeResult: model.NodeResultWaiting, DueAt: 100}, false},
+	}
+	for k, test := range tests {
+		if got := test.task.Overdue(200); got != test.expect {
+			t.Errorf("task %d: expect overdue %v, got %v", k, test.expect, got)
+		}
+	}
+}
+
+func TestRoundOf(t *testing.T) {
+	tasks := []model.Task{
+		{NodeDefKey: "a", CreatedAt: 10},
+		{NodeDefKey: "b", CreatedAt: 30},
+		{NodeDefKey: "b", CreatedAt: 20},
+		{NodeDefKey: "b", CreatedAt: 40, ExamineType: ExamineResubmit, NodeResult: string(v1alpha1.Pending)},
+	}
+	if since := roundOf(tasks, "b"); since != 20 {
+		t.Errorf("expect round of b at 20, got %d", since)
+	}
+	if since := roundOf(tasks, "c"); since != -1 {
+		t.Errorf("expect no round of c, got %d", since)
+	}
+	if list := handling(tasks); len(list) != 3 {
+		t.Errorf("expect the resubmit task left out, got %d tasks", len(list))
+	}
+}
+
+func TestFilterUser(t *testing.T) {
+	s := &task{admins: map[string]struct{}{"admin": {}}}
+
+	if _, err := s.filterUser("", "u2"); err == nil {
+		t.Error("expect a caller to be required")
+	}
+	if got, _ := s.filterUser("admin", "u2"); got != "u2" {
+		t.Errorf("expect an admin to filter by u2, got %q", got)
+	}
+	if got, _ := s.filterUser("u1", "u2"); got != "u1" {
+		t.Errorf("expect u1 to be pinned to their own tasks, got %q", got)
+	}
+}
+
+func TestExamineOrScope(t *testing.T) {
+	tasks := &memoryTasks{
+		tasks: []model.Task{
+			{ID: "e0", TaskID: "1", NodeDefKey: "n0", UserID: "u0", ExamineType: ExamineOr, NodeResult: string(v1alpha1.Pending)},
+			{ID: "e1", TaskID: "1", NodeDefKey: "n1", UserID: "u1", ExamineType: ExamineOr, NodeResult: string(v1alpha1.Pending)},
+			{ID: "e2", TaskID: "1", NodeDefKey: "n1", UserID: "u2", ExamineType: ExamineOr, NodeResult: string(v1alpha1.Pending)},
+		},
+	}
+	s := &task{taskRepo: tasks}
+
+	if _, err := s.examineTask(context.Background(), "u1", "e1", ResultAgree, ""); err != nil {
+		t.Fatal(err)
+	}
+	// the node of the task is closed, the task of the other node stays pending
+	if tasks.tasks[0].NodeResult != string(v1alpha1.Pending) || tasks.tasks[2].NodeResult != string(v1alpha1.Finish) {
+		t.Errorf("unexpected tasks %+v", tasks.tasks)
+	}
+}
+
+// delegations delegates the approvals of the users it maps to their delegates.
+type delegations struct {
+	model.DelegationRepo
+	to map[string]string
+}
+
+func (d delegations) GetActive(ctx context.Context, userID, appID, flowID string, at int64) (*model.Delegation, error) {
+	if delegate, ok := d.to[userID]; ok {
+		return &model.Delegation{UserID: userID, Delegate: delegate}, nil
+	}
+	return nil, nil
+}
+
+func TestDelegate(t *testing.T) {
+	s := &task{delegationRepo: delegations{to: map[string]string{"u1": "u3", "u2": "u3"}}}
+
+	assignees, err := s.delegate(context.Background(), &DoRequest{}, []string{"u1", "u2", "u3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// u3 gets a task for each approver it replaces, and its own one is not delegated
+	want := []assignee{{"u3", "u1"}, {"u3", "u2"}, {"u3", ""}}
+	if len(assignees) != len(want) {
+		t.Fatalf("expect %v, got %v", want, assignees)
+	}
+	for k := range want {
+		if assignees[k] != want[k] {
+			t.Errorf("expect %v, got %v", want[k], assignees[k])
+		}
+	}
+
+	req := &DoRequest{UserID: []string{"u3", "u3", "u3"}, DelegatedFrom: []string{"u1", "u2", ""}}
+	tasks := []model.Task{*newTask(req, "u3", "u1")}
+	if next := nextApprover(tasks, req); next != 1 {
+		t.Errorf("expect the approver replacing u2 next, got %d", next)
+	}
+}
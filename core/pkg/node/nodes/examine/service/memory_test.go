@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+
+	"git.yunify.com/quanxiang/workflow/apis"
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
+	"git.yunify.com/quanxiang/workflow/pkg/client/clientset/versioned"
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+)
+
+// memoryTasks keeps the tasks of the calls of Recall, examineTask and do, the other calls are not implemented.
+type memoryTasks struct {
+	model.TaskRepo
+	tasks []model.Task
+	// inserted are the tasks inserted by do, like the one returning the run to its initiator.
+	inserted []*model.Task
+}
+
+func (m *memoryTasks) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (m *memoryTasks) ListByTaskID(ctx context.Context, taskID string) ([]model.Task, error) {
+	list := make([]model.Task, 0, len(m.tasks))
+	for _, task := range m.tasks {
+		if task.TaskID == taskID {
+			list = append(list, task)
+		}
+	}
+	return list, nil
+}
+
+func (m *memoryTasks) ListByTaskIDAndNodeDefKey(ctx context.Context, taskID, nodeDefKey string) ([]model.Task, error) {
+	list := make([]model.Task, 0, len(m.tasks))
+	for _, task := range m.tasks {
+		if task.TaskID == taskID && task.NodeDefKey == nodeDefKey {
+			list = append(list, task)
+		}
+	}
+	return list, nil
+}
+
+func (m *memoryTasks) RecallByTaskIDAndNodeDefKey(ctx context.Context, data *model.Task) error {
+	for k := range m.tasks {
+		task := &m.tasks[k]
+		if task.TaskID != data.TaskID || task.NodeDefKey != data.NodeDefKey {
+			continue
+		}
+		if task.NodeResult == string(v1alpha1.Pending) || task.NodeResult == model.NodeResultWaiting {
+			task.Result, task.NodeResult, task.UpdatedAt = data.Result, data.NodeResult, data.UpdatedAt
+		}
+	}
+	return nil
+}
+
+func (m *memoryTasks) GetByID(ctx context.Context, id string) (*model.Task, error) {
+	for k := range m.tasks {
+		if m.tasks[k].ID == id {
+			task := m.tasks[k]
+			return &task, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryTasks) UpdateResult(ctx context.Context, data *model.Task) error {
+	for k := range m.tasks {
+		if m.tasks[k].ID == data.ID {
+			m.tasks[k] = *data
+		}
+	}
+	return nil
+}
+
+func (m *memoryTasks) UpdateByTaskIDAndNodeDefKey(ctx context.Context, data *model.Task) error {
+	for k := range m.tasks {
+		task := &m.tasks[k]
+		if task.TaskID != data.TaskID || task.NodeDefKey != data.NodeDefKey {
+			continue
+		}
+		if task.NodeResult == string(v1alpha1.Pending) || task.NodeResult == model.NodeResultWaiting {
+			task.NodeResult, task.UpdatedAt = data.NodeResult, data.UpdatedAt
+		}
+	}
+	return nil
+}
+
+func (m *memoryTasks) InsertBranch(ctx context.Context, data ...*model.Task) error {
+	m.inserted = append(m.inserted, data...)
+	for _, task := range data {
+		m.tasks = append(m.tasks, *task)
+	}
+	return nil
+}
+
+type memoryActions struct {
+	model.ActionRepo
+	actions []*model.Action
+}
+
+func (m *memoryActions) Create(ctx context.Context, data ...*model.Action) error {
+	m.actions = append(m.actions, data...)
+	return nil
+}
+
+type memoryOutbox struct {
+	model.OutboxRepo
+	msgs map[string]*model.Outbox
+}
+
+func (m *memoryOutbox) Create(ctx context.Context, data *model.Outbox) error {
+	m.msgs[data.ID] = data
+	return nil
+}
+
+func (m *memoryOutbox) UpdateAttempt(ctx context.Context, data *model.Outbox) error {
+	m.msgs[data.ID] = data
+	return nil
+}
+
+func (m *memoryOutbox) Claim(ctx context.Context, id string, nextAt, lease int64) (bool, error) {
+	msg, ok := m.msgs[id]
+	if !ok || msg.NextAt != nextAt {
+		return false, nil
+	}
+	msg.NextAt = lease
+	return true, nil
+}
+
+func (m *memoryOutbox) Delete(ctx context.Context, id string) error {
+	delete(m.msgs, id)
+	return nil
+}
+
+// runs records the runs resumed and rewound, the rewinds fail with rewindErr.
+type runs struct {
+	versioned.Client
+	resumed   []int64
+	rewound   []int64
+	rewindErr error
+}
+
+func (r *runs) RewindPipelineRun(ctx context.Context, in *apis.RewindPipelineRun) error {
+	r.rewound = append(r.rewound, in.ID)
+	return r.rewindErr
+}
+
+func (r *runs) ExecPipelineRun(ctx context.Context, in *apis.ExecPipelineRun) error {
+	r.resumed = append(r.resumed, in.ID)
+	return nil
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+	ctime "github.com/quanxiang-cloud/cabin/time"
+
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+	"git.yunify.com/quanxiang/workflow/pkg/thirdparty/quanxiang"
+)
+
+const (
+	ChannelLetter = "letter"
+	ChannelEmail  = "email"
+)
+
+// Notify configures the messages sent by the examine node. The titles and contents of
+// the templates may use ${link}, ${runID}, ${result}, ${remark} and ${urgeTimes}.
+type Notify struct {
+	// Channels are letter and email, both of them when empty.
+	Channels []string `yaml:"channels"`
+	// Pending is sent to the approver of a new task.
+	Pending Template `yaml:"pending"`
+	// Urge is sent to the approvers urged by the initiator or reminded automatically.
+	Urge Template `yaml:"urge"`
+	// Result is sent to the initiator once an examine node of the run is decided.
+	Result Template `yaml:"result"`
+	// CC is sent to the users a cc node copies the run to.
+	CC Template `yaml:"cc"`
+	// Resubmit is sent to the initiator of a run returned to resubmit once rejected.
+	Resubmit Template `yaml:"resubmit"`
+}
+
+type Template struct {
+	Title   string `yaml:"title"`
+	Content string `yaml:"content"`
+}
+
+var defaultNotify = Notify{
+	Channels: []string{ChannelLetter, ChannelEmail},
+	Pending: Template{
+		Title:   "审批提醒",
+		Content: "您有新的流程的审批，请点击查看：${link}",
+	},
+	Urge: Template{
+		Title:   "催办提醒",
+		Content: "您有流程的审批待处理，已催办 ${urgeTimes} 次，请点击查看：${link}",
+	},
+	Result: Template{
+		Title:   "审批结果",
+		Content: "您发起的流程审批${result}，请点击查看：${link}",
+	},
+	CC: Template{
+		Title:   "抄送提醒",
+		Content: "您收到一条抄送的流程，请点击查看：${link}",
+	},
+	Resubmit: Template{
+		Title:   "重新提交提醒",
+		Content: "您发起的流程未通过，已退回给您，请修改后重新提交：${link}",
+	},
+}
+
+// withDefaults fills what n leaves empty from defaultNotify.
+func (n Notify) withDefaults() Notify {
+	if len(n.Channels) == 0 {
+		n.Channels = defaultNotify.Channels
+	}
+	for _, tmpl := range []struct{ t, d *Template }{
+		{&n.Pending, &defaultNotify.Pending},
+		{&n.Urge, &defaultNotify.Urge},
+		{&n.Result, &defaultNotify.Result},
+		{&n.CC, &defaultNotify.CC},
+		{&n.Resubmit, &defaultNotify.Resubmit},
+	} {
+		if tmpl.t.Title == "" {
+			tmpl.t.Title = tmpl.d.Title
+		}
+		if tmpl.t.Content == "" {
+			tmpl.t.Content = tmpl.d.Content
+		}
+	}
+	return n
+}
+
+func (n Notify) has(channel string) bool {
+	for _, c := range n.Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+func (tmpl Template) render(values map[string]string) (title, content string) {
+	title, content = tmpl.Title, tmpl.Content
+	for k, v := range values {
+		title = strings.ReplaceAll(title, fmt.Sprintf("${%s}", k), v)
+		content = strings.ReplaceAll(content, fmt.Sprintf("${%s}", k), v)
+	}
+	return
+}
+
+const (
+	pageWaitHandle = "WAIT_HANDLE_PAGE"
+	pageApply      = "APPLY_PAGE"
+	pageCC         = "CC_PAGE"
+)
+
+// notifyPending tells the approvers of the pending ones of tasks about them.
+func (t *task) notifyPending(tasks ...*model.Task) {
+	for _, task := range tasks {
+		if task.NodeResult == string(v1alpha1.Pending) {
+			t.notify(t.notifyConf.Pending, task, task.UserID, pageWaitHandle, nil)
+		}
+	}
+}
+
+// notifyUrge reminds the approver of task about it.
+func (t *task) notifyUrge(task *model.Task) {
+	t.notify(t.notifyConf.Urge, task, task.UserID, pageWaitHandle, map[string]string{
+		"urgeTimes": strconv.FormatInt(task.UrgeTimes, 10),
+	})
+}
+
+// notifyResult tells the initiator of the run of task whether its node was agreed.
+func (t *task) notifyResult(task *model.Task, agree bool) {
+	result := "未通过"
+	if agree {
+		result = "已通过"
+	}
+	t.notify(t.notifyConf.Result, task, task.CreatedBy, pageApply, map[string]string{
+		"result": result,
+	})
+}
+
+// notifyCC tells the users of ccs about them.
+func (t *task) notifyCC(ccs ...*model.CC) {
+	for _, cc := range ccs {
+		t.send(t.notifyConf.CC, cc.ID, cc.UserID, map[string]string{
+			"link":  t.homeHost + "/approvals/" + cc.TaskID + "/" + cc.ID + "/" + pageCC,
+			"runID": cc.TaskID,
+		})
+	}
+}
+
+// notify sends tmpl about task to userID through the configured channels in the background,
+// a message failing to be sent is only logged.
+func (t *task) notify(tmpl Template, task *model.Task, userID, page string, values map[string]string) {
+	all := map[string]string{
+		"link":   t.homeHost + "/approvals/" + task.TaskID + "/" + task.ID + "/" + page,
+		"runID":  task.TaskID,
+		"remark": task.Remark,
+	}
+	for k, v := range values {
+		all[k] = v
+	}
+	t.send(tmpl, task.ID, userID, all)
+}
+
+// send sends tmpl rendered with values to userID in the background, ref is what it is about.
+func (t *task) send(tmpl Template, ref, userID string, values map[string]string) {
+	if userID == "" {
+		return
+	}
+	title, content := tmpl.render(values)
+
+	go func() {
+		ctx := context.Background()
+		reqs := make([]*quanxiang.CreateReq, 0, 2)
+		if t.notifyConf.has(ChannelLetter) {
+			m := new(quanxiang.CreateReq)
+			m.Letter = &quanxiang.Letter{
+				UUID: []string{userID},
+				Content: &quanxiang.Content{
+					Content: content,
+				},
+			}
+			reqs = append(reqs, m)
+		}
+		if t.notifyConf.has(ChannelEmail) {
+			user, err := t.qx.GetUserInfo(ctx, &quanxiang.GetUsersInfoRequest{
+				ID: userID,
+			})
+			if err != nil {
+				level.Error(t.logger).Log("message", "examine notify get user", "userID", userID, "err", err)
+			} else if user != nil && user.Data != nil && user.Data.Email != "" {
+				m := new(quanxiang.CreateReq)
+				m.Email = &quanxiang.Email{
+					To:    []string{user.Data.Email},
+					Title: title,
+					Content: &quanxiang.Content{
+						Content:    content,
+						TemplateID: "quanliang",
+					},
+				}
+				reqs = append(reqs, m)
+			}
+		}
+		if len(reqs) == 0 {
+			return
+		}
+
+		_, err := t.qx.SendMessage(ctx, reqs)
+		if err != nil {
+			level.Error(t.logger).Log("message", "examine send message", "userID", userID, "ref", ref, "err", err)
+			return
+		}
+		level.Info(t.logger).Log("message", "examine send message ok", "userID", userID, "ref", ref)
+	}()
+}
+
+// remindOf returns the interval in milliseconds of the reminders of a node, given in hours
+// like "24", or as a duration like "30m". Empty means no reminder.
+func remindOf(s string) (int64, error) {
+	return millisOf(Remind, s)
+}
+
+// millisOf returns in milliseconds the param name of a node, a period of at least a minute
+// given in hours like "24", or as a duration like "30m". Empty is 0.
+func millisOf(name, s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	every, err := time.ParseDuration(s)
+	if hours, e := strconv.ParseFloat(s, 64); e == nil {
+		every, err = time.Duration(hours*float64(time.Hour)), nil
+	}
+	if err != nil || every < time.Minute {
+		return 0, fmt.Errorf("invalid %s %q", name, s)
+	}
+	return every.Milliseconds(), nil
+}
+
+// Remind reminds every interval the approvers of the pending tasks whose node asks for
+// reminders, once its remind interval passed since the task was created or last reminded.
+func (t *task) Remind(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := ctime.NowUnix()
+		tasks, err := t.taskRepo.ListRemindDue(ctx, now, relayBatch)
+		if err != nil {
+			level.Error(t.logger).Log("message", "examine list remind", "err", err.Error())
+			continue
+		}
+		for k := range tasks {
+			tasks[k].RemindedAt = now
+			err = t.taskRepo.UpdateRemindedAt(ctx, &tasks[k])
+			if err != nil {
+				level.Error(t.logger).Log("message", "examine update remind", "id", tasks[k].ID, "err", err.Error())
+				continue
+			}
+			t.notifyUrge(&tasks[k])
+		}
+	}
+}
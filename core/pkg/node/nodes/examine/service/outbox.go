@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/quanxiang-cloud/cabin/id"
+	ctime "github.com/quanxiang-cloud/cabin/time"
+
+	"git.yunify.com/quanxiang/workflow/apis"
+	"git.yunify.com/quanxiang/workflow/pkg/client/clientset/versioned"
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+)
+
+const (
+	relayBatch   = 100
+	retryBackoff = 5 * time.Second
+	retryMaxWait = 10 * time.Minute
+	// claimLease is how long a message is left to the delivery that claimed it.
+	claimLease = time.Minute
+)
+
+// enqueue records msg, a request to the workflow core about the run msg.RunID, in the outbox,
+// within the transaction of ctx, so it is kept if and only if the state change causing it is.
+// It is sent by deliver right after the commit, Relay only picks it up once that had time to fail.
+// msg.Form is the form data update applied before the request, returned by formUpdateOf, none when empty.
+func (t *task) enqueue(ctx context.Context, msg *model.Outbox) (*model.Outbox, error) {
+	now := ctime.NowUnix()
+	msg.ID = id.BaseUUID()
+	msg.NextAt = now + retryBackoff.Milliseconds()
+	msg.CreatedAt = now
+	return msg, t.outboxRepo.Create(ctx, msg)
+}
+
+// deliver updates the form data of msg and sends msg to the workflow core, then drops it from the outbox.
+// A failed delivery stays in the outbox and is retried by Relay later, without the form data update
+// once that is done. msg is claimed first, so that it is sent by a single delivery at once, none
+// when another one claimed it already.
+func (t *task) deliver(ctx context.Context, msg *model.Outbox) error {
+	lease := ctime.NowUnix() + claimLease.Milliseconds()
+	claimed, err := t.outboxRepo.Claim(ctx, msg.ID, msg.NextAt, lease)
+	if err != nil {
+		level.Error(t.logger).Log("message", "examine claim outbox", "id", msg.ID, "err", err.Error())
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+	msg.NextAt = lease
+
+	if msg.Form != "" {
+		err = t.updateFormData(ctx, msg.Form)
+		if err == nil {
+			msg.Form = ""
+		}
+	}
+	// the run is only resumed once its form data is updated
+	if err == nil {
+		switch msg.Action {
+		case model.OutboxExec:
+			err = t.piplineRun.ExecPipelineRun(ctx, &apis.ExecPipelineRun{
+				ID: msg.RunID,
+			})
+		case model.OutboxRewind:
+			err = t.piplineRun.RewindPipelineRun(ctx, &apis.RewindPipelineRun{
+				ID:   msg.RunID,
+				Node: msg.Node,
+				From: msg.From,
+			})
+			if versioned.IsConflict(err) {
+				// delivered already, or the run moved on since: it is not rewound again
+				level.Warn(t.logger).Log("message", "examine drop outbox rewind", "run id", msg.RunID, "from", msg.From, "err", err.Error())
+				err = nil
+			}
+		default:
+			err = errors.Errorf("unknown outbox action %q", msg.Action)
+		}
+	}
+	if err != nil {
+		level.Error(t.logger).Log("message", "examine deliver outbox", "run id", msg.RunID, "action", msg.Action, "err", err.Error())
+
+		msg.Attempts++
+		msg.NextAt = ctime.NowUnix() + backoff(msg.Attempts).Milliseconds()
+		msg.LastError = err.Error()
+		if e := t.outboxRepo.UpdateAttempt(ctx, msg); e != nil {
+			level.Error(t.logger).Log("message", "examine update outbox", "id", msg.ID, "err", e.Error())
+		}
+		return err
+	}
+	level.Info(t.logger).Log("message", "examine deliver outbox ok", "run id", msg.RunID, "action", msg.Action)
+	return t.outboxRepo.Delete(ctx, msg.ID)
+}
+
+// backoff doubles the wait after each failed attempt, up to retryMaxWait.
+func backoff(attempts int64) time.Duration {
+	wait := retryBackoff
+	for i := int64(1); i < attempts && wait < retryMaxWait; i++ {
+		wait *= 2
+	}
+	if wait > retryMaxWait {
+		wait = retryMaxWait
+	}
+	return wait
+}
+
+// Relay delivers the due messages of the outbox every interval until ctx is done,
+// picking up the ones whose delivery failed or never happened after their commit.
+func (t *task) Relay(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		list, err := t.outboxRepo.ListDue(ctx, ctime.NowUnix(), relayBatch)
+		if err != nil {
+			level.Error(t.logger).Log("message", "examine list outbox", "err", err.Error())
+			continue
+		}
+		for k := range list {
+			// failures are recorded by deliver and retried on a later tick
+			_ = t.deliver(ctx, &list[k])
+		}
+	}
+}
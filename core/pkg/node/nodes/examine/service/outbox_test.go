@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/go-kit/log"
+
+	"git.yunify.com/quanxiang/workflow/pkg/client/clientset/versioned"
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+	"git.yunify.com/quanxiang/workflow/pkg/node/nodetest"
+)
+
+func TestDeliverForm(t *testing.T) {
+	qx := nodetest.NewQuanXiang()
+	outbox := &memoryOutbox{msgs: make(map[string]*model.Outbox)}
+	runs := &runs{}
+	s := &task{
+		logger:     log.NewNopLogger(),
+		qx:         qx,
+		outboxRepo: outbox,
+		piplineRun: runs,
+	}
+
+	update, err := formUpdateOf(&model.Task{AppID: "app", FormTableID: "form", FormDataID: "d1"}, &FormData{
+		Entity: map[string]interface{}{"amount": json.Number("12345678901234567890")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := s.enqueue(context.Background(), &model.Outbox{RunID: 1, Action: model.OutboxExec, Form: update})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the form data does not exist yet, the run waits for its update
+	if err := s.deliver(context.Background(), msg); err == nil {
+		t.Fatal("expect the update of missing form data to fail")
+	}
+	if len(runs.resumed) != 0 || outbox.msgs[msg.ID] == nil || outbox.msgs[msg.ID].Form == "" {
+		t.Fatalf("expect the run not resumed and the update kept, got %v, %+v", runs.resumed, outbox.msgs[msg.ID])
+	}
+
+	qx.PutFormData("app", "form", map[string]interface{}{"_id": "d1"})
+	if err := s.deliver(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if got := qx.FormData("app", "form", "d1")["amount"]; got != json.Number("12345678901234567890") {
+		t.Errorf("expect the amount kept as it was given, got %v", got)
+	}
+	if len(runs.resumed) != 1 || len(outbox.msgs) != 0 {
+		t.Errorf("expect the run resumed once and the outbox delivered, got %v, %v", runs.resumed, outbox.msgs)
+	}
+}
+
+func TestDeliverClaim(t *testing.T) {
+	outbox := &memoryOutbox{msgs: make(map[string]*model.Outbox)}
+	runs := &runs{rewindErr: errors.New("core unavailable")}
+	s := &task{
+		logger:     log.NewNopLogger(),
+		outboxRepo: outbox,
+		piplineRun: runs,
+	}
+
+	msg, err := s.enqueue(context.Background(), &model.Outbox{RunID: 1, Action: model.OutboxRewind, Node: "n1", From: "n2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Relay listed the message, due, before the delivery claimed it
+	msg.NextAt = 1
+	listed := *msg
+	if err := s.deliver(context.Background(), msg); err == nil {
+		t.Fatal("expect the delivery to fail")
+	}
+	if err := s.deliver(context.Background(), &listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(runs.rewound) != 1 || outbox.msgs[msg.ID] == nil {
+		t.Fatalf("expect a single delivery and the message kept, got %v, %+v", runs.rewound, outbox.msgs)
+	}
+
+	// the run moved on from n2 meanwhile, the rewind is dropped
+	runs.rewindErr = versioned.ErrConflict
+	if err := s.deliver(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if len(runs.rewound) != 2 || len(outbox.msgs) != 0 {
+		t.Errorf("expect the conflicting rewind dropped, got %v, %+v", runs.rewound, outbox.msgs)
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+	"git.yunify.com/quanxiang/workflow/pkg/thirdparty/quanxiang"
+)
+
+// fieldPermission is the permission of one form field on an examine node, as configured by
+// the fieldPermission of the node in the flow.
+type fieldPermission struct {
+	XInternal struct {
+		Permission int `json:"permission"`
+	} `json:"x-internal"`
+}
+
+// permissionWrite is the bit of fieldPermission.XInternal.Permission allowing to edit the field.
+const permissionWrite = 2
+
+// Editable reports whether the approver may write the field.
+func (p fieldPermission) Editable() bool {
+	return p.XInternal.Permission&permissionWrite != 0
+}
+
+// refusedFields returns the fields of form, sorted, that permissions, the JSON config of a node
+// keyed by field, does not allow to write. Fields missing from the config are not writable,
+// an empty config leaves every field writable.
+func refusedFields(permissions string, form *FormData) ([]string, error) {
+	if permissions == "" || form == nil {
+		return nil, nil
+	}
+	fields := make(map[string]fieldPermission)
+	if err := json.Unmarshal([]byte(permissions), &fields); err != nil {
+		return nil, err
+	}
+
+	refused := make(map[string]struct{})
+	for field := range form.Entity {
+		if !fields[field].Editable() {
+			refused[field] = struct{}{}
+		}
+	}
+	for field := range form.Ref {
+		if !fields[field].Editable() {
+			refused[field] = struct{}{}
+		}
+	}
+
+	list := make([]string, 0, len(refused))
+	for field := range refused {
+		list = append(list, field)
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+// writable returns form without the fields the node of task does not allow to write but which
+// keep their current value, and refuses it if it changes any of them.
+func (t *task) writable(ctx context.Context, task *model.Task, form *FormData) (*FormData, error) {
+	refused, err := refusedFields(task.FieldPermission, form)
+	if err != nil || len(refused) == 0 {
+		return form, err
+	}
+	current, err := t.qx.GetFormData(ctx, &quanxiang.GetFormDataRequest{
+		AppID:  task.AppID,
+		FormID: task.FormTableID,
+		DataID: task.FormDataID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entity := make(map[string]interface{}, len(form.Entity))
+	for k, v := range form.Entity {
+		entity[k] = v
+	}
+	changed := make([]string, 0, len(refused))
+	for _, field := range refused {
+		v, ok := form.Entity[field]
+		if ok && current != nil && reflect.DeepEqual(current.Entity[field], v) {
+			delete(entity, field)
+			continue
+		}
+		changed = append(changed, field)
+	}
+	if len(changed) != 0 {
+		return nil, fieldsRefused(changed)
+	}
+	return &FormData{
+		Entity: entity,
+		Ref:    form.Ref,
+	}, nil
+}
+
+// fieldsRefused is returned when the form data of an action writes fields the node does not allow.
+func fieldsRefused(fields []string) error {
+	return forbidden("无权修改字段：" + strings.Join(fields, ","))
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/log"
+
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+)
+
+func TestRecall(t *testing.T) {
+	tasks := &memoryTasks{
+		tasks: []model.Task{
+			{ID: "e1", TaskID: "1", NodeDefKey: "n1", UserID: "u1", CreatedBy: "c1", ExamineType: ExamineAll, Result: ResultAgree, NodeResult: string(v1alpha1.Finish)},
+			{ID: "e2", TaskID: "1", NodeDefKey: "n1", UserID: "u2", CreatedBy: "c1", ExamineType: ExamineAll, NodeResult: string(v1alpha1.Pending)},
+		},
+	}
+	actions := &memoryActions{}
+	outbox := &memoryOutbox{msgs: make(map[string]*model.Outbox)}
+	runs := &runs{}
+	s := &task{
+		logger:     log.NewNopLogger(),
+		taskRepo:   tasks,
+		actionRepo: actions,
+		outboxRepo: outbox,
+		piplineRun: runs,
+	}
+
+	if _, err := s.Recall(context.Background(), &RecallRequest{TaskID: "1", UserID: "u1"}); err == nil {
+		t.Fatal("expect an approver not to recall the run")
+	}
+	if _, err := s.Recall(context.Background(), &RecallRequest{TaskID: "1", UserID: "c1"}); err != nil {
+		t.Fatal(err)
+	}
+	if tasks.tasks[0].Result != ResultAgree || tasks.tasks[1].Result != ResultRecall || tasks.tasks[1].NodeResult != string(v1alpha1.Finish) {
+		t.Errorf("expect the pending task only to be recalled, got %+v", tasks.tasks)
+	}
+	if len(actions.actions) != 1 || actions.actions[0].ExamineID != "e2" || actions.actions[0].Action != ResultRecall {
+		t.Errorf("unexpected actions %+v", actions.actions)
+	}
+	// the run is resumed, its node sees the recall
+	if len(runs.resumed) != 1 || runs.resumed[0] != 1 || len(outbox.msgs) != 0 {
+		t.Errorf("expect run 1 resumed once and the outbox delivered, got %v, %v", runs.resumed, outbox.msgs)
+	}
+
+	// the node ends rejected, without returning the recalled run to its initiator
+	res, err := s.do(context.Background(), &DoRequest{
+		TaskID:     "1",
+		NodeDefKey: "n1",
+		TaskType:   ExamineAll,
+		UserID:     []string{"u1", "u2"},
+		CreatedBy:  "c1",
+		Resubmit:   ResubmitNode,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.NodeType != string(v1alpha1.Finish) || res.Result != "false" {
+		t.Errorf("expect the node finished rejected, got %+v", res)
+	}
+	if len(tasks.inserted) != 0 {
+		t.Errorf("expect no resubmit, got %+v", tasks.inserted[0])
+	}
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"git.yunify.com/quanxiang/workflow/pkg/thirdparty/quanxiang"
+)
+
+// Resolution is what the resolvers find the approvers of an examine node from.
+type Resolution struct {
+	QX quanxiang.QuanXiang
+	// Entity is the form data of the run.
+	Entity map[string]interface{}
+	// Initiator is the creator of the form data, empty when unknown.
+	Initiator string
+	// CreatedBy is the created_by param of the node, the approver when nobody else is found.
+	CreatedBy string
+	// Params holds the params of the node by key, among them the flow variables it references.
+	Params map[string]string
+}
+
+// Resolver resolves the approvers of one entry of dealUsers, written name.arg or name.
+type Resolver interface {
+	Resolve(ctx context.Context, r *Resolution, arg string) ([]string, error)
+}
+
+// ResolverFunc is a function used as a Resolver.
+type ResolverFunc func(ctx context.Context, r *Resolution, arg string) ([]string, error)
+
+func (f ResolverFunc) Resolve(ctx context.Context, r *Resolution, arg string) ([]string, error) {
+	return f(ctx, r, arg)
+}
+
+var resolvers = make(map[string]Resolver)
+
+// RegisterResolver makes r resolve the entries of dealUsers named name,
+// replacing the resolver registered before under it. It is not safe for concurrent use
+// and is meant to be called from init.
+func RegisterResolver(name string, r Resolver) {
+	resolvers[name] = r
+}
+
+func init() {
+	RegisterResolver("person", ResolverFunc(resolvePerson))
+	RegisterResolver("field", ResolverFunc(resolveField))
+	RegisterResolver("formApplyUserID", ResolverFunc(resolveInitiator))
+	RegisterResolver("leader", ResolverFunc(resolveLeader))
+	RegisterResolver("role", ResolverFunc(resolveRole))
+	RegisterResolver("dep", ResolverFunc(resolveDep))
+	RegisterResolver("depLeader", ResolverFunc(resolveDepLeader))
+	RegisterResolver("fieldDepLeader", ResolverFunc(resolveFieldDepLeader))
+	RegisterResolver("variable", ResolverFunc(resolveVariable))
+}
+
+// resolve returns the approvers of dealUsers, a comma separated list of entries,
+// in order. Entries no resolver is registered for are skipped.
+func resolve(ctx context.Context, r *Resolution, dealUsers string) ([]string, []string, error) {
+	ids := make([]string, 0)
+	unknown := make([]string, 0)
+	for _, entry := range strings.Split(dealUsers, ",") {
+		name, arg := entry, ""
+		if i := strings.Index(entry, "."); i >= 0 {
+			name, arg = entry[:i], entry[i+1:]
+		}
+		resolver, ok := resolvers[name]
+		if !ok {
+			unknown = append(unknown, entry)
+			continue
+		}
+		list, err := resolver.Resolve(ctx, r, arg)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "resolve %s", entry)
+		}
+		for _, id := range list {
+			if id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, unknown, nil
+}
+
+// resolvePerson resolves person.userID.
+func resolvePerson(ctx context.Context, r *Resolution, arg string) ([]string, error) {
+	return []string{arg}, nil
+}
+
+// resolveField resolves field.x to the users picked in the user field x of the form.
+func resolveField(ctx context.Context, r *Resolution, arg string) ([]string, error) {
+	if arg == "" {
+		return []string{r.CreatedBy}, nil
+	}
+	return fieldValues(r.Entity, arg), nil
+}
+
+// resolveInitiator resolves formApplyUserID to the creator of the form data.
+func resolveInitiator(ctx context.Context, r *Resolution, arg string) ([]string, error) {
+	if r.Initiator == "" {
+		return []string{r.CreatedBy}, nil
+	}
+	userInfo, err := r.QX.GetUserInfo(ctx, &quanxiang.GetUsersInfoRequest{
+		ID: r.Initiator,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if userInfo == nil || userInfo.Data == nil {
+		return []string{r.CreatedBy}, nil
+	}
+	return []string{userInfo.Data.ID}, nil
+}
+
+// resolveLeader resolves leader to the direct leader of the initiator, and leader.n to
+// its leaders up to the nth level, from the closest one. The chain stops at the first user
+// without a leader; the initiator is the approver when it has none.
+func resolveLeader(ctx context.Context, r *Resolution, arg string) ([]string, error) {
+	levels := 1
+	if arg != "" {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 {
+			return nil, errors.Errorf("invalid leader level %q", arg)
+		}
+		levels = n
+	}
+	if r.Initiator == "" {
+		return []string{r.CreatedBy}, nil
+	}
+
+	ids := make([]string, 0, levels)
+	seen := map[string]struct{}{r.Initiator: {}}
+	userID := r.Initiator
+	for len(ids) < levels {
+		userInfo, err := r.QX.GetUserInfo(ctx, &quanxiang.GetUsersInfoRequest{
+			ID: userID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if userInfo == nil || userInfo.Data == nil || len(userInfo.Data.Leader) == 0 || len(userInfo.Data.Leader[0]) == 0 {
+			break
+		}
+		userID = userInfo.Data.Leader[0][0].ID
+		if _, ok := seen[userID]; ok || userID == "" {
+			break
+		}
+		seen[userID] = struct{}{}
+		ids = append(ids, userID)
+	}
+	if len(ids) == 0 {
+		return []string{r.CreatedBy}, nil
+	}
+	return ids, nil
+}
+
+// resolveRole resolves role.roleID to the members of the role.
+func resolveRole(ctx context.Context, r *Resolution, arg string) ([]string, error) {
+	resp, err := r.QX.GetRoleUsers(ctx, &quanxiang.GetRoleUsersRequest{
+		RoleID: arg,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return userIDs(resp), nil
+}
+
+// resolveDep resolves dep.depID to the members of the department.
+func resolveDep(ctx context.Context, r *Resolution, arg string) ([]string, error) {
+	resp, err := r.QX.GetDepUsers(ctx, &quanxiang.GetDepUsersRequest{
+		DepID: arg,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return userIDs(resp), nil
+}
+
+// resolveDepLeader resolves depLeader.depID to the heads of the department.
+func resolveDepLeader(ctx context.Context, r *Resolution, arg string) ([]string, error) {
+	resp, err := r.QX.GetDepUsers(ctx, &quanxiang.GetDepUsersRequest{
+		DepID:    arg,
+		IsLeader: 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return userIDs(resp), nil
+}
+
+// resolveFieldDepLeader resolves fieldDepLeader.x to the heads of the departments
+// picked in the department field x of the form.
+func resolveFieldDepLeader(ctx context.Context, r *Resolution, arg string) ([]string, error) {
+	ids := make([]string, 0)
+	for _, depID := range fieldValues(r.Entity, arg) {
+		list, err := resolveDepLeader(ctx, r, depID)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, list...)
+	}
+	return ids, nil
+}
+
+// resolveVariable resolves variable.code to the comma separated user ids in the flow variable code.
+func resolveVariable(ctx context.Context, r *Resolution, arg string) ([]string, error) {
+	ids := make([]string, 0)
+	for _, id := range strings.Split(r.Params[arg], ",") {
+		ids = append(ids, strings.TrimSpace(id))
+	}
+	return ids, nil
+}
+
+// fieldValues returns the values of the options picked in the user or department field of entity.
+func fieldValues(entity map[string]interface{}, field string) []string {
+	values := make([]string, 0)
+	options, _ := entity[field].([]interface{})
+	for k := range options {
+		if option, ok := options[k].(map[string]interface{}); option != nil && ok {
+			if value, ok := option["value"].(string); value != "" && ok {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+func userIDs(resp *quanxiang.GetUsersResponse) []string {
+	if resp == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(resp.Data))
+	for _, user := range resp.Data {
+		if user != nil {
+			ids = append(ids, user.ID)
+		}
+	}
+	return ids
+}
+
+// dedupe returns ids without the repeated ones, in order.
+func dedupe(ids []string) []string {
+	seen := make(map[string]struct{}, len(ids))
+	list := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		list = append(list, id)
+	}
+	return list
+}
+
+// without returns ids without userID.
+func without(ids []string, userID string) []string {
+	list := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id != userID {
+			list = append(list, id)
+		}
+	}
+	return list
+}
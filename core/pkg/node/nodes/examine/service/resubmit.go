@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+)
+
+// Values of the resubmit param of a node, what happens once it is rejected.
+// When empty the node finishes as rejected and the run goes on.
+const (
+	// ResubmitNode returns the run to the initiator, it restarts from the node once resubmitted.
+	ResubmitNode = "node"
+	// ResubmitStart returns the run to the initiator, it restarts from its first node once resubmitted.
+	ResubmitStart = "start"
+)
+
+const (
+	// ExamineResubmit is the type of the task of the initiator to resubmit a run rejected by
+	// a node configured with ResubmitNode, ExamineResubmitStart with ResubmitStart.
+	ExamineResubmit      = "resubmit"
+	ExamineResubmitStart = "resubmitStart"
+	// ResultResubmit marks the task of the initiator who resubmitted the run.
+	ResultResubmit = "resubmit"
+)
+
+// resubmitting reports whether task is the task of the initiator to resubmit its run.
+func resubmitting(task *model.Task) bool {
+	return task.ExamineType == ExamineResubmit || task.ExamineType == ExamineResubmitStart
+}
+
+// returnToInitiator pauses the run of the node of req, rejected, until its initiator resubmits it:
+// the initiator gets a task of the node to do so.
+func (t *task) returnToInitiator(ctx context.Context, req *DoRequest) error {
+	data := resubmitTask(req)
+	err := t.taskRepo.InsertBranch(ctx, data)
+	if err != nil {
+		return err
+	}
+	level.Info(t.logger).Log("message", "examine return to initiator", "id", req.TaskID, "nodeDefKey", req.NodeDefKey, "initiator", req.CreatedBy)
+	t.notify(t.notifyConf.Resubmit, data, data.UserID, pageApply, nil)
+	return nil
+}
+
+// resubmitTask returns the task of the initiator to resubmit the run of the node of req.
+func resubmitTask(req *DoRequest) *model.Task {
+	data := newTask(req, req.CreatedBy, "")
+	data.ExamineType = ExamineResubmit
+	if req.Resubmit == ResubmitStart {
+		data.ExamineType = ExamineResubmitStart
+	}
+	data.Quorum, data.RemindEvery, data.DueAt = "", 0, 0
+	return data
+}
+
+type ResubmitRequest struct {
+	ExamineID string
+	UserID    string
+	Remark    string
+	// ForMData is the form data edited by the initiator, nil leaves it as it is.
+	ForMData *FormData
+}
+type ResubmitResponse struct {
+}
+
+// Resubmit resumes the run returned to its initiator by the rejection of a node, once the form data
+// is updated with req.ForMData. The run restarts from the rejecting node or from its first node,
+// the rounds asked again are archived like by SendBack.
+func (t *task) Resubmit(ctx context.Context, req *ResubmitRequest) (*ResubmitResponse, error) {
+	task, err := t.taskRepo.GetByID(ctx, req.ExamineID)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil || !resubmitting(task) || !t.canHandle(task, req.UserID) {
+		return nil, forbidden("当前任务与发起人不匹配")
+	}
+	if task.NodeResult != string(v1alpha1.Pending) {
+		return nil, errors.New("流程已经重新提交或撤回")
+	}
+	runID, err := strconv.ParseInt(task.TaskID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := t.taskRepo.ListByTaskID(ctx, task.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	tasks = current(tasks)
+
+	var since int64
+	var target string
+	if task.ExamineType == ExamineResubmit {
+		since, target = roundOf(tasks, task.NodeDefKey), task.NodeDefKey
+	}
+
+	var update string
+	if form := req.ForMData; form != nil && form.Entity != nil {
+		update, err = formUpdateOf(task, form)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var msg *model.Outbox
+	err = t.taskRepo.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		// the form data is updated by deliver once committed, before the run is rewound
+		msg, err = t.rewind(ctx, runID, task, tasks, since, &model.Action{
+			Action: ResultResubmit,
+			UserID: req.UserID,
+			Remark: req.Remark,
+		}, target, update)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// a failed update or rewind is retried from the outbox by Relay
+	_ = t.deliver(ctx, msg)
+	return &ResubmitResponse{}, nil
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/pkg/errors"
+	e "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/cabin/time"
+
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+)
+
+type SendBackRequest struct {
+	ExamineID string
+	UserID    string
+	// NodeDefKey is the earlier examine node to send the run back to, empty returns it to
+	// the initiator, and the run restarts from its first node once they resubmit it.
+	NodeDefKey string
+	Remark     string
+}
+type SendBackResponse struct {
+}
+
+// SendBack returns the run of the pending task ExamineID to an earlier examine node, or to its initiator.
+// The tasks of that node and of the nodes after it are archived, so they are asked again.
+func (t *task) SendBack(ctx context.Context, req *SendBackRequest) (*SendBackResponse, error) {
+	task, err := t.taskRepo.GetByID(ctx, req.ExamineID)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil || !t.canHandle(task, req.UserID) {
+		return nil, forbidden("当前任务与审核人不匹配")
+	}
+	if task.NodeResult != string(v1alpha1.Pending) || resubmitting(task) {
+		return nil, errors.New("只有待审核的任务可以退回")
+	}
+	if req.NodeDefKey == task.NodeDefKey {
+		return nil, e.NewErrorWithString(e.ErrParams, "不能退回到当前节点")
+	}
+	if req.NodeDefKey == "" {
+		return t.sendBackToInitiator(ctx, task, req)
+	}
+	runID, err := strconv.ParseInt(task.TaskID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := t.taskRepo.ListByTaskID(ctx, task.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	tasks = current(tasks)
+
+	since := roundOf(tasks, req.NodeDefKey)
+	if since < 0 {
+		return nil, e.NewErrorWithString(e.ErrParams, "只能退回到已经审批过的节点")
+	}
+
+	var msg *model.Outbox
+	err = t.taskRepo.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		msg, err = t.rewind(ctx, runID, task, tasks, since, &model.Action{
+			Action: ResultSendBack,
+			UserID: req.UserID,
+			Remark: req.Remark,
+		}, req.NodeDefKey, "")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the rounds are archived already, a failed rewind is retried from the outbox by Relay
+	_ = t.deliver(ctx, msg)
+	return &SendBackResponse{}, nil
+}
+
+// sendBackToInitiator closes the node of task, sent back, and returns the run to its initiator like
+// a node rejected with ResubmitStart: the run waits at the node until they resubmit it.
+func (t *task) sendBackToInitiator(ctx context.Context, task *model.Task, req *SendBackRequest) (*SendBackResponse, error) {
+	data := resubmitTask(&DoRequest{
+		TaskID:     task.TaskID,
+		FlowID:     task.FlowID,
+		AppID:      task.AppID,
+		FormID:     task.FormTableID,
+		FormDataID: task.FormDataID,
+		CreatedBy:  task.CreatedBy,
+		NodeDefKey: task.NodeDefKey,
+		TaskType:   task.ExamineType,
+		Resubmit:   ResubmitStart,
+	})
+	err := t.taskRepo.Transaction(ctx, func(ctx context.Context) error {
+		now := time.NowUnix()
+		task.Result, task.Remark = ResultSendBack, req.Remark
+		task.NodeResult, task.UpdatedAt = string(v1alpha1.Finish), now
+		err := t.taskRepo.UpdateResult(ctx, task)
+		if err != nil {
+			return err
+		}
+		err = t.taskRepo.UpdateByTaskIDAndNodeDefKey(ctx, &model.Task{
+			TaskID:     task.TaskID,
+			NodeDefKey: task.NodeDefKey,
+			NodeResult: string(v1alpha1.Finish),
+			UpdatedAt:  now,
+		})
+		if err != nil {
+			return err
+		}
+		err = t.taskRepo.InsertBranch(ctx, data)
+		if err != nil {
+			return err
+		}
+		record := newAction(task, ResultSendBack, req.UserID)
+		record.Remark = req.Remark
+		return t.actionRepo.Create(ctx, record)
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.notify(t.notifyConf.Resubmit, data, data.UserID, pageApply, nil)
+	return &SendBackResponse{}, nil
+}
+
+// roundOf returns when the round of the node nodeDefKey started with its first task among tasks,
+// the tasks created since then belong to it or to the nodes after it. It is -1 when the node has no task.
+func roundOf(tasks []model.Task, nodeDefKey string) int64 {
+	since := int64(-1)
+	for k := range tasks {
+		if tasks[k].NodeDefKey == nodeDefKey && (since < 0 || tasks[k].CreatedAt < since) {
+			since = tasks[k].CreatedAt
+		}
+	}
+	return since
+}
+
+// rewind archives the tasks created since since, the rounds to ask again, with the result act of task
+// and records act, then enqueues the rewind of the run runID to the node target, empty for its first node,
+// after the form data update form if any, all of it within the transaction of ctx.
+func (t *task) rewind(ctx context.Context, runID int64, task *model.Task, tasks []model.Task, since int64, act *model.Action, target, form string) (*model.Outbox, error) {
+	now := time.NowUnix()
+	for k := range tasks {
+		if tasks[k].CreatedAt < since {
+			continue
+		}
+		tasks[k].NodeResult = model.NodeResultArchived
+		tasks[k].UpdatedAt = now
+		if tasks[k].ID == task.ID {
+			tasks[k].Result = act.Action
+			tasks[k].Remark = act.Remark
+		}
+		err := t.taskRepo.UpdateResult(ctx, &tasks[k])
+		if err != nil {
+			return nil, err
+		}
+	}
+	record := newAction(task, act.Action, act.UserID)
+	record.Target, record.Remark = target, act.Remark
+	err := t.actionRepo.Create(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+	// the run is rewound from the node of task only, not once it moved on
+	return t.enqueue(ctx, &model.Outbox{RunID: runID, Action: model.OutboxRewind, Node: target, From: task.NodeDefKey, Form: form})
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/log"
+
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+)
+
+func TestSendBackToInitiator(t *testing.T) {
+	tasks := &memoryTasks{
+		tasks: []model.Task{
+			{ID: "e1", TaskID: "1", NodeDefKey: "n1", UserID: "u1", CreatedBy: "c1", ExamineType: ExamineAll, NodeResult: string(v1alpha1.Pending)},
+			{ID: "e2", TaskID: "1", NodeDefKey: "n1", UserID: "u2", CreatedBy: "c1", ExamineType: ExamineAll, NodeResult: string(v1alpha1.Pending)},
+		},
+	}
+	actions := &memoryActions{}
+	outbox := &memoryOutbox{msgs: make(map[string]*model.Outbox)}
+	runs := &runs{}
+	s := &task{
+		logger:     log.NewNopLogger(),
+		taskRepo:   tasks,
+		actionRepo: actions,
+		outboxRepo: outbox,
+		piplineRun: runs,
+	}
+
+	if _, err := s.SendBack(context.Background(), &SendBackRequest{ExamineID: "e1", UserID: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+	if tasks.tasks[0].Result != ResultSendBack || tasks.tasks[0].NodeResult != string(v1alpha1.Finish) ||
+		tasks.tasks[1].NodeResult != string(v1alpha1.Finish) {
+		t.Errorf("expect the node closed, got %+v", tasks.tasks)
+	}
+	if len(tasks.inserted) != 1 || tasks.inserted[0].UserID != "c1" || tasks.inserted[0].ExamineType != ExamineResubmitStart {
+		t.Fatalf("expect the initiator to get a task to resubmit, got %+v", tasks.inserted)
+	}
+	// the run waits for the initiator, it is not rewound
+	if len(runs.resumed) != 0 || len(outbox.msgs) != 0 {
+		t.Errorf("expect the run to wait, got %v, %v", runs.resumed, outbox.msgs)
+	}
+
+	res, err := s.do(context.Background(), &DoRequest{TaskID: "1", NodeDefKey: "n1", TaskType: ExamineAll, UserID: []string{"u1", "u2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.NodeType != string(v1alpha1.Pending) {
+		t.Errorf("expect the node pending until resubmitted, got %+v", res)
+	}
+}
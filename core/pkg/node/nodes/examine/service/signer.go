@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	e "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/cabin/id"
+	"github.com/quanxiang-cloud/cabin/time"
+
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+)
+
+const (
+	// SignBefore asks the added signers before the approver, who waits for them.
+	SignBefore = "before"
+	// SignAfter asks the added signers once the approver agreed.
+	SignAfter = "after"
+)
+
+type AddSignerRequest struct {
+	ExamineID string
+	UserID    string
+	Users     []string
+	Position  string // before | after
+}
+type AddSignerResponse struct {
+}
+
+// AddSigner adds Users as signers of the pending task ExamineID of UserID.
+// Signers get tasks of the same node and all of them have to agree for the node to be agreed.
+func (t *task) AddSigner(ctx context.Context, req *AddSignerRequest) (*AddSignerResponse, error) {
+	if len(req.Users) == 0 {
+		return nil, e.NewErrorWithString(e.ErrParams, "加签人不能为空")
+	}
+	if req.Position != SignBefore && req.Position != SignAfter {
+		return nil, e.NewErrorWithString(e.ErrParams, "加签方式只能为 before 或 after")
+	}
+	task, err := t.taskRepo.GetByID(ctx, req.ExamineID)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil || !t.canHandle(task, req.UserID) {
+		return nil, forbidden("当前任务与审核人不匹配")
+	}
+	if task.NodeResult != string(v1alpha1.Pending) || resubmitting(task) {
+		return nil, errors.New("只有待审核的任务可以加签")
+	}
+
+	tasks, err := t.taskRepo.ListByTaskIDAndNodeDefKey(ctx, task.TaskID, task.NodeDefKey)
+	if err != nil {
+		return nil, err
+	}
+	busy := make(map[string]struct{}, len(tasks))
+	for _, other := range current(tasks) {
+		if other.NodeResult != string(v1alpha1.Finish) {
+			busy[other.UserID] = struct{}{}
+		}
+	}
+
+	now := time.NowUnix()
+	signers := make([]*model.Task, 0, len(req.Users))
+	for _, userID := range req.Users {
+		if _, ok := busy[userID]; ok || userID == "" {
+			return nil, e.NewErrorWithString(e.ErrParams, "加签人 "+userID+" 已是当前节点的审核人")
+		}
+		busy[userID] = struct{}{}
+
+		signer := *task
+		signer.ID = id.BaseUUID()
+		signer.UserID = userID
+		signer.SignerOf = task.ID
+		signer.Substitute = ""
+		signer.DelegatedFrom = ""
+		signer.Remark = ""
+		signer.UrgeTimes = 0
+		signer.CreatedAt = now
+		signer.UpdatedAt = 0
+		signer.RemindedAt = 0
+		if req.Position == SignAfter {
+			signer.NodeResult = model.NodeResultWaiting
+		}
+		signers = append(signers, &signer)
+	}
+	err = t.taskRepo.Transaction(ctx, func(ctx context.Context) error {
+		err := t.taskRepo.InsertBranch(ctx, signers...)
+		if err != nil {
+			return err
+		}
+		record := newAction(task, ActionAddSigner, req.UserID)
+		record.Target = strings.Join(req.Users, ",")
+		err = t.actionRepo.Create(ctx, record)
+		if err != nil || req.Position != SignBefore {
+			return err
+		}
+		task.NodeResult = model.NodeResultWaiting
+		task.UpdatedAt = now
+		return t.taskRepo.UpdateResult(ctx, task)
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.notifyPending(signers...)
+	return &AddSignerResponse{}, nil
+}
+
+// releaseSigners gives the signers added after task their turn if it agreed,
+// otherwise they are finished without one. It reports whether signers were released.
+func (t *task) releaseSigners(ctx context.Context, task *model.Task, tasks []model.Task) (bool, error) {
+	next := string(v1alpha1.Finish)
+	if task.Result == ResultAgree {
+		next = string(v1alpha1.Pending)
+	}
+
+	var released bool
+	for k := range tasks {
+		if tasks[k].SignerOf != task.ID || tasks[k].NodeResult != model.NodeResultWaiting {
+			continue
+		}
+		tasks[k].NodeResult = next
+		tasks[k].UpdatedAt = time.NowUnix()
+		err := t.taskRepo.UpdateResult(ctx, &tasks[k])
+		if err != nil {
+			return false, err
+		}
+		released = true
+	}
+	return released && task.Result == ResultAgree, nil
+}
+
+// signed moves the node on once the signer task acted. A refusing signer refuses the node,
+// and the task the signers were added before gets its turn once all of them agreed.
+func (t *task) signed(ctx context.Context, task *model.Task, tasks []model.Task) error {
+	if task.Result != ResultAgree {
+		return t.taskRepo.UpdateByTaskIDAndNodeDefKey(ctx, &model.Task{
+			TaskID:     task.TaskID,
+			NodeDefKey: task.NodeDefKey,
+			NodeResult: string(v1alpha1.Finish),
+			UpdatedAt:  time.NowUnix(),
+		})
+	}
+
+	var parent *model.Task
+	for k := range tasks {
+		switch {
+		case tasks[k].ID == task.SignerOf:
+			parent = &tasks[k]
+		case tasks[k].SignerOf == task.SignerOf && tasks[k].NodeResult != string(v1alpha1.Finish):
+			// the other signers are still signing
+			return nil
+		}
+	}
+	if parent != nil && parent.NodeResult == model.NodeResultWaiting {
+		parent.NodeResult = string(v1alpha1.Pending)
+		parent.UpdatedAt = time.NowUnix()
+		err := t.taskRepo.UpdateResult(ctx, parent)
+		if err != nil {
+			return err
+		}
+	}
+	if task.ExamineType == ExamineQuorum {
+		return t.closeQuorum(ctx, task)
+	}
+	return nil
+}
+
+// notifySigners tells the signers added after task, released once it agreed, about their tasks.
+func (t *task) notifySigners(ctx context.Context, task *model.Task) {
+	tasks, err := t.taskRepo.ListByTaskIDAndNodeDefKey(ctx, task.TaskID, task.NodeDefKey)
+	if err != nil {
+		level.Error(t.logger).Log("message", "examine list signers", "task", task.ID, "err", err.Error())
+		return
+	}
+	for k := range tasks {
+		if tasks[k].SignerOf == task.ID {
+			t.notifyPending(&tasks[k])
+		}
+	}
+}
+
+// finish finishes the tasks matching match without a result.
+func (t *task) finish(ctx context.Context, tasks []model.Task, match func(*model.Task) bool) error {
+	for k := range tasks {
+		if !match(&tasks[k]) {
+			continue
+		}
+		tasks[k].NodeResult = string(v1alpha1.Finish)
+		tasks[k].UpdatedAt = time.NowUnix()
+		err := t.taskRepo.UpdateResult(ctx, &tasks[k])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	e "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/cabin/time"
+
+	model "git.yunify.com/quanxiang/workflow/pkg/node/nodes/examine/db"
+)
+
+// Orders of StatsRequest, all of them descending.
+const (
+	OrderOverdue     = "overdue"
+	OrderPending     = "pending"
+	OrderUrged       = "urged"
+	OrderLate        = "late"
+	OrderAvgHandling = "avgHandling"
+)
+
+type StatsRequest struct {
+	// GroupBy is model.StatsByUser, model.StatsByApp or model.StatsByFlow, by user when empty.
+	GroupBy string
+	// CallerID counts the tasks of its own only, unless an admin.
+	CallerID    string `json:"-"`
+	UserID      string
+	AppID       string
+	FlowID      string
+	CreatedFrom int64
+	CreatedTo   int64
+	// OrderBy is one of the orders, OrderOverdue when empty.
+	OrderBy string
+	// Limit keeps the first stats only, 0 keeps all of them.
+	Limit int
+}
+
+type StatsResponse struct {
+	Stats []model.TaskStats `json:"stats"`
+}
+
+var statsOrders = map[string]func(model.TaskStats) int64{
+	OrderOverdue:     func(s model.TaskStats) int64 { return s.Overdue },
+	OrderPending:     func(s model.TaskStats) int64 { return s.Pending },
+	OrderUrged:       func(s model.TaskStats) int64 { return s.Urged },
+	OrderLate:        func(s model.TaskStats) int64 { return s.Late },
+	OrderAvgHandling: func(s model.TaskStats) int64 { return s.AvgHandling },
+}
+
+// Stats returns the statistics of the tasks per approver, app or flow. Grouped by user and ordered
+// by overdue, the default, its first ones are the approvers holding the runs back the most.
+func (t *task) Stats(ctx context.Context, req *StatsRequest) (*StatsResponse, error) {
+	groupBy := req.GroupBy
+	if groupBy == "" {
+		groupBy = model.StatsByUser
+	}
+	if groupBy != model.StatsByUser && groupBy != model.StatsByApp && groupBy != model.StatsByFlow {
+		return nil, e.NewErrorWithString(e.ErrParams, "groupBy must be user, app or flow")
+	}
+	orderBy := req.OrderBy
+	if orderBy == "" {
+		orderBy = OrderOverdue
+	}
+	metric, ok := statsOrders[orderBy]
+	if !ok {
+		return nil, e.NewErrorWithString(e.ErrParams, "unknown orderBy "+orderBy)
+	}
+
+	userID, err := t.filterUser(req.CallerID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := t.taskRepo.Stats(ctx, &model.TaskFilter{
+		UserID:      userID,
+		AppID:       req.AppID,
+		FlowID:      req.FlowID,
+		CreatedFrom: req.CreatedFrom,
+		CreatedTo:   req.CreatedTo,
+	}, groupBy, time.NowUnix())
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		if a, b := metric(stats[i]), metric(stats[j]); a != b {
+			return a > b
+		}
+		if stats[i].Pending != stats[j].Pending {
+			return stats[i].Pending > stats[j].Pending
+		}
+		return stats[i].Key < stats[j].Key
+	})
+	if req.Limit > 0 && len(stats) > req.Limit {
+		stats = stats[:req.Limit]
+	}
+	return &StatsResponse{
+		Stats: stats,
+	}, nil
+}
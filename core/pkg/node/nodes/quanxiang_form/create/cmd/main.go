@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	wl "git.yunify.com/quanxiang/workflow/pkg/log"
+	"git.yunify.com/quanxiang/workflow/pkg/node"
+	"git.yunify.com/quanxiang/workflow/pkg/node/nodes/quanxiang_form/create"
+	"github.com/kelseyhightower/envconfig"
+)
+
+type config struct {
+	LogLevel           string   `envconfig:"LOG_LEVEL" default:"debug"`
+	QuanxiangInstances []string `envconfig:"QUANXIANG_INSTANCES"`
+	Port               string   `envconfig:"PORT" default:"8085"`
+
+	node.Config
+}
+
+func main() {
+	conf := &config{}
+	envconfig.MustProcess("", conf)
+
+	logger := wl.NewLogger(conf.LogLevel)
+	s := create.New(conf.QuanxiangInstances, logger)
+	node.MainWithConfig(logger, fmt.Sprintf(":%s", conf.Port), conf.Config)(context.Background(), s)
+}
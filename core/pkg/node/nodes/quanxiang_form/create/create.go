@@ -1,20 +1,17 @@
-package main
+package create
 
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
 	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
 	"git.yunify.com/quanxiang/workflow/pkg/helper/errors"
-	wl "git.yunify.com/quanxiang/workflow/pkg/log"
 	"git.yunify.com/quanxiang/workflow/pkg/node"
 	"git.yunify.com/quanxiang/workflow/pkg/node/nodes"
 	quanxiangform "git.yunify.com/quanxiang/workflow/pkg/node/nodes/quanxiang_form"
 	"git.yunify.com/quanxiang/workflow/pkg/thirdparty/quanxiang"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
-	"github.com/kelseyhightower/envconfig"
 )
 
 type From struct {
@@ -119,21 +116,29 @@ func genRule(in []*v1alpha1.KeyAndValue) (*rule, error) {
 	return rule, nil
 }
 
-type config struct {
-	LogLevel           string   `envconfig:"LOG_LEVEL" default:"debug"`
-	QuanxiangInstances []string `envconfig:"QUANXIANG_INSTANCES"`
-	Port               string   `envconfig:"PORT" default:"8085"`
-}
+// Type is the type of the node.
+const Type = "form-create-data"
 
-func main() {
-	conf := &config{}
-	envconfig.MustProcess("", conf)
+// Config is the config of the node when it is local to the core, the node service takes it from
+// the environment.
+type Config struct {
+	QuanxiangInstances []string `yaml:"quanxiang_instances"`
+}
 
-	logger := wl.NewLogger(conf.LogLevel)
-	s := &From{
+// New returns the node, calling quanxiang at instances.
+func New(instances []string, logger log.Logger) *From {
+	return &From{
 		logger: logger,
+		qx:     quanxiang.New(instances, logger),
 	}
-	ctx := context.Background()
-	s.qx = quanxiang.New(conf.QuanxiangInstances, logger)
-	node.Main(logger, fmt.Sprintf(":%s", conf.Port))(ctx, s)
+}
+
+func init() {
+	node.Register(Type, func(_ context.Context, logger log.Logger, decode func(interface{}) error) (node.Interface, error) {
+		conf := &Config{}
+		if err := decode(conf); err != nil {
+			return nil, err
+		}
+		return New(conf.QuanxiangInstances, logger), nil
+	})
 }
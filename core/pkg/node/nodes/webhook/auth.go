@@ -0,0 +1,230 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Types of Auth.
+const (
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+	AuthAPIKey = "apiKey"
+	AuthHMAC   = "hmac"
+	AuthOAuth2 = "oauth2"
+)
+
+const (
+	// signatureHeader is the header of the hmac signature when Auth.Name is empty.
+	signatureHeader = "X-Signature"
+	// tokenMargin is how long before they expire the oauth2 tokens are fetched again.
+	tokenMargin = 30 * time.Second
+)
+
+// Auth is how a webhook authenticates, with the secret named Secret of the secrets file.
+type Auth struct {
+	// Type is one of the auth types, no auth when empty.
+	Type string `json:"type"`
+	// Secret names the password of basic, the token of bearer, the key of apiKey and hmac and the
+	// client secret of oauth2.
+	Secret string `json:"secret"`
+	// Username is the user of basic.
+	Username string `json:"username"`
+	// Name is the header of the key of apiKey, or its query parameter when In is query, and the
+	// header of the signature of hmac, X-Signature when empty.
+	Name string `json:"name"`
+	In   string `json:"in"`
+	// TokenURL, ClientID and Scopes are the client credentials grant of oauth2.
+	TokenURL string   `json:"tokenURL"`
+	ClientID string   `json:"clientID"`
+	Scopes   []string `json:"scopes"`
+}
+
+// authError is an error authenticating a request, temporary when it may succeed later.
+type authError struct {
+	message   string
+	temporary bool
+}
+
+func (e *authError) Error() string {
+	return e.message
+}
+
+func invalidAuth(format string, a ...interface{}) error {
+	return &authError{
+		message: fmt.Sprintf(format, a...),
+	}
+}
+
+// authenticate sets the credentials of auth on req, of which body is the body.
+// The secrets are only put in req, never in the errors.
+func (w *WebHook) authenticate(ctx context.Context, auth *Auth, req *http.Request, body []byte) error {
+	if auth == nil || auth.Type == "" {
+		return nil
+	}
+	secret, err := w.secrets.Get(auth.Secret)
+	if err != nil {
+		return invalidAuth("auth %s: %s", auth.Type, err.Error())
+	}
+
+	switch auth.Type {
+	case AuthBasic:
+		req.SetBasicAuth(auth.Username, secret)
+	case AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+secret)
+	case AuthAPIKey:
+		if auth.Name == "" {
+			return invalidAuth("auth apiKey: name is required")
+		}
+		switch auth.In {
+		case "", "header":
+			req.Header.Set(auth.Name, secret)
+		case "query":
+			query := req.URL.Query()
+			query.Set(auth.Name, secret)
+			req.URL.RawQuery = query.Encode()
+		default:
+			return invalidAuth("auth apiKey: in must be header or query")
+		}
+	case AuthHMAC:
+		name := auth.Name
+		if name == "" {
+			name = signatureHeader
+		}
+		req.Header.Set(name, "sha256="+sign(secret, body))
+	case AuthOAuth2:
+		token, err := w.tokens.get(ctx, auth, secret)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	default:
+		return invalidAuth("unknown auth type %q", auth.Type)
+	}
+	return nil
+}
+
+// sign returns the hex of the HMAC-SHA256 of body with key.
+func sign(key string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// tokens caches the oauth2 tokens of the client credentials grant until they expire.
+type tokens struct {
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]token
+}
+
+type token struct {
+	value    string
+	expireAt time.Time
+}
+
+func newTokens(client *http.Client) *tokens {
+	return &tokens{
+		client: client,
+		cache:  make(map[string]token),
+	}
+}
+
+func tokenKey(auth *Auth) string {
+	return strings.Join([]string{auth.TokenURL, auth.ClientID, strings.Join(auth.Scopes, " ")}, "\n")
+}
+
+// get returns a token of auth, fetched with the client secret secret when none is cached.
+func (t *tokens) get(ctx context.Context, auth *Auth, secret string) (string, error) {
+	key := tokenKey(auth)
+	t.mu.Lock()
+	cached, ok := t.cache[key]
+	t.mu.Unlock()
+	if ok && time.Now().Before(cached.expireAt) {
+		return cached.value, nil
+	}
+
+	value, expiresIn, err := t.fetch(ctx, auth, secret)
+	if err != nil {
+		return "", err
+	}
+	// a token without expiry is used once
+	if expiresIn > tokenMargin {
+		t.mu.Lock()
+		t.cache[key] = token{
+			value:    value,
+			expireAt: time.Now().Add(expiresIn - tokenMargin),
+		}
+		t.mu.Unlock()
+	}
+	return value, nil
+}
+
+// drop forgets the token of auth, the webhook refused it.
+func (t *tokens) drop(auth *Auth) {
+	t.mu.Lock()
+	delete(t.cache, tokenKey(auth))
+	t.mu.Unlock()
+}
+
+func (t *tokens) fetch(ctx context.Context, auth *Auth, secret string) (string, time.Duration, error) {
+	if auth.TokenURL == "" || auth.ClientID == "" {
+		return "", 0, invalidAuth("auth oauth2: tokenURL and clientID are required")
+	}
+	form := url.Values{
+		"grant_type": {"client_credentials"},
+	}
+	if len(auth.Scopes) != 0 {
+		form.Set("scope", strings.Join(auth.Scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, auth.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, invalidAuth("auth oauth2: invalid tokenURL")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(auth.ClientID), url.QueryEscape(secret))
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", 0, &authError{
+			message:   "auth oauth2: fail fetch token: " + causeOf(err).Error(),
+			temporary: true,
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, &authError{
+			message:   fmt.Sprintf("auth oauth2: token endpoint responded with status %d", resp.StatusCode),
+			temporary: resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests,
+		}
+	}
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBody)).Decode(&body); err != nil || body.AccessToken == "" {
+		return "", 0, invalidAuth("auth oauth2: token endpoint responded without access_token")
+	}
+	return body.AccessToken, time.Duration(body.ExpiresIn) * time.Second, nil
+}
+
+// causeOf returns the cause of an error of an http.Client, without the url that may hold secrets.
+func causeOf(err error) error {
+	if ue, ok := err.(*url.Error); ok {
+		return ue.Err
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuthenticate(t *testing.T) {
+	k := make([]byte, 32)
+	if _, err := rand.Read(k); err != nil {
+		t.Fatal(err)
+	}
+	key := base64.StdEncoding.EncodeToString(k)
+	aead, err := newAEAD(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := seal(aead, "token", "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "secrets.yml")
+	// the value of token sealed as other may not be opened as other
+	if err := os.WriteFile(path, []byte("token: "+token+"\nother: "+token+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := newSecrets(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &WebHook{secrets: s}
+
+	tests := []struct {
+		auth   Auth
+		header string
+		want   string
+		query  string
+		err    bool
+	}{
+		{auth: Auth{Type: AuthBearer, Secret: "token"}, header: "Authorization", want: "Bearer s3cret"},
+		{auth: Auth{Type: AuthAPIKey, Secret: "token", Name: "X-Api-Key"}, header: "X-Api-Key", want: "s3cret"},
+		{auth: Auth{Type: AuthAPIKey, Secret: "token", Name: "key", In: "query"}, query: "s3cret"},
+		{auth: Auth{Type: AuthHMAC, Secret: "token"}, header: signatureHeader, want: "sha256=" + sign("s3cret", []byte(`{}`))},
+		{auth: Auth{Type: AuthBearer, Secret: "other"}, err: true},
+		{auth: Auth{Type: AuthBearer, Secret: "missing"}, err: true},
+		{auth: Auth{Type: "digest", Secret: "token"}, err: true},
+	}
+	for _, tt := range tests {
+		req := &http.Request{
+			URL:    &url.URL{Scheme: "https", Host: "example.com"},
+			Header: make(http.Header),
+		}
+		err := w.authenticate(context.Background(), &tt.auth, req, []byte(`{}`))
+		if tt.err {
+			if err == nil || strings.Contains(err.Error(), "s3cret") {
+				t.Errorf("%s %s: err = %v", tt.auth.Type, tt.auth.Secret, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.auth.Type, err)
+		}
+		if tt.header != "" && req.Header.Get(tt.header) != tt.want {
+			t.Errorf("%s: %s = %q, want %q", tt.auth.Type, tt.header, req.Header.Get(tt.header), tt.want)
+		}
+		if tt.query != "" && req.URL.Query().Get(tt.auth.Name) != tt.query {
+			t.Errorf("%s: query = %q", tt.auth.Type, req.URL.RawQuery)
+		}
+	}
+}
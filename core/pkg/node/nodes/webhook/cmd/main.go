@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	wl "git.yunify.com/quanxiang/workflow/pkg/log"
+	"git.yunify.com/quanxiang/workflow/pkg/node"
+	"git.yunify.com/quanxiang/workflow/pkg/node/nodes/webhook"
+	"github.com/kelseyhightower/envconfig"
+)
+
+type config struct {
+	LogLevel string `envconfig:"LOG_LEVEL" default:"debug"`
+	Port     string `envconfig:"PORT" default:"80"`
+
+	webhook.ServiceConfig
+	node.Config
+}
+
+func main() {
+	conf := &config{}
+	envconfig.MustProcess("", conf)
+
+	// seal <name> prints the value read from stdin sealed as the secret name of the secrets file
+	if len(os.Args) == 3 && os.Args[1] == "seal" {
+		if err := webhook.SealSecret(conf.SecretsKey, os.Args[2]); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	logger := wl.NewLogger(conf.LogLevel)
+	s, err := webhook.New(&conf.ServiceConfig, logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	node.MainWithConfig(logger, fmt.Sprintf(":%s", conf.Port), conf.Config)(context.Background(), s)
+}
@@ -0,0 +1,218 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
+)
+
+// Roots of the path of an output.
+const (
+	pathStatus = "status"
+	pathHeader = "header"
+	pathBody   = "body"
+)
+
+// response is what the outputs of a webhook are taken from.
+type response struct {
+	status int
+	header http.Header
+	// body is the decoded JSON body, its numbers as json.Number, or the body as a string when it is not JSON.
+	body interface{}
+}
+
+func newResponse(status int, header http.Header, raw []byte) *response {
+	r := &response{
+		status: status,
+		header: header,
+	}
+	if len(raw) == 0 {
+		return r
+	}
+	// the numbers are kept as they are, the ids larger than 2^53 would not survive a float64
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var body interface{}
+	if err := dec.Decode(&body); err != nil || dec.Decode(&struct{}{}) != io.EOF {
+		r.body = string(raw)
+		return r
+	}
+	r.body = body
+	return r
+}
+
+// output is an output of Config flattened: the objects of the response schema are
+// replaced by their fields, named parent.field.
+type output struct {
+	name     string
+	path     string
+	communal string
+}
+
+// flatten returns the outputs of outputs, their names prefixed by prefix.
+func flatten(prefix string, outputs []Outputs) []output {
+	list := make([]output, 0, len(outputs))
+	for _, o := range outputs {
+		if o.Name == "" {
+			continue
+		}
+		name := prefix + o.Name
+		if o.Type == "object" && o.Path == "" {
+			if fields := fieldsOf(o.Data); len(fields) != 0 {
+				list = append(list, flatten(name+".", fields)...)
+				continue
+			}
+		}
+		path := o.Path
+		if path == "" {
+			path = pathBody + "." + name
+		}
+		list = append(list, output{
+			name:     name,
+			path:     path,
+			communal: o.Communal,
+		})
+	}
+	return list
+}
+
+// fieldsOf returns the fields of an object output, given as its data.
+func fieldsOf(data interface{}) []Outputs {
+	if _, ok := data.([]interface{}); !ok {
+		return nil
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	var fields []Outputs
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil
+	}
+	return fields
+}
+
+// outputsOf maps resp to the outputs of the node and the flow variables they set.
+// The outputs missing from resp are empty.
+func outputsOf(outputs []Outputs, resp *response) (out, communal []*v1alpha1.KeyAndValue, err error) {
+	for _, o := range flatten("", outputs) {
+		value, _, err := resp.lookup(o.path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("output %s: %w", o.name, err)
+		}
+		s := stringOf(value)
+		out = append(out, &v1alpha1.KeyAndValue{
+			Key:   o.name,
+			Value: s,
+		})
+		if o.communal != "" {
+			communal = append(communal, &v1alpha1.KeyAndValue{
+				Key:   o.communal,
+				Value: s,
+			})
+		}
+	}
+	return out, communal, nil
+}
+
+// lookup returns the value at path in r, and whether it is there. path is status, header.<name>,
+// body, or body followed by the keys of objects and [index] of arrays like body.data.items[0].id,
+// $.data.items[0].id being short for it.
+func (r *response) lookup(path string) (interface{}, bool, error) {
+	if strings.HasPrefix(path, "$") {
+		path = pathBody + strings.TrimPrefix(path, "$")
+	}
+	root, rest := path, ""
+	if i := strings.IndexAny(path, ".["); i >= 0 {
+		root, rest = path[:i], path[i:]
+	}
+
+	switch root {
+	case pathStatus:
+		if rest != "" {
+			return nil, false, fmt.Errorf("invalid path %q", path)
+		}
+		return strconv.Itoa(r.status), true, nil
+	case pathHeader, "headers":
+		name := strings.TrimPrefix(rest, ".")
+		if name == "" {
+			return nil, false, fmt.Errorf("invalid path %q", path)
+		}
+		values, ok := r.header[http.CanonicalHeaderKey(name)]
+		if !ok || len(values) == 0 {
+			return nil, false, nil
+		}
+		return values[0], true, nil
+	case pathBody:
+		return walk(r.body, rest, path)
+	}
+	return nil, false, fmt.Errorf("invalid path %q, it must start with status, header or body", path)
+}
+
+// walk returns the value at rest, keys like .a and indexes like [0], in value.
+func walk(value interface{}, rest, path string) (interface{}, bool, error) {
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			if key == "" {
+				return nil, false, fmt.Errorf("invalid path %q", path)
+			}
+			rest = rest[end:]
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, false, nil
+			}
+			if value, ok = object[key]; !ok {
+				return nil, false, nil
+			}
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, false, fmt.Errorf("invalid path %q", path)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, false, fmt.Errorf("invalid path %q", path)
+			}
+			rest = rest[end+1:]
+			array, ok := value.([]interface{})
+			if !ok || index >= len(array) {
+				return nil, false, nil
+			}
+			value = array[index]
+		default:
+			return nil, false, fmt.Errorf("invalid path %q", path)
+		}
+	}
+	return value, true, nil
+}
+
+// stringOf returns value as the value of an output: strings and numbers as they are, null as empty
+// and the other values as JSON.
+func stringOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
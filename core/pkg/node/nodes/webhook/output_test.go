@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"net/http"
+	"testing"
+
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
+)
+
+func TestOutputsOf(t *testing.T) {
+	header := make(http.Header)
+	header.Set("X-Request-Id", "r1")
+	resp := newResponse(http.StatusCreated, header, []byte(`{"code":0,"data":{"id":"d1","items":[{"n":1.5},{"n":2}],"ok":true,"none":null}}`))
+
+	outputs := []Outputs{
+		{Name: "status", Path: "status"},
+		{Name: "requestID", Path: "header.x-request-id", Communal: "requestID"},
+		{Name: "code"},
+		{Name: "data", Type: "object", Data: []interface{}{
+			map[string]interface{}{"name": "id", "type": "string"},
+			map[string]interface{}{"name": "ok", "type": "boolean"},
+		}},
+		{Name: "second", Path: "$.data.items[1].n"},
+		{Name: "items", Path: "body.data.items"},
+		{Name: "none", Path: "body.data.none"},
+		{Name: "missing", Path: "body.data.items[5].n"},
+	}
+	out, communal, err := outputsOf(outputs, resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"status":    "201",
+		"requestID": "r1",
+		"code":      "0",
+		"data.id":   "d1",
+		"data.ok":   "true",
+		"second":    "2",
+		"items":     `[{"n":1.5},{"n":2}]`,
+		"none":      "",
+		"missing":   "",
+	}
+	if len(out) != len(want) {
+		t.Fatalf("got %d outputs, want %d", len(out), len(want))
+	}
+	for _, kv := range out {
+		if kv.Value != want[kv.Key] {
+			t.Errorf("output %s = %q, want %q", kv.Key, kv.Value, want[kv.Key])
+		}
+	}
+	if len(communal) != 1 || *communal[0] != (v1alpha1.KeyAndValue{Key: "requestID", Value: "r1"}) {
+		t.Errorf("communal = %v", communal)
+	}
+
+	// large ids and the digits of decimals are kept as they are
+	large := newResponse(http.StatusOK, nil, []byte(`{"id":12345678901234567890,"items":[{"id":9007199254740993}],"price":1.50}`))
+	out, _, err = outputsOf([]Outputs{{Name: "id"}, {Name: "items"}, {Name: "price"}}, large)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]string{
+		"id":    "12345678901234567890",
+		"items": `[{"id":9007199254740993}]`,
+		"price": "1.50",
+	}
+	for _, kv := range out {
+		if kv.Value != want[kv.Key] {
+			t.Errorf("output %s = %q, want %q", kv.Key, kv.Value, want[kv.Key])
+		}
+	}
+
+	for _, path := range []string{"code", "status.x", "header", "body.data[x]", "body..id"} {
+		if _, _, err := outputsOf([]Outputs{{Name: "x", Path: path}}, resp); err == nil {
+			t.Errorf("path %q: want error", path)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Formats of the body of a webhook, chosen by Config.ContentType.
+const (
+	formatJSON      = "json"
+	formatForm      = "form"
+	formatMultipart = "multipart"
+	formatXML       = "xml"
+	formatText      = "text"
+)
+
+const (
+	defaultTimeout = 3 * time.Second
+	maxTimeout     = 60 * time.Second
+	maxRetries     = 5
+	// retryBackoff is the wait before the first retry, doubled before each next one up to maxBackoff.
+	retryBackoff = 500 * time.Millisecond
+	maxBackoff   = 5 * time.Second
+	// defaultXMLRoot is the root element of an xml body when Config.XMLRoot is empty.
+	defaultXMLRoot = "xml"
+)
+
+// methods are the methods of the webhooks.
+var methods = map[string]struct{}{
+	http.MethodGet:    {},
+	http.MethodPost:   {},
+	http.MethodPut:    {},
+	http.MethodPatch:  {},
+	http.MethodDelete: {},
+	http.MethodHead:   {},
+}
+
+var formats = map[string]struct {
+	format      string
+	contentType string
+}{
+	"":                                  {formatJSON, "application/json"},
+	"json":                              {formatJSON, "application/json"},
+	"application/json":                  {formatJSON, "application/json"},
+	"form":                              {formatForm, "application/x-www-form-urlencoded"},
+	"application/x-www-form-urlencoded": {formatForm, "application/x-www-form-urlencoded"},
+	"multipart":                         {formatMultipart, "multipart/form-data"},
+	"multipart/form-data":               {formatMultipart, "multipart/form-data"},
+	"xml":                               {formatXML, "application/xml"},
+	"application/xml":                   {formatXML, "application/xml"},
+	"text/xml":                          {formatXML, "text/xml"},
+	"text":                              {formatText, "text/plain"},
+	"text/plain":                        {formatText, "text/plain"},
+}
+
+// formatOf returns the format of the body of contentType, a media type or the name of a format,
+// and the Content-Type it is sent with.
+func formatOf(contentType string) (format, header string, err error) {
+	mediaType := strings.ToLower(strings.TrimSpace(contentType))
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		mediaType = mt
+	}
+	f, ok := formats[mediaType]
+	switch {
+	case ok:
+	case strings.HasSuffix(mediaType, "+json"):
+		f.format = formatJSON
+	case strings.HasSuffix(mediaType, "+xml"):
+		f.format = formatXML
+	case strings.HasPrefix(mediaType, "text/"):
+		f.format = formatText
+	default:
+		return "", "", fmt.Errorf("unsupported contentType %q", contentType)
+	}
+	// a media type is sent as configured, with its parameters
+	if strings.Contains(contentType, "/") {
+		return f.format, contentType, nil
+	}
+	return f.format, f.contentType, nil
+}
+
+// field is a body input, its name a path like a.b.c to nest it in the json and xml bodies.
+type field struct {
+	name  string
+	value interface{}
+}
+
+// encodeBody returns the body of fields in format, and its Content-Type when it is not header.
+// The form and multipart bodies are flat, the names of their fields kept as they are, and the text
+// body is the values of fields, one per line.
+func encodeBody(format, header, xmlRoot string, fields []field) ([]byte, string, error) {
+	switch format {
+	case formatJSON:
+		b, err := json.Marshal(nest(fields))
+		return b, header, err
+	case formatForm:
+		values := make(url.Values, len(fields))
+		for _, f := range fields {
+			values.Add(f.name, stringOf(f.value))
+		}
+		return []byte(values.Encode()), header, nil
+	case formatMultipart:
+		buf := &bytes.Buffer{}
+		mw := multipart.NewWriter(buf)
+		for _, f := range fields {
+			if err := mw.WriteField(f.name, stringOf(f.value)); err != nil {
+				return nil, "", err
+			}
+		}
+		if err := mw.Close(); err != nil {
+			return nil, "", err
+		}
+		// the boundary is part of the content type
+		return buf.Bytes(), mw.FormDataContentType(), nil
+	case formatXML:
+		if xmlRoot == "" {
+			xmlRoot = defaultXMLRoot
+		}
+		buf := &bytes.Buffer{}
+		if err := writeXML(buf, xmlRoot, nest(fields)); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), header, nil
+	case formatText:
+		lines := make([]string, 0, len(fields))
+		for _, f := range fields {
+			lines = append(lines, stringOf(f.value))
+		}
+		return []byte(strings.Join(lines, "\n")), header, nil
+	}
+	return nil, "", fmt.Errorf("unsupported format %q", format)
+}
+
+// nest returns fields as an object, the fields named a.b set as the key b of the object a.
+func nest(fields []field) map[string]interface{} {
+	body := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		keys := strings.Split(f.name, ".")
+		object := body
+		for _, key := range keys[:len(keys)-1] {
+			child, ok := object[key].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				object[key] = child
+			}
+			object = child
+		}
+		object[keys[len(keys)-1]] = f.value
+	}
+	return body
+}
+
+// writeXML writes value as the element name: objects as their keys in order, arrays as one
+// element per item and the other values as text.
+func writeXML(w io.Writer, name string, value interface{}) error {
+	if !xmlName(name) {
+		return fmt.Errorf("invalid xml element %q", name)
+	}
+
+	if items, ok := value.([]interface{}); ok {
+		for _, item := range items {
+			if err := writeXML(w, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	fmt.Fprintf(w, "<%s>", name)
+	if object, ok := value.(map[string]interface{}); ok {
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := writeXML(w, key, object[key]); err != nil {
+				return err
+			}
+		}
+	} else if err := xml.EscapeText(w, []byte(stringOf(value))); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "</%s>", name)
+	return nil
+}
+
+// xmlName reports whether name may name an xml element, a letter or _ followed by letters,
+// digits, -, _ and dots.
+func xmlName(name string) bool {
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
+
+// hostMap rewrites the hosts of the webhooks, like localhost to the service of the cluster
+// serving it.
+type hostMap map[string]string
+
+// parseHostMap parses s, pairs like from=to separated by commas. A from without port matches the
+// host on any port, kept unless to has one.
+func parseHostMap(s string) (hostMap, error) {
+	hosts := make(hostMap)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(pair, "=")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid host map pair %q, it must be from=to", pair)
+		}
+		hosts[from] = to
+	}
+	return hosts, nil
+}
+
+// rewrite returns the host u is sent to.
+func (h hostMap) rewrite(u *url.URL) string {
+	if to, ok := h[u.Host]; ok {
+		return to
+	}
+	to, ok := h[u.Hostname()]
+	if !ok {
+		return u.Host
+	}
+	if port := u.Port(); port != "" && !strings.Contains(to, ":") {
+		return to + ":" + port
+	}
+	return to
+}
+
+// timeoutOf returns the timeout of seconds, defaultTimeout when 0 and at most maxTimeout.
+func timeoutOf(seconds int) time.Duration {
+	timeout := time.Duration(seconds) * time.Second
+	switch {
+	case timeout <= 0:
+		return defaultTimeout
+	case timeout > maxTimeout:
+		return maxTimeout
+	}
+	return timeout
+}
+
+// backoff returns the wait before the retry attempt, from 0.
+func backoff(attempt int) time.Duration {
+	wait := retryBackoff
+	for i := 0; i < attempt && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		return maxBackoff
+	}
+	return wait
+}
+
+// retryable reports whether the receiver may succeed later: it could not be reached, timed out,
+// failed or throttled the request.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// send sends req with body, retrying up to retries times while retryable. The body of the response
+// is read and closed.
+func send(ctx context.Context, client *http.Client, req *http.Request, body []byte, retries int, onRetry func(attempt int, err error)) (*http.Response, []byte, error) {
+	for attempt := 0; ; attempt++ {
+		resp, raw, err := sendOnce(ctx, client, req, body)
+		if attempt >= retries || !retryable(resp, err) {
+			return resp, raw, err
+		}
+		onRetry(attempt+1, err)
+		select {
+		case <-ctx.Done():
+			return resp, raw, err
+		case <-time.After(backoff(attempt)):
+		}
+	}
+}
+
+func sendOnce(ctx context.Context, client *http.Client, req *http.Request, body []byte) (*http.Response, []byte, error) {
+	r := req.Clone(ctx)
+	if body != nil {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+	}
+	resp, err := client.Do(r)
+	if err != nil {
+		// the error of the client holds the url, and so the api keys sent in the query
+		return nil, nil, causeOf(err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, raw, nil
+}
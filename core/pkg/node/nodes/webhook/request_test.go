@@ -0,0 +1,95 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestEncodeBody(t *testing.T) {
+	fields := []field{
+		{name: "a.b", value: "x&y"},
+		{name: "a.c", value: 1.5},
+		{name: "d", value: []interface{}{"1", "2"}},
+	}
+	tests := []struct {
+		contentType string
+		want        string
+		header      string
+	}{
+		{contentType: "", want: `{"a":{"b":"x\u0026y","c":1.5},"d":["1","2"]}`, header: "application/json"},
+		{contentType: "application/json; charset=utf-8", want: `{"a":{"b":"x\u0026y","c":1.5},"d":["1","2"]}`, header: "application/json; charset=utf-8"},
+		{contentType: "form", want: `a.b=x%26y&a.c=1.5&d=%5B%221%22%2C%222%22%5D`, header: "application/x-www-form-urlencoded"},
+		{contentType: "application/xml", want: `<xml><a><b>x&amp;y</b><c>1.5</c></a><d>1</d><d>2</d></xml>`, header: "application/xml"},
+		{contentType: "text", want: "x&y\n1.5\n[\"1\",\"2\"]", header: "text/plain"},
+	}
+	for _, tt := range tests {
+		format, header, err := formatOf(tt.contentType)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.contentType, err)
+		}
+		body, header, err := encodeBody(format, header, "", fields)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.contentType, err)
+		}
+		if string(body) != tt.want || header != tt.header {
+			t.Errorf("%q: body = %s, header = %s", tt.contentType, body, header)
+		}
+	}
+
+	body, header, err := encodeBody(formatMultipart, "multipart/form-data", "", fields)
+	if err != nil || !strings.HasPrefix(header, "multipart/form-data; boundary=") || !strings.Contains(string(body), "x&y") {
+		t.Errorf("multipart: body = %s, header = %s, err = %v", body, header, err)
+	}
+	if _, _, err := formatOf("application/octet-stream"); err == nil {
+		t.Error("octet-stream: want error")
+	}
+}
+
+func TestHostMap(t *testing.T) {
+	hosts, err := parseHostMap("localhost=polyapi, example.com:8080=backend:9090")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for host, want := range map[string]string{
+		"localhost":        "polyapi",
+		"localhost:8080":   "polyapi:8080",
+		"example.com:8080": "backend:9090",
+		"example.com":      "example.com",
+	} {
+		if got := hosts.rewrite(&url.URL{Host: host}); got != want {
+			t.Errorf("%s: got %s, want %s", host, got, want)
+		}
+	}
+	if _, err := parseHostMap("localhost"); err == nil {
+		t.Error("want error")
+	}
+}
+
+func TestSendRetries(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		b := make([]byte, 2)
+		if n, _ := r.Body.Read(b); string(b[:n]) != "hi" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, nil)
+	var retried int
+	resp, raw, err := send(context.Background(), srv.Client(), req, []byte("hi"), 2, func(int, error) { retried++ })
+	if err != nil || resp.StatusCode != http.StatusOK || string(raw) != "ok" || retried != 1 {
+		t.Errorf("status = %v, body = %s, retried = %d, err = %v", resp, raw, retried, err)
+	}
+}
@@ -0,0 +1,140 @@
+package webhook
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"git.yunify.com/quanxiang/workflow/pkg/helper/errors"
+)
+
+// secrets are the secrets the webhooks authenticate with. They are kept in a YAML file mapping
+// their names to their values sealed with AES-256-GCM, the base64 of the nonce followed by the
+// sealed value, so the pipelines only hold the names. The file is read again once it changed.
+type secrets struct {
+	path string
+	aead cipher.AEAD
+
+	mu      sync.Mutex
+	modTime time.Time
+	sealed  map[string]string
+}
+
+// newSecrets returns the secrets of the file path, sealed with key, the base64 of 32 bytes.
+func newSecrets(path, key string) (*secrets, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	s := &secrets{
+		path: path,
+		aead: aead,
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func newAEAD(key string) (cipher.AEAD, error) {
+	k, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "fail decode secrets key")
+	}
+	if len(k) != 32 {
+		return nil, fmt.Errorf("secrets key must be 32 bytes, got %d", len(k))
+	}
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// load reads the file again if it changed since it was last read.
+func (s *secrets) load() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return errors.Wrap(err, "fail stat secrets file")
+	}
+	if s.sealed != nil && info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+
+	body, err := os.ReadFile(s.path)
+	if err != nil {
+		return errors.Wrap(err, "fail read secrets file")
+	}
+	sealed := make(map[string]string)
+	if err := yaml.Unmarshal(body, &sealed); err != nil {
+		return errors.Wrap(err, "fail unmarshal secrets file")
+	}
+	s.sealed, s.modTime = sealed, info.ModTime()
+	return nil
+}
+
+// Get returns the value of the secret name. Its errors never hold a value.
+func (s *secrets) Get(name string) (string, error) {
+	if s == nil {
+		return "", fmt.Errorf("secret %q not found, no secrets are configured", name)
+	}
+
+	s.mu.Lock()
+	err := s.load()
+	value, ok := s.sealed[name]
+	s.mu.Unlock()
+	if err != nil && !ok {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("secret %q not found", name)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(b) < s.aead.NonceSize() {
+		return "", fmt.Errorf("secret %q is malformed", name)
+	}
+	nonce, sealedValue := b[:s.aead.NonceSize()], b[s.aead.NonceSize():]
+	plain, err := s.aead.Open(nil, nonce, sealedValue, []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("secret %q cannot be opened with the secrets key", name)
+	}
+	return string(plain), nil
+}
+
+// seal returns value sealed as the secret name of the secrets file.
+func seal(aead cipher.AEAD, name, value string) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(value), []byte(name))), nil
+}
+
+// SealSecret prints the value read from stdin, without its trailing newline, as the line of the
+// secret name of the secrets file.
+func SealSecret(key, name string) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	value, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+	sealed, err := seal(aead, name, strings.TrimSuffix(strings.TrimSuffix(string(value), "\n"), "\r"))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: %s\n", name, sealed)
+	return nil
+}
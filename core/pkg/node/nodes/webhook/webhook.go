@@ -0,0 +1,340 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
+	"git.yunify.com/quanxiang/workflow/pkg/helper/errors"
+	"git.yunify.com/quanxiang/workflow/pkg/node"
+	"git.yunify.com/quanxiang/workflow/pkg/thirdparty/quanxiang"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+type Config struct {
+	API         string    `json:"sendUrl"`
+	ContentType string    `json:"contentType"`
+	Outputs     []Outputs `json:"outputs"`
+	Method      string    `json:"sendMethod"`
+	EditWay     string    `json:"editWay"`
+	Inputs      []Inputs  `json:"inputs"`
+	// Auth references the secrets the webhook authenticates with, none when nil.
+	Auth *Auth `json:"auth"`
+	// Timeout is the timeout of a call in seconds, 3 when 0 and at most 60.
+	Timeout int `json:"timeout"`
+	// Retries is how many times a call is retried when the receiver could not be reached or failed,
+	// at most 5. The receiver must tolerate duplicates of the requests it timed out. Once they are
+	// used up the run is killed, when 0 the run is retried later by the runner instead.
+	Retries int `json:"retries"`
+	// XMLRoot is the root element of an xml body, xml when empty.
+	XMLRoot string `json:"xmlRoot"`
+}
+
+type Outputs struct {
+	Type  string      `json:"type"`
+	Name  string      `json:"name"`
+	Title string      `json:"title"`
+	Data  interface{} `json:"data"`
+	// Path is where the value of the output is taken from the response, see response.lookup.
+	// When empty it is body.<name>, the fields of an object output being taken from the object.
+	Path string `json:"path"`
+	// Communal is the flow variable set to the value of the output too, if any.
+	Communal string `json:"communal"`
+}
+type Data struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	Data  string `json:"data"`
+}
+type Inputs struct {
+	Type string `json:"type"`
+	// Name nests a body input in the json and xml bodies when it is a path like a.b.c.
+	Name string      `json:"name"`
+	Data interface{} `json:"data"`
+	// In is body, header, query or path, a path input replacing {<name>} in the api.
+	In        string `json:"in"`
+	FieldType string `json:"fieldType"`
+	FieldName string `json:"fieldName"`
+	TableID   string `json:"tableID"`
+}
+
+type WebHook struct {
+	logger  log.Logger
+	qx      quanxiang.QuanXiang
+	secrets *secrets
+	tokens  *tokens
+	hosts   hostMap
+}
+
+const (
+	Title       = "title"
+	Content     = "content"
+	To          = "to"
+	ContentType = "content_type"
+)
+
+// maxBody is the most of the response read, the outputs of a larger body may be missing.
+const maxBody = 4 << 20
+
+func (w *WebHook) Do(ctx context.Context, in *node.Request) (*node.Result, error) {
+	rule, err := genRule(in.Params)
+	if err != nil {
+		level.Error(w.logger).Log("message", err)
+		return node.Permanent(node.ErrCodeInvalidParams, err.Error(), nil), nil
+	}
+
+	var sourceData map[string]interface{}
+	if rule.dataID != "" && rule.tableID != "" {
+		result, err := w.qx.GetFormData(ctx, &quanxiang.GetFormDataRequest{
+			AppID:  rule.appID,
+			FormID: rule.tableID,
+			DataID: rule.dataID,
+		})
+		if err != nil {
+			level.Error(w.logger).Log("message", err, "appID", rule.appID, "tableID", rule.tableID, "dataID", rule.dataID)
+			return &node.Result{}, errors.Wrap(err, "fail get from data")
+		}
+		sourceData = result.Entity
+	}
+
+	var getValue = func(name interface{}, sourceData ...map[string]interface{}) interface{} {
+		v, ok := name.(string)
+		if !ok {
+			return name
+		}
+		values := strings.Split(v, ".")
+		if len(values) == 2 && (strings.HasPrefix(values[0], "$formData") || strings.HasPrefix(values[0], "$variable")) {
+			for _, data := range sourceData {
+				value, ok := data[strings.ReplaceAll(values[1], " ", "")]
+				if ok {
+					return value
+				}
+			}
+		}
+		return v
+	}
+
+	api := rule.rule.API
+	var (
+		fields []field
+		header = make(http.Header)
+		query  = make(url.Values)
+	)
+	for _, input := range rule.rule.Inputs {
+		if input.Name == "" {
+			continue
+		}
+		value := getValue(input.Data, sourceData, rule.communal)
+		switch input.In {
+		case "body":
+			fields = append(fields, field{
+				name:  input.Name,
+				value: value,
+			})
+		case "header":
+			header.Set(input.Name, stringOf(value))
+		case "query":
+			query.Set(input.Name, stringOf(value))
+		case "path":
+			api = strings.ReplaceAll(api, "{"+input.Name+"}", url.PathEscape(stringOf(value)))
+		}
+	}
+
+	url, err := url.Parse(api)
+	if err == nil && (url.Scheme == "" || url.Host == "") {
+		err = fmt.Errorf("api %q must be an absolute url", rule.rule.API)
+	}
+	if err != nil {
+		level.Error(w.logger).Log("message", err, "appID", rule.appID, "tableID", rule.tableID, "dataID", rule.dataID)
+		return node.Permanent(node.ErrCodeInvalidParams, err.Error(), map[string]string{
+			"api": rule.rule.API,
+		}), nil
+	}
+	url.Host = w.hosts.rewrite(url)
+	if len(query) != 0 {
+		q := url.Query()
+		for name, values := range query {
+			q[name] = values
+		}
+		url.RawQuery = q.Encode()
+	}
+
+	method := strings.ToUpper(rule.rule.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	if _, ok := methods[method]; !ok {
+		err := fmt.Errorf("unsupported method %q", rule.rule.Method)
+		level.Error(w.logger).Log("message", err, "api", rule.rule.API)
+		return node.Permanent(node.ErrCodeInvalidParams, err.Error(), nil), nil
+	}
+
+	req := http.Request{
+		Method: method,
+		URL:    url,
+		Header: header,
+	}
+
+	var body []byte
+	// the requests of GET and HEAD have no body
+	if method != http.MethodGet && method != http.MethodHead {
+		format, contentType, err := formatOf(rule.rule.ContentType)
+		if err == nil {
+			body, contentType, err = encodeBody(format, contentType, rule.rule.XMLRoot, fields)
+		}
+		if err != nil {
+			level.Error(w.logger).Log("message", err, "api", rule.rule.API)
+			return node.Permanent(node.ErrCodeInvalidParams, err.Error(), nil), nil
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	if err := w.authenticate(ctx, rule.rule.Auth, &req, body); err != nil {
+		level.Error(w.logger).Log("message", err, "api", rule.rule.API)
+		if ae, ok := err.(*authError); ok && ae.temporary {
+			return node.Transient(node.ErrCodeUnavailable, err.Error(), nil), nil
+		}
+		return node.Permanent(node.ErrCodeInvalidParams, err.Error(), nil), nil
+	}
+
+	retries := rule.rule.Retries
+	if retries > maxRetries {
+		retries = maxRetries
+	}
+	client := &http.Client{
+		Timeout: timeoutOf(rule.rule.Timeout),
+	}
+	resp, raw, err := send(ctx, client, &req, body, retries, func(attempt int, err error) {
+		level.Warn(w.logger).Log("message", "retry webhook", "api", rule.rule.API, "attempt", attempt, "err", err)
+	})
+	if err != nil {
+		level.Error(w.logger).Log("message", err, "api", rule.rule.API)
+		return failed(retries, node.ErrCodeUnavailable, err.Error(), nil), nil
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		details := map[string]string{
+			"statusCode": strconv.Itoa(resp.StatusCode),
+		}
+		message := fmt.Sprintf("webhook responded with status %d", resp.StatusCode)
+		// an oauth2 token may be revoked before it expires, the next try fetches another one
+		if resp.StatusCode == http.StatusUnauthorized && rule.rule.Auth != nil && rule.rule.Auth.Type == AuthOAuth2 {
+			w.tokens.drop(rule.rule.Auth)
+			return node.Transient(node.ErrCodeUpstream, message, details), nil
+		}
+		// the receiver may recover from server errors and throttling, but not from a rejected request
+		if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+			return failed(retries, node.ErrCodeUpstream, message, details), nil
+		}
+		return node.Permanent(node.ErrCodeUpstream, message, details), nil
+	}
+
+	out, communal, err := outputsOf(rule.rule.Outputs, newResponse(resp.StatusCode, resp.Header, raw))
+	if err != nil {
+		level.Error(w.logger).Log("message", err, "api", rule.rule.API)
+		return node.Permanent(node.ErrCodeInvalidParams, err.Error(), nil), nil
+	}
+
+	return &node.Result{
+		Status:   v1alpha1.Finish,
+		Out:      out,
+		Communal: communal,
+	}, nil
+}
+
+// failed returns the result of a call that may succeed later, retried by the runner unless the node
+// retried it retries times already, so that their retries do not multiply.
+func failed(retries int, code, message string, details map[string]string) *node.Result {
+	if retries > 0 {
+		return node.Permanent(code, message, details)
+	}
+	return node.Transient(code, message, details)
+}
+
+func genRule(in []*v1alpha1.KeyAndValue) (*rule, error) {
+	rule := &rule{
+		communal: map[string]interface{}{},
+	}
+	for _, elem := range in {
+		switch elem.Key {
+		case "appID":
+			rule.appID = elem.Value
+		case "tableID":
+			rule.tableID = elem.Value
+		case "dataID":
+			rule.dataID = elem.Value
+		case "config":
+			err := json.Unmarshal([]byte(elem.Value), &rule.rule)
+			if err != nil {
+				return nil, errors.Wrap(err, "fail unmashal rule")
+			}
+		default:
+			rule.communal[elem.Key] = elem.Value
+		}
+	}
+
+	return rule, nil
+}
+
+type rule struct {
+	appID    string
+	tableID  string
+	dataID   string
+	rule     Config
+	communal map[string]interface{}
+}
+
+// Type is the type of the node.
+const Type = "web-hook"
+
+// ServiceConfig is the config of the node, from the environment for the node service and from the
+// core config when it is local.
+type ServiceConfig struct {
+	QuanxiangInstances []string `yaml:"quanxiang_instances" envconfig:"QUANXIANG_INSTANCES"`
+	// SecretsFile holds the secrets of the webhooks sealed with SecretsKey, the base64 of 32 bytes.
+	SecretsFile string `yaml:"secrets_file" envconfig:"SECRETS_FILE"`
+	SecretsKey  string `yaml:"secrets_key" envconfig:"SECRETS_KEY"`
+	// HostMap rewrites the hosts of the webhooks, pairs like from=to separated by commas,
+	// like localhost=polyapi where the webhooks of localhost are served by polyapi. None when empty.
+	HostMap string `yaml:"host_map" envconfig:"HOST_MAP"`
+}
+
+// New returns the node of conf.
+func New(conf *ServiceConfig, logger log.Logger) (*WebHook, error) {
+	hosts, err := parseHostMap(conf.HostMap)
+	if err != nil {
+		return nil, err
+	}
+	w := &WebHook{
+		logger: logger,
+		qx:     quanxiang.New(conf.QuanxiangInstances, logger),
+		tokens: newTokens(&http.Client{
+			Timeout: time.Second * 3,
+		}),
+		hosts: hosts,
+	}
+	if conf.SecretsFile != "" {
+		if w.secrets, err = newSecrets(conf.SecretsFile, conf.SecretsKey); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+func init() {
+	node.Register(Type, func(_ context.Context, logger log.Logger, decode func(interface{}) error) (node.Interface, error) {
+		conf := &ServiceConfig{}
+		if err := decode(conf); err != nil {
+			return nil, err
+		}
+		return New(conf, logger)
+	})
+}
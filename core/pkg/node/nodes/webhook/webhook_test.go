@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
+	"git.yunify.com/quanxiang/workflow/pkg/node"
+	"git.yunify.com/quanxiang/workflow/pkg/node/nodetest"
+	"github.com/go-kit/log"
+)
+
+func TestDo(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["name"] != "alice" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "r1")
+		w.Write([]byte(`{"user":{"id":"u1","age":18}}`)) // nolint: errcheck
+	}))
+	defer receiver.Close()
+
+	w, err := New(&ServiceConfig{}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := nodetest.NewServer(t, w)
+
+	var do = func(conf Config) *node.Result {
+		b, err := json.Marshal(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return srv.Do(t, nodetest.Params("config", string(b), "name", "alice"), nil)
+	}
+
+	result := do(Config{
+		API:    receiver.URL,
+		Method: http.MethodPost,
+		Inputs: []Inputs{
+			{Name: "name", Data: "$variable.name", In: "body"},
+		},
+		Outputs: []Outputs{
+			{Name: "user", Type: "object", Data: []interface{}{
+				map[string]interface{}{"name": "id", "type": "string"},
+				map[string]interface{}{"name": "age", "type": "number"},
+			}},
+			{Name: "requestID", Path: "header.X-Request-Id", Communal: "lastRequest"},
+			{Name: "code", Path: "status"},
+		},
+	})
+	nodetest.AssertStatus(t, result, v1alpha1.Finish)
+	nodetest.AssertOut(t, result, "user.id", "u1")
+	nodetest.AssertOut(t, result, "user.age", "18")
+	nodetest.AssertOut(t, result, "requestID", "r1")
+	nodetest.AssertOut(t, result, "code", "200")
+	nodetest.AssertCommunal(t, result, "lastRequest", "r1")
+
+	// the receiver rejects the request without name
+	result = do(Config{
+		API:    receiver.URL,
+		Method: http.MethodPost,
+	})
+	nodetest.AssertStatus(t, result, v1alpha1.Kill)
+	if result.Error == nil || result.Error.Code != node.ErrCodeUpstream || result.Error.Retryable {
+		t.Errorf("unexpected error %v", result.Error)
+	}
+
+	// a failed receiver is retried by the runner, unless the node retried it already
+	result = do(Config{
+		API: receiver.URL + "/down",
+	})
+	nodetest.AssertStatus(t, result, v1alpha1.Pending)
+	if result.Error == nil || !result.Error.Retryable {
+		t.Errorf("expect a retryable error, got %v", result.Error)
+	}
+	result = do(Config{
+		API:     receiver.URL + "/down",
+		Retries: 1,
+	})
+	nodetest.AssertStatus(t, result, v1alpha1.Kill)
+	if result.Error == nil || result.Error.Retryable {
+		t.Errorf("expect an error not retryable, got %v", result.Error)
+	}
+}
@@ -0,0 +1,111 @@
+// Package nodetest provides utilities for testing node implementations
+// without the workflow core and the quanxiang platform.
+package nodetest
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
+	"git.yunify.com/quanxiang/workflow/pkg/node"
+	"github.com/go-kit/log"
+)
+
+// Server is a node service listening on a system-chosen port on the local
+// loopback interface, for use in end-to-end tests.
+type Server struct {
+	*httptest.Server
+
+	// Client calls the node over HTTP, the same way the workflow core does.
+	Client node.Interface
+}
+
+// NewServer starts s behind node.NewHTTPHandler. The server is closed when the test finishes.
+func NewServer(t testing.TB, s node.Interface, opts ...node.Option) *Server {
+	t.Helper()
+
+	h := node.NewHTTPHandler(context.Background(), s, log.NewNopLogger(), opts...)
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	client, err := node.NewClientEndPoints(srv.URL)
+	if err != nil {
+		t.Fatalf("nodetest: fail create client: %v", err)
+	}
+
+	return &Server{
+		Server: srv,
+		Client: client,
+	}
+}
+
+// Do sends a request with params and annotations to the node.
+func (s *Server) Do(t testing.TB, params []*v1alpha1.KeyAndValue, annotations map[string]string) *node.Result {
+	t.Helper()
+
+	req := &node.Request{
+		Params: params,
+	}
+	req.Metadata.Annotations = annotations
+
+	result, err := s.Client.Do(context.Background(), req)
+	if err != nil {
+		t.Fatalf("nodetest: fail do: %v", err)
+	}
+	return result
+}
+
+// Params builds node params from alternating keys and values.
+func Params(kv ...string) []*v1alpha1.KeyAndValue {
+	if len(kv)%2 != 0 {
+		panic("nodetest: Params requires key value pairs")
+	}
+
+	params := make([]*v1alpha1.KeyAndValue, 0, len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		params = append(params, &v1alpha1.KeyAndValue{
+			Key:   kv[i],
+			Value: kv[i+1],
+		})
+	}
+	return params
+}
+
+// AssertStatus fails the test if the result status is not expect.
+func AssertStatus(t testing.TB, result *node.Result, expect v1alpha1.NodeStatus) {
+	t.Helper()
+
+	if result == nil {
+		t.Fatalf("nodetest: result is nil, expect status %s", expect)
+	}
+	if result.Status != expect {
+		t.Errorf("nodetest: status is %q, expect %q, message %q", result.Status, expect, result.Message)
+	}
+}
+
+// AssertOut fails the test if the result has no output key with value expect.
+func AssertOut(t testing.TB, result *node.Result, key, expect string) {
+	t.Helper()
+	assertValue(t, "out", result.Out, key, expect)
+}
+
+// AssertCommunal fails the test if the result has no communal key with value expect.
+func AssertCommunal(t testing.TB, result *node.Result, key, expect string) {
+	t.Helper()
+	assertValue(t, "communal", result.Communal, key, expect)
+}
+
+func assertValue(t testing.TB, kind string, kvs []*v1alpha1.KeyAndValue, key, expect string) {
+	t.Helper()
+
+	for _, kv := range kvs {
+		if kv.Key == key {
+			if kv.Value != expect {
+				t.Errorf("nodetest: %s %s is %q, expect %q", kind, key, kv.Value, expect)
+			}
+			return
+		}
+	}
+	t.Errorf("nodetest: %s %s is missing", kind, key)
+}
@@ -0,0 +1,80 @@
+package nodetest
+
+import (
+	"context"
+	"testing"
+
+	"git.yunify.com/quanxiang/workflow/pkg/apis/v1alpha1"
+	"git.yunify.com/quanxiang/workflow/pkg/node"
+	"git.yunify.com/quanxiang/workflow/pkg/thirdparty/quanxiang"
+)
+
+// greet reads a form and mails its creator, like the builtin nodes do.
+type greet struct {
+	qx quanxiang.QuanXiang
+}
+
+func (g *greet) Do(ctx context.Context, in *node.Request) (*node.Result, error) {
+	var appID, tableID, dataID string
+	for _, kv := range in.Params {
+		switch kv.Key {
+		case "appID":
+			appID = kv.Value
+		case "tableID":
+			tableID = kv.Value
+		case "dataID":
+			dataID = kv.Value
+		}
+	}
+
+	data, err := g.qx.GetFormData(ctx, &quanxiang.GetFormDataRequest{
+		AppID:  appID,
+		FormID: tableID,
+		DataID: dataID,
+	})
+	if err != nil {
+		return &node.Result{}, err
+	}
+	user, err := g.qx.GetUserInfo(ctx, &quanxiang.GetUsersInfoRequest{
+		ID: data.Entity["creator_id"].(string),
+	})
+	if err != nil {
+		return &node.Result{}, err
+	}
+
+	m := new(quanxiang.CreateReq)
+	m.Email = &quanxiang.Email{
+		To:    []string{user.Data.Email},
+		Title: "hello " + user.Data.Name,
+	}
+	if _, err := g.qx.SendMessage(ctx, []*quanxiang.CreateReq{m}); err != nil {
+		return &node.Result{}, err
+	}
+
+	return &node.Result{
+		Status:   v1alpha1.Finish,
+		Out:      Params("to", user.Data.Email),
+		Communal: Params("greeted", user.Data.ID),
+	}, nil
+}
+
+func TestServer(t *testing.T) {
+	qx := NewQuanXiang()
+	qx.PutUser(&quanxiang.UserData{ID: "u1", Name: "alice", Email: "alice@example.com"})
+	qx.PutFormData("app", "form", map[string]interface{}{
+		"_id":        "d1",
+		"creator_id": "u1",
+	})
+
+	srv := NewServer(t, &greet{qx: qx})
+	result := srv.Do(t, Params("appID", "app", "tableID", "form", "dataID", "d1"), nil)
+
+	AssertStatus(t, result, v1alpha1.Finish)
+	AssertOut(t, result, "to", "alice@example.com")
+	AssertCommunal(t, result, "greeted", "u1")
+
+	messages := qx.Messages()
+	if len(messages) != 1 || messages[0].Email.Title != "hello alice" {
+		t.Errorf("unexpected outbox %v", messages)
+	}
+}
@@ -0,0 +1,258 @@
+package nodetest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"git.yunify.com/quanxiang/workflow/pkg/thirdparty/quanxiang"
+)
+
+// QuanXiang is an in-memory quanxiang.QuanXiang with forms, users and a message outbox.
+// It is safe for concurrent use.
+type QuanXiang struct {
+	mu sync.Mutex
+
+	// Forms holds form data by app id, form id and data id.
+	Forms map[string]map[string]map[string]map[string]interface{}
+	// Schemas holds form schemas by app id and form id.
+	Schemas map[string]map[string]map[string]interface{}
+	Users   map[string]*quanxiang.UserData
+	Apps    map[string]*quanxiang.AppData
+	// DepLeaders holds the ids of the heads of a department by department id,
+	// its members are the users having the department among their Dep.
+	DepLeaders map[string][]string
+	// Roles holds the ids of the members of a role by role id.
+	Roles map[string][]string
+	// Outbox records every message sent through SendMessage.
+	Outbox []*quanxiang.CreateReq
+
+	seq int
+}
+
+var _ quanxiang.QuanXiang = &QuanXiang{}
+
+// NewQuanXiang returns an empty fake.
+func NewQuanXiang() *QuanXiang {
+	return &QuanXiang{
+		Forms:      make(map[string]map[string]map[string]map[string]interface{}),
+		Schemas:    make(map[string]map[string]map[string]interface{}),
+		Users:      make(map[string]*quanxiang.UserData),
+		Apps:       make(map[string]*quanxiang.AppData),
+		DepLeaders: make(map[string][]string),
+		Roles:      make(map[string][]string),
+	}
+}
+
+// PutFormData stores entity, keyed by its _id.
+func (q *QuanXiang) PutFormData(appID, formID string, entity map[string]interface{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.putFormData(appID, formID, entity)
+}
+
+func (q *QuanXiang) putFormData(appID, formID string, entity map[string]interface{}) {
+	if _, ok := q.Forms[appID]; !ok {
+		q.Forms[appID] = make(map[string]map[string]map[string]interface{})
+	}
+	if _, ok := q.Forms[appID][formID]; !ok {
+		q.Forms[appID][formID] = make(map[string]map[string]interface{})
+	}
+
+	id, _ := entity["_id"].(string)
+	if id == "" {
+		q.seq++
+		id = fmt.Sprintf("nodetest-%d", q.seq)
+		entity["_id"] = id
+	}
+	q.Forms[appID][formID][id] = entity
+}
+
+// FormData returns the stored entity or nil.
+func (q *QuanXiang) FormData(appID, formID, dataID string) map[string]interface{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.Forms[appID][formID][dataID]
+}
+
+// PutUser stores a user, keyed by its ID.
+func (q *QuanXiang) PutUser(user *quanxiang.UserData) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.Users[user.ID] = user
+}
+
+// Messages returns a copy of the outbox.
+func (q *QuanXiang) Messages() []*quanxiang.CreateReq {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]*quanxiang.CreateReq(nil), q.Outbox...)
+}
+
+func (q *QuanXiang) SendMessage(ctx context.Context, req []*quanxiang.CreateReq) (*quanxiang.Resp, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.Outbox = append(q.Outbox, req...)
+	return &quanxiang.Resp{}, nil
+}
+
+func (q *QuanXiang) GetFormData(ctx context.Context, req *quanxiang.GetFormDataRequest) (*quanxiang.GetFormDataResponse, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entity, ok := q.Forms[req.AppID][req.FormID][req.DataID]
+	if !ok {
+		return nil, fmt.Errorf("nodetest: form data %s/%s/%s not found", req.AppID, req.FormID, req.DataID)
+	}
+	return &quanxiang.GetFormDataResponse{
+		Entity: entity,
+	}, nil
+}
+
+// SearchFormDataList returns every entity of the form; Query, Page and Sort are ignored.
+func (q *QuanXiang) SearchFormDataList(ctx context.Context, req *quanxiang.SearchFormDataListRequest) (*quanxiang.SearchFormDataListResponse, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	resp := &quanxiang.SearchFormDataListResponse{}
+	for _, entity := range q.Forms[req.AppID][req.FormID] {
+		resp.Data.Entities = append(resp.Data.Entities, entity)
+	}
+	resp.Data.Total = int64(len(resp.Data.Entities))
+	return resp, nil
+}
+
+func (q *QuanXiang) CreateFormData(ctx context.Context, req *quanxiang.CreateFormDataRequest) (*quanxiang.CreateFormDataResponse, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entity := make(map[string]interface{}, len(req.Data.Entity))
+	for k, v := range req.Data.Entity {
+		entity[k] = v
+	}
+	q.putFormData(req.AppID, req.FormID, entity)
+	return &quanxiang.CreateFormDataResponse{}, nil
+}
+
+// UpdateFormData merges the entity into the data matched by query _id.
+func (q *QuanXiang) UpdateFormData(ctx context.Context, req *quanxiang.UpdateFormDataRequest) (*quanxiang.UpdateFormDataResponse, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	id := queryID(req.Data.Query)
+	entity, ok := q.Forms[req.AppID][req.FormID][id]
+	if !ok {
+		return nil, fmt.Errorf("nodetest: form data %s/%s/%s not found", req.AppID, req.FormID, id)
+	}
+	for k, v := range req.Data.Entity {
+		entity[k] = v
+	}
+	return &quanxiang.UpdateFormDataResponse{}, nil
+}
+
+func (q *QuanXiang) GetAppInfo(ctx context.Context, req *quanxiang.GetAppInfoRequest) (*quanxiang.GetAppInfoResponse, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	app, ok := q.Apps[req.AppID]
+	if !ok {
+		app = &quanxiang.AppData{ID: req.AppID}
+	}
+	return &quanxiang.GetAppInfoResponse{
+		Data: app,
+	}, nil
+}
+
+func (q *QuanXiang) GetUserInfo(ctx context.Context, req *quanxiang.GetUsersInfoRequest) (*quanxiang.GetUsersInfoResponse, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	user, ok := q.Users[req.ID]
+	if !ok {
+		return nil, fmt.Errorf("nodetest: user %s not found", req.ID)
+	}
+	return &quanxiang.GetUsersInfoResponse{
+		Data: user,
+	}, nil
+}
+
+func (q *QuanXiang) GetFormSchema(c context.Context, req *quanxiang.GetFormSchemaRequest) (*quanxiang.GetFormSchemaResponse, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return &quanxiang.GetFormSchemaResponse{
+		ID:     req.FormID,
+		Schema: q.Schemas[req.AppID][req.FormID],
+	}, nil
+}
+
+// GetDepUsers returns the users of the department sorted by ID, or its heads;
+// sub departments are ignored.
+func (q *QuanXiang) GetDepUsers(ctx context.Context, req *quanxiang.GetDepUsersRequest) (*quanxiang.GetUsersResponse, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	resp := &quanxiang.GetUsersResponse{}
+	if req.IsLeader == 1 {
+		resp.Data = q.users(q.DepLeaders[req.DepID])
+		return resp, nil
+	}
+	for _, user := range q.Users {
+		if inDep(user, req.DepID) {
+			resp.Data = append(resp.Data, user)
+		}
+	}
+	sort.Slice(resp.Data, func(i, j int) bool {
+		return resp.Data[i].ID < resp.Data[j].ID
+	})
+	return resp, nil
+}
+
+func (q *QuanXiang) GetRoleUsers(ctx context.Context, req *quanxiang.GetRoleUsersRequest) (*quanxiang.GetUsersResponse, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return &quanxiang.GetUsersResponse{
+		Data: q.users(q.Roles[req.RoleID]),
+	}, nil
+}
+
+// users returns the stored users among ids, with only their ID for the unknown ones.
+func (q *QuanXiang) users(ids []string) []*quanxiang.UserData {
+	users := make([]*quanxiang.UserData, 0, len(ids))
+	for _, id := range ids {
+		user, ok := q.Users[id]
+		if !ok {
+			user = &quanxiang.UserData{ID: id}
+		}
+		users = append(users, user)
+	}
+	return users
+}
+
+func inDep(user *quanxiang.UserData, depID string) bool {
+	for _, path := range user.Dep {
+		for _, dep := range path {
+			if dep.ID == depID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// queryID reads the _id from the term query built by the form nodes.
+func queryID(query map[string]interface{}) string {
+	if id, ok := query["_id"].(string); ok {
+		return id
+	}
+	for _, v := range query {
+		if sub, ok := v.(map[string]interface{}); ok {
+			if id := queryID(sub); id != "" {
+				return id
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/go-kit/log"
+)
+
+// Factory builds a node implementation that runs inside the core process. decode decodes the
+// config of the node type in the core config into a value, and ctx is done when the core stops,
+// ending the background work of the node.
+type Factory func(ctx context.Context, logger log.Logger, decode func(v interface{}) error) (Interface, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a node implementation available by type, so that it can be
+// compiled into the core binary instead of being reached over HTTP.
+// It panics if the same type is registered twice or if factory is nil.
+func Register(_type string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("node: Register factory is nil")
+	}
+	if _, dup := registry[_type]; dup {
+		panic(fmt.Sprintf("node: Register called twice for type %s", _type))
+	}
+	registry[_type] = factory
+}
+
+// Lookup returns the factory registered for the node type.
+func Lookup(_type string) (Factory, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	factory, ok := registry[_type]
+	return factory, ok
+}
+
+// Registered returns the sorted list of registered node types.
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	types := make([]string, 0, len(registry))
+	for _type := range registry {
+		types = append(types, _type)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func init() {
+	Register("null", func(context.Context, log.Logger, func(interface{}) error) (Interface, error) {
+		return &Null{}, nil
+	})
+}
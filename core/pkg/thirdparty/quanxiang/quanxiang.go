@@ -34,6 +34,8 @@ import (
 
 const (
 	sendMessageURI = "/api/v1/message/manager/create/batch"
+	depUsersURI    = "/api/v1/org/o/user/dep/id"
+	roleUsersURI   = "/api/v1/goalie/o/role/user/list"
 )
 
 // QuanXiang 消息服务提供
@@ -46,6 +48,8 @@ type QuanXiang interface {
 	GetAppInfo(ctx context.Context, req *GetAppInfoRequest) (*GetAppInfoResponse, error)
 	GetUserInfo(ctx context.Context, req *GetUsersInfoRequest) (*GetUsersInfoResponse, error)
 	GetFormSchema(c context.Context, req *GetFormSchemaRequest) (*GetFormSchemaResponse, error)
+	GetDepUsers(ctx context.Context, req *GetDepUsersRequest) (*GetUsersResponse, error)
+	GetRoleUsers(ctx context.Context, req *GetRoleUsersRequest) (*GetUsersResponse, error)
 }
 
 type message struct {
@@ -105,6 +109,8 @@ type Endpoints struct {
 	GetAppInfoEndpoint         endpoint.Endpoint
 	GetUsersInfoEndpoint       endpoint.Endpoint
 	GetFormSchemaEndpoint      endpoint.Endpoint
+	GetDepUsersEndpoint        endpoint.Endpoint
+	GetRoleUsersEndpoint       endpoint.Endpoint
 }
 
 func (e Endpoints) SendMessage(ctx context.Context, in []*CreateReq) (*Resp, error) {
@@ -195,6 +201,20 @@ func New(instance []string, logger log.Logger) QuanXiang {
 		retry := lb.Retry(retryMax, retryTimeout, balancer)
 		endpoints.GetUsersInfoEndpoint = retry
 	}
+	{
+		factory := factoryFor(GetDepUsersEndpoint)
+		endpointer := sd.NewEndpointer(getInstancer(instance, "http://org"), factory, logger)
+		balancer := lb.NewRoundRobin(endpointer)
+		retry := lb.Retry(retryMax, retryTimeout, balancer)
+		endpoints.GetDepUsersEndpoint = retry
+	}
+	{
+		factory := factoryFor(GetRoleUsersEndpoint)
+		endpointer := sd.NewEndpointer(getInstancer(instance, "http://goalie"), factory, logger)
+		balancer := lb.NewRoundRobin(endpointer)
+		retry := lb.Retry(retryMax, retryTimeout, balancer)
+		endpoints.GetRoleUsersEndpoint = retry
+	}
 
 	return endpoints
 }
@@ -524,9 +544,73 @@ func NewClientEndPoints(instance string) (Endpoints, error) {
 			}
 			return &respData, err
 		}, options...).Endpoint(),
+		GetDepUsersEndpoint: httptransport.NewClient("POST", tgt, func(ctx context.Context, r *http.Request, request interface{}) error {
+			req := request.(*GetDepUsersRequest)
+
+			r.URL.Path = depUsersURI
+			paramByte, err := json.Marshal(req)
+			if err != nil {
+				return err
+			}
+
+			reader := bytes.NewReader(paramByte)
+			r.Header.Set("Content-Type", "application/json")
+			r.Body = io.NopCloser(reader)
+
+			return nil
+		}, decodeUsersResponse("org"), options...).Endpoint(),
+		GetRoleUsersEndpoint: httptransport.NewClient("POST", tgt, func(ctx context.Context, r *http.Request, request interface{}) error {
+			req := request.(*GetRoleUsersRequest)
+
+			r.URL.Path = roleUsersURI
+			paramByte, err := json.Marshal(req)
+			if err != nil {
+				return err
+			}
+
+			reader := bytes.NewReader(paramByte)
+			r.Header.Set("Content-Type", "application/json")
+			r.Body = io.NopCloser(reader)
+
+			return nil
+		}, decodeUsersResponse("goalie"), options...).Endpoint(),
 	}, nil
 }
 
+// decodeUsersResponse decodes the list of users returned by server.
+func decodeUsersResponse(server string) httptransport.DecodeResponseFunc {
+	return func(ctx context.Context, resp *http.Response) (response interface{}, err error) {
+		if resp.StatusCode != http.StatusOK {
+			return &GetUsersResponse{}, errors.NewErr(http.StatusInternalServerError, &errors.CodeError{
+				Code:    resp.StatusCode,
+				Message: "get users from " + server + " err",
+			})
+		}
+
+		r := new(BaseResp)
+		err = json.NewDecoder(resp.Body).Decode(r)
+		if err != nil {
+			return &GetUsersResponse{}, pkgerrors.Wrap(err, "json decode users from "+server+" err ")
+		}
+		if r.Code != 0 {
+			return &GetUsersResponse{}, errors.NewErr(http.StatusInternalServerError, &errors.CodeError{
+				Code:    r.Code,
+				Message: r.Message,
+			})
+		}
+		users := make([]*UserData, 0)
+		if r.Data != nil {
+			err = makeData(r.Data, &users)
+			if err != nil {
+				return &GetUsersResponse{}, pkgerrors.Wrap(err, "json decode users from "+server+" err ")
+			}
+		}
+		return &GetUsersResponse{
+			Data: users,
+		}, nil
+	}
+}
+
 // form ----------
 type GetFormDataRequest struct {
 	AppID  string
@@ -792,6 +876,54 @@ func GetFormSchemaEndpoint(e QuanXiang) endpoint.Endpoint {
 	}
 }
 
+type GetDepUsersRequest struct {
+	DepID string `json:"depID"`
+	// IncludeChild set to 1 also returns the users of the sub departments.
+	IncludeChild int `json:"includeChildDEPChild"`
+	// IsLeader set to 1 only returns the heads of the department.
+	IsLeader int `json:"isLeader,omitempty"`
+}
+
+type GetRoleUsersRequest struct {
+	RoleID string `json:"roleID"`
+}
+
+type GetUsersResponse struct {
+	Data []*UserData `json:"data"`
+}
+
+func (e Endpoints) GetDepUsers(ctx context.Context, in *GetDepUsersRequest) (*GetUsersResponse, error) {
+	resp, err := e.GetDepUsersEndpoint(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*GetUsersResponse), err
+}
+
+func GetDepUsersEndpoint(e QuanXiang) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(*GetDepUsersRequest)
+		resp, err := e.GetDepUsers(ctx, req)
+		return resp, err
+	}
+}
+
+func (e Endpoints) GetRoleUsers(ctx context.Context, in *GetRoleUsersRequest) (*GetUsersResponse, error) {
+	resp, err := e.GetRoleUsersEndpoint(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*GetUsersResponse), err
+}
+
+func GetRoleUsersEndpoint(e QuanXiang) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(*GetRoleUsersRequest)
+		resp, err := e.GetRoleUsers(ctx, req)
+		return resp, err
+	}
+}
+
 func makeData(src interface{}, entity interface{}) error {
 	marshal, err := json.Marshal(src)
 	if err != nil {